@@ -3,18 +3,29 @@ package cli
 import "strings"
 
 var knownCommands = map[string]struct{}{
-	"help":    {},
-	"install": {},
-	"config":  {},
-	"create":  {},
-	"start":   {},
-	"stop":    {},
-	"update":  {},
-	"ssh":     {},
-	"scp":     {},
-	"status":  {},
-	"state":   {},
-	"version": {},
+	"help":      {},
+	"install":   {},
+	"config":    {},
+	"create":    {},
+	"start":     {},
+	"stop":      {},
+	"reset":     {},
+	"suspend":   {},
+	"resume":    {},
+	"scale":     {},
+	"reimage":   {},
+	"watch":     {},
+	"plan":      {},
+	"reconcile": {},
+	"gc":        {},
+	"ports":     {},
+	"disk":      {},
+	"update":    {},
+	"ssh":       {},
+	"scp":       {},
+	"status":    {},
+	"state":     {},
+	"version":   {},
 }
 
 // NormalizeArgs rewrites convenience shorthand forms into explicit subcommands.
@@ -36,11 +36,46 @@ func TestNormalizeArgs(t *testing.T) {
 			in:   []string{"gpunow", "start", "foo", "-n", "3"},
 			want: []string{"gpunow", "start", "foo", "-n", "3"},
 		},
+		{
+			name: "scale command remains",
+			in:   []string{"gpunow", "scale", "foo", "-n", "8"},
+			want: []string{"gpunow", "scale", "foo", "-n", "8"},
+		},
+		{
+			name: "watch command remains",
+			in:   []string{"gpunow", "watch", "train", "-n", "4"},
+			want: []string{"gpunow", "watch", "train", "-n", "4"},
+		},
 		{
 			name: "config command remains",
 			in:   []string{"gpunow", "config", "--gcp-zone", "us-east1-d"},
 			want: []string{"gpunow", "config", "--gcp-zone", "us-east1-d"},
 		},
+		{
+			name: "suspend command remains",
+			in:   []string{"gpunow", "suspend", "train", "--start"},
+			want: []string{"gpunow", "suspend", "train", "--start"},
+		},
+		{
+			name: "resume command remains",
+			in:   []string{"gpunow", "resume", "train", "--start"},
+			want: []string{"gpunow", "resume", "train", "--start"},
+		},
+		{
+			name: "reset command remains",
+			in:   []string{"gpunow", "reset", "train/1", "--start"},
+			want: []string{"gpunow", "reset", "train/1", "--start"},
+		},
+		{
+			name: "reimage command remains",
+			in:   []string{"gpunow", "reimage", "train", "--rolling", "-n", "2"},
+			want: []string{"gpunow", "reimage", "train", "--rolling", "-n", "2"},
+		},
+		{
+			name: "disk command remains",
+			in:   []string{"gpunow", "disk", "resize", "train", "--size-gb", "200", "-n", "2"},
+			want: []string{"gpunow", "disk", "resize", "train", "--size-gb", "200", "-n", "2"},
+		},
 		{
 			name: "no create flags remains",
 			in:   []string{"gpunow", "foo"},
@@ -57,3 +92,11 @@ func TestNormalizeArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestKnownCommandsCoverApp(t *testing.T) {
+	for _, command := range NewApp().Commands {
+		if _, ok := knownCommands[command.Name]; !ok {
+			t.Errorf("command %s is missing from knownCommands", command.Name)
+		}
+	}
+}
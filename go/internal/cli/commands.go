@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -49,6 +50,17 @@ func NewApp() *cli.App {
 			createCommand(),
 			startCommand(),
 			stopCommand(),
+			resetCommand(),
+			suspendCommand(),
+			resumeCommand(),
+			scaleCommand(),
+			reimageCommand(),
+			watchCommand(),
+			planCommand(),
+			reconcileCommand(),
+			gcCommand(),
+			portsCommand(),
+			diskCommand(),
 			updateCommand(),
 			sshCommand(),
 			scpCommand(),
@@ -81,6 +93,13 @@ func createCommand() *cli.Command {
 			&cli.StringFlag{Name: "gcp-termination-action", Usage: "Override termination action (DELETE|STOP) for this cluster"},
 			&cli.IntFlag{Name: "gcp-disk-size-gb", Usage: "Override boot disk size in GB for this cluster"},
 			&cli.BoolFlag{Name: "keep-disks", Usage: "Preserve boot disks on delete for this cluster"},
+			&cli.IntFlag{Name: "min-ready", Usage: "With --start, succeed once this many nodes are READY instead of failing on the first node error"},
+			&cli.BoolFlag{Name: "delete-failed", Usage: "With --min-ready, delete nodes that fail to become READY"},
+			&cli.StringFlag{Name: "zones", Usage: "With --start, comma-separated zones to try in order on stockouts"},
+			&cli.StringFlag{Name: "ssh-source-ranges", Usage: "Comma-separated CIDRs allowed to reach SSH on this cluster (overrides network.ssh_source_ranges)"},
+			&cli.StringFlag{Name: "port-source-ranges", Usage: "Comma-separated CIDRs allowed to reach exposed ports on this cluster (overrides network.port_source_ranges)"},
+			&cli.BoolFlag{Name: "my-ip", Usage: "Allow only this machine's public IP (plus any --ssh-source-ranges/--port-source-ranges)"},
+			&cli.StringFlag{Name: "subnet-cidr", Usage: "Subnet range for this cluster (overrides the range allocated from cluster.subnet_cidr_base)"},
 		},
 		Action: createCluster,
 	}
@@ -89,10 +108,17 @@ func createCommand() *cli.Command {
 func startCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "start",
-		Usage:     "Start a cluster",
-		ArgsUsage: "<cluster>",
+		Usage:     "Start a cluster or some of its nodes",
+		ArgsUsage: "<cluster|cluster/index>",
 		Flags: []cli.Flag{
 			&cli.IntFlag{Name: "num-instances", Aliases: []string{"n"}, Usage: "Number of instances (required to create new clusters)"},
+			&cli.IntFlag{Name: "min-ready", Usage: "Succeed once this many nodes are READY instead of failing on the first node error"},
+			&cli.BoolFlag{Name: "delete-failed", Usage: "With --min-ready, delete nodes that fail to become READY"},
+			&cli.StringFlag{Name: "zones", Usage: "Comma-separated zones to try in order on stockouts (overrides project.zone and project.fallback_zones)"},
+			&cli.StringFlag{Name: "ssh-source-ranges", Usage: "Comma-separated CIDRs allowed to reach SSH on this cluster (overrides network.ssh_source_ranges)"},
+			&cli.StringFlag{Name: "port-source-ranges", Usage: "Comma-separated CIDRs allowed to reach exposed ports on this cluster (overrides network.port_source_ranges)"},
+			&cli.BoolFlag{Name: "my-ip", Usage: "Allow only this machine's public IP (plus any --ssh-source-ranges/--port-source-ranges)"},
+			&cli.StringFlag{Name: "subnet-cidr", Usage: "Subnet range for this cluster (overrides the range allocated from cluster.subnet_cidr_base)"},
 		},
 		Action: startCluster,
 	}
@@ -101,12 +127,13 @@ func startCommand() *cli.Command {
 func stopCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "stop",
-		Usage:     "Stop or delete a cluster",
-		ArgsUsage: "<cluster>",
+		Usage:     "Stop or delete a cluster or some of its nodes",
+		ArgsUsage: "<cluster|cluster/index>",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{Name: "delete", Usage: "Delete instances"},
 			&cli.BoolFlag{Name: "keep-disks", Usage: "Keep boot disks when deleting instances"},
 			&cli.BoolFlag{Name: "delete-disks", Usage: "Delete boot disks when deleting instances"},
+			&cli.BoolFlag{Name: "force", Usage: "Stop even if hooks.pre_stop fails on a node"},
 		},
 		Action: stopCluster,
 	}
@@ -116,9 +143,13 @@ func updateCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "update",
 		Usage:     "Update cluster settings",
-		ArgsUsage: "<cluster>",
+		ArgsUsage: "<cluster|cluster/index>",
 		Flags: []cli.Flag{
-			&cli.IntFlag{Name: "max-hours", Usage: "Max run duration in hours"},
+			&cli.IntFlag{Name: "max-hours", Usage: "Max run duration in hours (nodes must be stopped)"},
+			&cli.StringFlag{Name: "machine-type", Usage: "Switch to this machine type (nodes must be stopped)"},
+			&cli.StringFlag{Name: "termination-action", Usage: "Termination action DELETE|STOP (nodes must be stopped)"},
+			&cli.StringFlag{Name: "labels", Usage: "Comma-separated key=value labels to add or change"},
+			&cli.StringFlag{Name: "metadata", Usage: "Comma-separated key=value metadata items to add or change"},
 		},
 		Action: updateCluster,
 	}
@@ -172,24 +203,54 @@ func createCluster(c *cli.Context) error {
 	if err != nil {
 		return usageError(c, err.Error())
 	}
-	if !numInstancesExplicit || numInstances <= 0 {
+	pools := profilePools(state.Config)
+	switch {
+	case len(pools) > 0 && numInstancesExplicit:
+		return usageError(c, "--num-instances cannot be used with cluster.pools; set pool counts in the profile")
+	case len(pools) > 0:
+		numInstances = poolsTotal(pools)
+	case !numInstancesExplicit || numInstances <= 0:
 		return usageError(c, "--num-instances must be a positive integer")
 	}
 	clusterConfig, err := parseCreateClusterConfig(c)
 	if err != nil {
 		return usageError(c, err.Error())
 	}
+	clusterConfig.Pools = pools
+	clusterConfig.Network = recordedNetwork(state.Config)
+	if err := applySourceRangeFlags(c, state.Config, &clusterConfig); err != nil {
+		return err
+	}
+	if err := applySubnetCIDRFlag(c, &clusterConfig); err != nil {
+		return usageError(c, err.Error())
+	}
 	startNow := c.Bool("start") || hasBoolArg(c.Args().Slice(), "start")
 	estimateCost := c.Bool("estimate-cost") || hasBoolArg(c.Args().Slice(), "estimate-cost")
 	refreshPricing := c.Bool("refresh") || hasBoolArg(c.Args().Slice(), "refresh")
 	if refreshPricing && !estimateCost {
 		return usageError(c, "--refresh requires --estimate-cost")
 	}
+	quorum, err := parseStartQuorum(c, numInstances)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if quorum.MinReady > 0 && !startNow {
+		return usageError(c, "--min-ready requires --start")
+	}
+	zones, err := parseZonesValue(c)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if len(zones) > 0 && !startNow {
+		return usageError(c, "--zones requires --start")
+	}
 	if startNow {
 		return createAndStartCluster(c, state, clusterName, numInstances, createOptions{
 			ClusterConfig:  clusterConfig,
 			EstimateCost:   estimateCost,
 			RefreshPricing: refreshPricing,
+			Quorum:         quorum,
+			Zones:          zones,
 		})
 	}
 	announce(state)
@@ -218,6 +279,8 @@ type createOptions struct {
 	ClusterConfig  appstate.ClusterConfig
 	EstimateCost   bool
 	RefreshPricing bool
+	Quorum         startQuorum
+	Zones          []string
 }
 
 func createAndStartCluster(c *cli.Context, state *State, clusterName string, numInstances int, opts createOptions) error {
@@ -249,12 +312,22 @@ func createAndStartCluster(c *cli.Context, state *State, clusterName string, num
 
 	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
 	startOptions := applyClusterConfig(cluster.StartOptions{
-		NumInstances:  numInstances,
-		SSHUser:       user,
-		SSHPublicKey:  selectionKey(selection),
-		OnStateChange: clusterStateUpdateFn(state, clusterName),
+		NumInstances:    numInstances,
+		SSHUser:         user,
+		SSHPublicKey:    selectionKey(selection),
+		SSHIdentityFile: selectionIdentityPath(selection),
+		MinReady:        opts.Quorum.MinReady,
+		DeleteFailed:    opts.Quorum.DeleteFailed,
+		Zones:           opts.Zones,
+		OnStateChange:   clusterStateUpdateFn(state, clusterName),
+		OnImage:         clusterImageRecordFn(state, clusterName),
 	}, opts.ClusterConfig)
-	if err := service.Start(c.Context, clusterName, startOptions); err != nil {
+	if startOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	result, err := service.Start(c.Context, clusterName, startOptions)
+	if err != nil {
+		reportStartResult(state, clusterName, result, opts.Quorum)
 		return err
 	}
 	if state.State != nil {
@@ -262,6 +335,7 @@ func createAndStartCluster(c *cli.Context, state *State, clusterName string, num
 			state.UI.Warnf("Failed to update state: %v", err)
 		}
 	}
+	reportStartResult(state, clusterName, result, opts.Quorum)
 	return nil
 }
 
@@ -270,10 +344,14 @@ func startCluster(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
 	if err != nil {
 		return err
 	}
+	clusterName, nodeNames, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
 	numInstances, numInstancesExplicit, err := parseNumInstancesValue(c)
 	if err != nil {
 		return usageError(c, err.Error())
@@ -281,6 +359,9 @@ func startCluster(c *cli.Context) error {
 	if numInstancesExplicit && numInstances <= 0 {
 		return usageError(c, "--num-instances must be a positive integer")
 	}
+	if numInstancesExplicit && nodeNames != nil {
+		return usageError(c, "--num-instances applies to whole clusters; use `gpunow scale` to resize")
+	}
 	clusterConfig := appstate.ClusterConfig{}
 	var clusterEntryNumInstances int
 	if state.State != nil {
@@ -294,6 +375,10 @@ func startCluster(c *cli.Context) error {
 		}
 		clusterEntryNumInstances = entry.NumInstances
 		clusterConfig = entry.Config
+		if numInstancesExplicit && len(entry.Config.Pools) > 0 {
+			return usageError(c, fmt.Sprintf("cluster %s uses node pools; resize them with `gpunow scale %s --pool <name> -n <count>`", clusterName, clusterName))
+		}
+		applyRecordedCluster(state, entry)
 	}
 	if !numInstancesExplicit {
 		numInstances = clusterEntryNumInstances
@@ -301,6 +386,23 @@ func startCluster(c *cli.Context) error {
 			return usageError(c, fmt.Sprintf("cluster %s has no instance count in state; run `gpunow create %s -n <num>`", clusterName, clusterName))
 		}
 	}
+	quorum, err := parseStartQuorum(c, numInstances)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	zones, err := parseZonesValue(c)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if nodeNames != nil && (quorum.MinReady > 0 || len(zones) > 0) {
+		return usageError(c, "--min-ready and --zones apply to whole clusters")
+	}
+	if err := applySourceRangeFlags(c, state.Config, &clusterConfig); err != nil {
+		return err
+	}
+	if err := applySubnetCIDRFlag(c, &clusterConfig); err != nil {
+		return usageError(c, err.Error())
+	}
 	selection, err := resolveSSHSelection(state)
 	if err != nil {
 		return err
@@ -318,12 +420,29 @@ func startCluster(c *cli.Context) error {
 
 	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
 	startOptions := applyClusterConfig(cluster.StartOptions{
-		NumInstances:  numInstances,
-		SSHUser:       user,
-		SSHPublicKey:  selectionKey(selection),
-		OnStateChange: clusterStateUpdateFn(state, clusterName),
+		NumInstances:    numInstances,
+		SSHUser:         user,
+		SSHPublicKey:    selectionKey(selection),
+		SSHIdentityFile: selectionIdentityPath(selection),
+		MinReady:        quorum.MinReady,
+		DeleteFailed:    quorum.DeleteFailed,
+		Zones:           zones,
+		OnStateChange:   clusterStateUpdateFn(state, clusterName),
+		OnImage:         clusterImageRecordFn(state, clusterName),
 	}, clusterConfig)
-	if err := service.Start(c.Context, clusterName, startOptions); err != nil {
+	if startOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	if nodeNames != nil {
+		if err := service.StartNodes(c.Context, clusterName, nodeNames, startOptions); err != nil {
+			return err
+		}
+		state.UI.Successf("Started %s", strings.Join(nodeNames, ", "))
+		return nil
+	}
+	result, err := service.Start(c.Context, clusterName, startOptions)
+	if err != nil {
+		reportStartResult(state, clusterName, result, quorum)
 		return err
 	}
 	if state.State != nil {
@@ -331,6 +450,7 @@ func startCluster(c *cli.Context) error {
 			state.UI.Warnf("Failed to update state: %v", err)
 		}
 	}
+	reportStartResult(state, clusterName, result, quorum)
 	return nil
 }
 
@@ -339,10 +459,17 @@ func stopCluster(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
 	if err != nil {
 		return err
 	}
+	clusterName, nodeNames, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
 	announce(state)
 	deleteFlag := c.Bool("delete") || hasBoolArg(c.Args().Slice(), "delete")
 	keepDisksFlag := c.Bool("keep-disks") || hasBoolArg(c.Args().Slice(), "keep-disks")
@@ -367,16 +494,31 @@ func stopCluster(c *cli.Context) error {
 		return err
 	}
 
-	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
-	if err := service.Stop(c.Context, clusterName, cluster.StopOptions{
+	stopOpts := cluster.StopOptions{
+		Nodes:         nodeNames,
 		Delete:        deleteFlag,
 		KeepDisks:     keepDisks,
 		DeleteDisks:   deleteDisks,
 		OnStateChange: clusterStateUpdateFn(state, clusterName),
-	}); err != nil {
+		Force:         c.Bool("force") || hasBoolArg(c.Args().Slice(), "force"),
+	}
+	if len(state.Config.Hooks.PreStop) > 0 {
+		selection, err := resolveSSHSelection(state)
+		if err != nil {
+			return err
+		}
+		stopOpts.SSHUser = strings.TrimSpace(state.Config.SSH.DefaultUser)
+		stopOpts.SSHPublicKey = selectionKey(selection)
+		stopOpts.SSHIdentityFile = selectionIdentityPath(selection)
+	}
+
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.Stop(c.Context, clusterName, stopOpts); err != nil {
 		return err
 	}
-	if state.State != nil {
+	// A partial stop only touches the selected nodes' state entries, which
+	// OnStateChange already updated.
+	if state.State != nil && nodeNames == nil {
 		if deleteFlag {
 			if err := state.State.DeleteCluster(clusterName); err != nil {
 				state.UI.Warnf("Failed to update state: %v", err)
@@ -409,17 +551,47 @@ func updateCluster(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
 	if err != nil {
 		return err
 	}
+	clusterName, nodeNames, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
 	announce(state)
-	maxHours, maxHoursExplicit, err := parseMaxHoursValue(c)
+	changes, err := parseUpdateClusterConfig(c)
 	if err != nil {
 		return usageError(c, err.Error())
 	}
-	if !maxHoursExplicit || maxHours <= 0 {
-		return usageError(c, "--max-hours must be a positive integer")
+	clusterConfig := appstate.ClusterConfig{}
+	if state.State != nil {
+		data, err := state.State.Load()
+		if err != nil {
+			return err
+		}
+		if entry := data.Clusters[clusterName]; entry != nil {
+			clusterConfig = entry.Config
+		}
+	}
+	if err := checkPooledMachineType(clusterName, clusterConfig, changes, nodeNames); err != nil {
+		return usageError(c, err.Error())
+	}
+	updateOptions := cluster.UpdateOptions{
+		MachineType: changes.GCPMachineType,
+		Labels:      changes.Labels,
+		Metadata:    changes.Metadata,
+		Nodes:       nodeNames,
+	}
+	// Scheduling is replaced as a whole, so the half not being changed keeps
+	// the cluster's setting.
+	if changes.GCPMaxRunHours > 0 || changes.GCPTerminationAction != "" {
+		updated := mergeClusterUpdate(clusterConfig, changes)
+		updateOptions.MaxRunHours = updated.GCPMaxRunHours
+		updateOptions.TerminationAction = updated.GCPTerminationAction
 	}
 
 	compute, err := state.ComputeClient(c.Context)
@@ -428,17 +600,151 @@ func updateCluster(c *cli.Context) error {
 	}
 
 	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
-	if err := service.Update(c.Context, clusterName, cluster.UpdateOptions{MaxRunHours: maxHours}); err != nil {
+	if err := service.Update(c.Context, clusterName, updateOptions); err != nil {
 		return err
 	}
-	if state.State != nil {
-		if err := state.State.RecordClusterUpdate(clusterName, time.Now()); err != nil {
+	// Per-node updates leave the cluster config alone; nodes created later
+	// follow the cluster.
+	if state.State != nil && nodeNames == nil {
+		if err := state.State.RecordClusterUpdate(clusterName, mergeClusterUpdate(clusterConfig, changes), time.Now()); err != nil {
 			state.UI.Warnf("Failed to update state: %v", err)
 		}
 	}
 	return nil
 }
 
+// checkPooledMachineType refuses a whole-cluster --machine-type on a cluster
+// laid out with pools: each pool's machine_type would win again for nodes
+// created later. A node target (e.g. cluster/gpu/0-3) is changed in place.
+func checkPooledMachineType(clusterName string, clusterConfig, changes appstate.ClusterConfig, nodeNames []string) error {
+	if changes.GCPMachineType == "" || nodeNames != nil || len(clusterConfig.Pools) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cluster %s uses pools; pass --machine-type with a pool target such as %s/%s/0 or set machine_type on the pool in the profile",
+		clusterName, clusterName, clusterConfig.Pools[0].Name)
+}
+
+// parseUpdateClusterConfig returns the settings passed to `gpunow update`
+// as a cluster config holding only the changed fields.
+func parseUpdateClusterConfig(c *cli.Context) (appstate.ClusterConfig, error) {
+	changes := appstate.ClusterConfig{}
+	maxHours, maxHoursExplicit, err := parseMaxHoursValue(c)
+	if err != nil {
+		return changes, err
+	}
+	if maxHoursExplicit {
+		if maxHours <= 0 {
+			return changes, fmt.Errorf("--max-hours must be a positive integer")
+		}
+		changes.GCPMaxRunHours = maxHours
+	}
+	machineType, machineTypeSet, err := parseStringFlagValue(c, "--machine-type", "machine-type")
+	if err != nil {
+		return changes, err
+	}
+	if machineTypeSet {
+		changes.GCPMachineType = strings.TrimSpace(machineType)
+		if changes.GCPMachineType == "" {
+			return changes, fmt.Errorf("--machine-type cannot be empty")
+		}
+	}
+	terminationAction, terminationActionSet, err := parseStringFlagValue(c, "--termination-action", "termination-action")
+	if err != nil {
+		return changes, err
+	}
+	if terminationActionSet {
+		changes.GCPTerminationAction = strings.ToUpper(strings.TrimSpace(terminationAction))
+		if changes.GCPTerminationAction != "DELETE" && changes.GCPTerminationAction != "STOP" {
+			return changes, fmt.Errorf("--termination-action must be DELETE or STOP")
+		}
+	}
+	labelsRaw, labelsSet, err := parseStringFlagValue(c, "--labels", "labels")
+	if err != nil {
+		return changes, err
+	}
+	if labelsSet {
+		if changes.Labels, err = parseKeyValues("--labels", labelsRaw, labelKeyPattern, labelValuePattern); err != nil {
+			return changes, err
+		}
+	}
+	metadataRaw, metadataSet, err := parseStringFlagValue(c, "--metadata", "metadata")
+	if err != nil {
+		return changes, err
+	}
+	if metadataSet {
+		if changes.Metadata, err = parseKeyValues("--metadata", metadataRaw, metadataKeyPattern, nil); err != nil {
+			return changes, err
+		}
+	}
+	if !maxHoursExplicit && !machineTypeSet && !terminationActionSet && !labelsSet && !metadataSet {
+		return changes, fmt.Errorf("nothing to update; pass --max-hours, --machine-type, --termination-action, --labels or --metadata")
+	}
+	return changes, nil
+}
+
+// GCE label values follow the key rules but may be empty or start with a
+// digit.
+var (
+	labelKeyPattern    = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValuePattern  = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+	metadataKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,128}$`)
+)
+
+// parseKeyValues parses a comma separated list of key=value pairs. Keys must
+// match keyPattern, and values valuePattern unless it is nil.
+func parseKeyValues(flag, raw string, keyPattern, valuePattern *regexp.Regexp) (map[string]string, error) {
+	values := map[string]string{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%s: invalid entry %q (want key=value)", flag, item)
+		}
+		if valuePattern != nil && !valuePattern.MatchString(value) {
+			return nil, fmt.Errorf("%s: invalid value %q for %s", flag, value, key)
+		}
+		values[key] = value
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s requires key=value pairs", flag)
+	}
+	return values, nil
+}
+
+// mergeClusterUpdate returns clusterConfig with the fields set in changes
+// applied; labels and metadata are merged key by key.
+func mergeClusterUpdate(clusterConfig, changes appstate.ClusterConfig) appstate.ClusterConfig {
+	if changes.GCPMachineType != "" {
+		clusterConfig.GCPMachineType = changes.GCPMachineType
+	}
+	if changes.GCPMaxRunHours > 0 {
+		clusterConfig.GCPMaxRunHours = changes.GCPMaxRunHours
+	}
+	if changes.GCPTerminationAction != "" {
+		clusterConfig.GCPTerminationAction = changes.GCPTerminationAction
+	}
+	clusterConfig.Labels = mergeStringMaps(clusterConfig.Labels, changes.Labels)
+	clusterConfig.Metadata = mergeStringMaps(clusterConfig.Metadata, changes.Metadata)
+	return clusterConfig
+}
+
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := map[string]string{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
 func sshAction(c *cli.Context) error {
 	state, err := GetState(c)
 	if err != nil {
@@ -462,20 +768,22 @@ func sshAction(c *cli.Context) error {
 	if selection != nil && selection.Key != "" && strings.TrimSpace(user) == "" {
 		return fmt.Errorf("ssh.default_user is required to set ssh keys")
 	}
-	announceWithKey(state, selection, true)
-
-	compute, err := state.ComputeClient(c.Context)
+	targetSpec, err := target.Parse(targetRaw)
 	if err != nil {
 		return err
 	}
+	if !targetSpec.IsCluster {
+		return fmt.Errorf("target must be cluster/index (foo/0, foo-0 or foo/gpu/0)")
+	}
+	if err := useRecordedCluster(state, targetSpec.Cluster); err != nil {
+		return err
+	}
+	announceWithKey(state, selection, true)
 
-	targetSpec, err := target.Parse(targetRaw)
+	compute, err := state.ComputeClient(c.Context)
 	if err != nil {
 		return err
 	}
-	if !targetSpec.IsCluster {
-		return fmt.Errorf("target must be cluster/index (foo/0 or foo-0)")
-	}
 
 	resolved, err := ssh.ResolveClusterTarget(c.Context, compute, state.Config, targetRaw)
 	if err != nil {
@@ -484,23 +792,20 @@ func sshAction(c *cli.Context) error {
 
 	publicKey := selectionKey(selection)
 	if publicKey != "" {
-		if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, targetSpec); err != nil {
+		if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, targetSpec, resolved); err != nil {
 			return err
 		}
 	}
-	if err := ensureTargetReadyForSSH(c.Context, state, compute, targetSpec, resolved); err != nil {
+	identityFile := selectionIdentityPath(selection)
+	if err := ensureTargetReadyForSSH(c.Context, state, compute, targetSpec, resolved, user, identityFile); err != nil {
 		return err
 	}
 
 	commandArgs := sshCommandArgs(c.Args().Slice(), true)
-	identityFile := ""
-	if selection != nil {
-		identityFile = selection.IdentityPath
-	}
 	args := ssh.BuildSSHArgs(ssh.SSHOptions{
 		User:         user,
 		Host:         resolved.Host,
-		ProxyJump:    "",
+		ProxyJump:    resolved.ProxyJump(user),
 		ForwardAgent: true,
 		IdentityFile: identityFile,
 		Command:      commandArgs,
@@ -533,8 +838,6 @@ func scpAction(c *cli.Context) error {
 	if selection != nil && selection.Key != "" && strings.TrimSpace(user) == "" {
 		return fmt.Errorf("ssh.default_user is required to set ssh keys")
 	}
-	announceWithKey(state, selection, true)
-
 	flags, srcArg, dstArg, err := parseScpArgs(c.Args().Slice())
 	if err != nil {
 		return err
@@ -555,6 +858,14 @@ func scpAction(c *cli.Context) error {
 	if !srcSpec.IsRemote && !dstSpec.IsRemote {
 		return fmt.Errorf("either src or dst must be remote")
 	}
+	remoteSpec := dstSpec
+	if srcSpec.IsRemote {
+		remoteSpec = srcSpec
+	}
+	if err := useRecordedCluster(state, remoteSpec.Target.Cluster); err != nil {
+		return err
+	}
+	announceWithKey(state, selection, true)
 
 	compute, err := state.ComputeClient(c.Context)
 	if err != nil {
@@ -563,42 +874,39 @@ func scpAction(c *cli.Context) error {
 
 	var src string
 	var dst string
+	var resolved *ssh.ResolvedTarget
 	publicKey := selectionKey(selection)
 
 	if srcSpec.IsRemote {
-		resolved, err := ssh.ResolveClusterTarget(c.Context, compute, state.Config, srcSpec.Target.Raw)
+		resolved, err = ssh.ResolveClusterTarget(c.Context, compute, state.Config, srcSpec.Target.Raw)
 		if err != nil {
 			return err
 		}
 		if publicKey != "" {
-			if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, srcSpec.Target); err != nil {
+			if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, srcSpec.Target, resolved); err != nil {
 				return err
 			}
 		}
-		src = fmt.Sprintf("%s:%s", ssh.FormatUserHost(user, resolved.Host), ssh.NormalizePath(srcSpec.Path))
+		src = fmt.Sprintf("%s:%s", ssh.FormatRemoteHost(user, resolved.Host), ssh.NormalizePath(srcSpec.Path))
 		dst = dstArg
 	} else {
-		resolved, err := ssh.ResolveClusterTarget(c.Context, compute, state.Config, dstSpec.Target.Raw)
+		resolved, err = ssh.ResolveClusterTarget(c.Context, compute, state.Config, dstSpec.Target.Raw)
 		if err != nil {
 			return err
 		}
 		if publicKey != "" {
-			if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, dstSpec.Target); err != nil {
+			if err := ensureSSHKeysForTarget(c.Context, compute, state.Config, user, publicKey, dstSpec.Target, resolved); err != nil {
 				return err
 			}
 		}
 		src = srcArg
-		dst = fmt.Sprintf("%s:%s", ssh.FormatUserHost(user, resolved.Host), ssh.NormalizePath(dstSpec.Path))
+		dst = fmt.Sprintf("%s:%s", ssh.FormatRemoteHost(user, resolved.Host), ssh.NormalizePath(dstSpec.Path))
 	}
 
-	identityFile := ""
-	if selection != nil {
-		identityFile = selection.IdentityPath
-	}
 	args := append([]string{}, flags...)
 	args = append(args, ssh.BuildSCPArgs(ssh.SCPOptions{
-		ProxyJump:    "",
-		IdentityFile: identityFile,
+		ProxyJump:    resolved.ProxyJump(user),
+		IdentityFile: selectionIdentityPath(selection),
 		Src:          src,
 		Dst:          dst,
 	})...)
@@ -647,7 +955,99 @@ func clusterStateUpdateFn(state *State, clusterName string) func(name, instanceS
 	}
 }
 
-func ensureTargetReadyForSSH(ctx context.Context, state *State, compute gcp.Compute, targetSpec target.Target, resolved *ssh.ResolvedTarget) error {
+// clusterImageRecordFn records the boot image of every node created for
+// clusterName in state.
+func clusterImageRecordFn(state *State, clusterName string) func(name, image string) {
+	if state == nil || state.State == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	return func(name, image string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := state.State.RecordClusterInstanceImage(clusterName, name, image, time.Now()); err != nil {
+			state.Logger.Debug("failed to persist instance image", zap.String("cluster", clusterName), zap.String("instance", name), zap.Error(err))
+		}
+	}
+}
+
+// useRecordedCluster applies what state records for a cluster to
+// state.Config; see applyRecordedCluster.
+func useRecordedCluster(state *State, clusterName string) error {
+	if state == nil || state.State == nil || clusterName == "" {
+		return nil
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	if entry := data.Clusters[clusterName]; entry != nil {
+		applyRecordedCluster(state, entry)
+	}
+	return nil
+}
+
+// applyRecordedCluster points state.Config at the zone recorded for a
+// cluster, which differs from project.zone when a start fell back to another
+// zone, and at the cluster settings it was created with.
+func applyRecordedCluster(state *State, entry *appstate.Cluster) {
+	if entry.Zone != "" {
+		state.Config = state.Config.WithZone(entry.Zone)
+	}
+	state.Config = withRecordedNetwork(state.Config, entry.Config.Network)
+}
+
+// recordedNetwork copies the cluster settings of cfg that create records.
+func recordedNetwork(cfg *config.Config) *appstate.ClusterNetwork {
+	return &appstate.ClusterNetwork{
+		Placement:      cfg.Cluster.Placement,
+		PrivateWorkers: cfg.Cluster.PrivateWorkers,
+		NetworkMode:    cfg.Cluster.NetworkMode,
+		Network:        cfg.Cluster.Network,
+		Subnetwork:     cfg.Cluster.Subnetwork,
+		NetworkProject: cfg.Cluster.NetworkProject,
+	}
+}
+
+// withRecordedNetwork returns cfg with the cluster settings in recorded, or
+// cfg itself when nothing was recorded.
+func withRecordedNetwork(cfg *config.Config, recorded *appstate.ClusterNetwork) *config.Config {
+	if recorded == nil {
+		return cfg
+	}
+	clone := *cfg
+	clone.Cluster.Placement = recorded.Placement
+	clone.Cluster.PrivateWorkers = recorded.PrivateWorkers
+	clone.Cluster.NetworkMode = recorded.NetworkMode
+	clone.Cluster.Network = recorded.Network
+	clone.Cluster.Subnetwork = recorded.Subnetwork
+	clone.Cluster.NetworkProject = recorded.NetworkProject
+	return &clone
+}
+
+func parseZonesValue(c *cli.Context) ([]string, error) {
+	raw, ok, err := parseStringFlagValue(c, "--zones", "zones")
+	if err != nil || !ok {
+		return nil, err
+	}
+	zones := []string{}
+	for _, zone := range strings.Split(raw, ",") {
+		zone = strings.TrimSpace(zone)
+		if zone == "" {
+			continue
+		}
+		if _, err := gcp.RegionFromZone(zone); err != nil {
+			return nil, fmt.Errorf("--zones: %w", err)
+		}
+		zones = append(zones, zone)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("--zones requires at least one zone")
+	}
+	return zones, nil
+}
+
+func ensureTargetReadyForSSH(ctx context.Context, state *State, compute gcp.Compute, targetSpec target.Target, resolved *ssh.ResolvedTarget, user, identityFile string) error {
 	if state == nil || state.State == nil || !targetSpec.IsCluster {
 		return nil
 	}
@@ -671,29 +1071,44 @@ func ensureTargetReadyForSSH(ctx context.Context, state *State, compute gcp.Comp
 		return fmt.Errorf("%s is TERMINATED; run `gpunow start %s` first", targetSpec.Name, targetSpec.Cluster)
 	case lifecycle.InstanceStateTerminating:
 		return fmt.Errorf("%s is TERMINATING; wait for stop to finish", targetSpec.Name)
+	case lifecycle.InstanceStateSuspended, lifecycle.InstanceStateSuspending:
+		return fmt.Errorf("%s is %s; run `gpunow resume %s` first", targetSpec.Name, instanceState, targetSpec.Cluster)
 	}
 
 	state.UI.Infof("%s is %s; waiting until READY", targetSpec.Name, instanceState)
-	if err := state.State.RecordClusterInstanceState(targetSpec.Cluster, targetSpec.Name, lifecycle.InstanceStateProvisioning, resolved.Host, "", time.Now()); err != nil {
+	externalIP, internalIP := resolved.Host, ""
+	var jump *cluster.ReadinessJump
+	if resolved.Private {
+		externalIP, internalIP = "", resolved.Host
+		jump = &cluster.ReadinessJump{User: user, Host: resolved.MasterPublicIP, IdentityFile: identityFile}
+	}
+	if err := state.State.RecordClusterInstanceState(targetSpec.Cluster, targetSpec.Name, lifecycle.InstanceStateProvisioning, externalIP, internalIP, time.Now()); err != nil {
 		state.Logger.Debug("failed to persist provisioning state before ssh wait", zap.String("cluster", targetSpec.Cluster), zap.String("instance", targetSpec.Name), zap.Error(err))
 	}
 	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
-	if err := service.WaitForReady(ctx, targetSpec.Name, resolved.Host, 10*time.Minute); err != nil {
+	if err := service.WaitForReady(ctx, targetSpec.Name, resolved.Host, jump, 10*time.Minute); err != nil {
 		return err
 	}
-	if err := state.State.RecordClusterInstanceState(targetSpec.Cluster, targetSpec.Name, lifecycle.InstanceStateReady, resolved.Host, "", time.Now()); err != nil {
+	if err := state.State.RecordClusterInstanceState(targetSpec.Cluster, targetSpec.Name, lifecycle.InstanceStateReady, externalIP, internalIP, time.Now()); err != nil {
 		state.Logger.Debug("failed to persist ready state after ssh wait", zap.String("cluster", targetSpec.Cluster), zap.String("instance", targetSpec.Name), zap.Error(err))
 	}
 	return nil
 }
 
-func ensureSSHKeysForTarget(ctx context.Context, compute gcp.Compute, cfg *config.Config, user string, publicKey string, targetSpec target.Target) error {
+// ensureSSHKeysForTarget adds the key to the target and, for private
+// workers, to the master the connection jumps through.
+func ensureSSHKeysForTarget(ctx context.Context, compute gcp.Compute, cfg *config.Config, user string, publicKey string, targetSpec target.Target, resolved *ssh.ResolvedTarget) error {
 	if publicKey == "" {
 		return nil
 	}
 	if err := ssh.EnsureInstanceSSHKey(ctx, compute, cfg, targetSpec.Name, user, publicKey); err != nil {
 		return fmt.Errorf("ensure ssh key on %s: %w", targetSpec.Name, err)
 	}
+	if resolved != nil && resolved.Private {
+		if err := ssh.EnsureInstanceSSHKey(ctx, compute, cfg, resolved.MasterName, user, publicKey); err != nil {
+			return fmt.Errorf("ensure ssh key on %s: %w", resolved.MasterName, err)
+		}
+	}
 	return nil
 }
 
@@ -821,9 +1236,43 @@ func applyClusterConfig(startOptions cluster.StartOptions, clusterConfig appstat
 		startOptions.DiskSizeGB = clusterConfig.GCPDiskSizeGB
 	}
 	startOptions.KeepDisks = clusterConfig.KeepDisks
+	startOptions.SSHSourceRanges = clusterConfig.SSHSourceRanges
+	startOptions.PortSourceRanges = clusterConfig.PortSourceRanges
+	startOptions.Ports = clusterConfig.Ports
+	startOptions.SubnetCIDR = clusterConfig.SubnetCIDR
+	startOptions.Labels = clusterConfig.Labels
+	startOptions.Metadata = clusterConfig.Metadata
+	if len(clusterConfig.Pools) > 0 {
+		startOptions.Pools = make([]cluster.Pool, 0, len(clusterConfig.Pools))
+		for _, pool := range clusterConfig.Pools {
+			startOptions.Pools = append(startOptions.Pools, cluster.Pool{Name: pool.Name, Count: pool.Count})
+		}
+		startOptions.NumInstances = poolsTotal(clusterConfig.Pools)
+	}
 	return startOptions
 }
 
+// profilePools returns the cluster.pools counts declared by the profile, or
+// nil when it has none.
+func profilePools(cfg *config.Config) []appstate.ClusterPool {
+	if len(cfg.Cluster.Pools) == 0 {
+		return nil
+	}
+	pools := make([]appstate.ClusterPool, 0, len(cfg.Cluster.Pools))
+	for _, pool := range cfg.Cluster.Pools {
+		pools = append(pools, appstate.ClusterPool{Name: pool.Name, Count: pool.Count})
+	}
+	return pools
+}
+
+func poolsTotal(pools []appstate.ClusterPool) int {
+	total := 0
+	for _, pool := range pools {
+		total += pool.Count
+	}
+	return total
+}
+
 func parseMaxHoursValue(c *cli.Context) (int, bool, error) {
 	return parseIntFlagValue(c, "max-hours", "", "--max-hours", "--max-hours must be a positive integer")
 }
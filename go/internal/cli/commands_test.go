@@ -1,6 +1,12 @@
 package cli
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"gpunow/internal/config"
+	appstate "gpunow/internal/state"
+)
 
 func TestParseIntFlagFromArgs(t *testing.T) {
 	tests := []struct {
@@ -138,3 +144,96 @@ func TestResolveStopKeepDisks(t *testing.T) {
 		})
 	}
 }
+
+func TestResizePool(t *testing.T) {
+	pools := []appstate.ClusterPool{{Name: "head", Count: 1}, {Name: "gpu", Count: 4}}
+
+	resized, err := resizePool(pools, "gpu", 0)
+	if err != nil {
+		t.Fatalf("resize gpu: %v", err)
+	}
+	if resized[1].Count != 0 || pools[1].Count != 4 {
+		t.Fatalf("expected a resized copy, got %+v (original %+v)", resized, pools)
+	}
+	if _, err := resizePool(pools, "head", 0); err == nil {
+		t.Fatalf("expected error when emptying the master pool")
+	}
+	if _, err := resizePool(pools, "tpu", 2); err == nil {
+		t.Fatalf("expected error for unknown pool")
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	got, err := parseKeyValues("--labels", "team=vision, env=", labelKeyPattern, labelValuePattern)
+	if err != nil {
+		t.Fatalf("parse labels: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"team": "vision", "env": ""}) {
+		t.Fatalf("labels = %v", got)
+	}
+	for _, bad := range []string{"", "team", "Team=vision", "team=Vision", "9team=x"} {
+		if _, err := parseKeyValues("--labels", bad, labelKeyPattern, labelValuePattern); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	got, err = parseKeyValues("--metadata", "WANDB_PROJECT=Sweep 7", metadataKeyPattern, nil)
+	if err != nil || got["WANDB_PROJECT"] != "Sweep 7" {
+		t.Fatalf("metadata = %v, %v", got, err)
+	}
+}
+
+func TestMergeClusterUpdate(t *testing.T) {
+	current := appstate.ClusterConfig{
+		GCPMachineType: "g2-standard-16",
+		GCPMaxRunHours: 12,
+		Labels:         map[string]string{"team": "vision"},
+	}
+	merged := mergeClusterUpdate(current, appstate.ClusterConfig{
+		GCPMachineType:       "g2-standard-48",
+		GCPTerminationAction: "DELETE",
+		Labels:               map[string]string{"env": "dev"},
+	})
+	if merged.GCPMachineType != "g2-standard-48" || merged.GCPMaxRunHours != 12 || merged.GCPTerminationAction != "DELETE" {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+	if !reflect.DeepEqual(merged.Labels, map[string]string{"team": "vision", "env": "dev"}) || len(current.Labels) != 1 {
+		t.Fatalf("labels = %v (current %v)", merged.Labels, current.Labels)
+	}
+}
+
+func TestCheckPooledMachineType(t *testing.T) {
+	pooled := appstate.ClusterConfig{Pools: []appstate.ClusterPool{{Name: "gpu", Count: 2}}}
+	change := appstate.ClusterConfig{GCPMachineType: "g2-standard-48"}
+	if err := checkPooledMachineType("train", pooled, change, nil); err == nil {
+		t.Fatalf("expected a whole-cluster machine type change on a pooled cluster to be refused")
+	}
+	if err := checkPooledMachineType("train", pooled, change, []string{"train-gpu-0"}); err != nil {
+		t.Fatalf("pool target: %v", err)
+	}
+	if err := checkPooledMachineType("train", appstate.ClusterConfig{}, change, nil); err != nil {
+		t.Fatalf("cluster without pools: %v", err)
+	}
+	if err := checkPooledMachineType("train", pooled, appstate.ClusterConfig{GCPMaxRunHours: 4}, nil); err != nil {
+		t.Fatalf("max hours on a pooled cluster: %v", err)
+	}
+}
+
+func TestWithRecordedNetwork(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cluster.Placement = "compact"
+	cfg.Cluster.PrivateWorkers = true
+	cfg.Cluster.NetworkMode = "per_cluster"
+	if got := withRecordedNetwork(cfg, nil); got != cfg {
+		t.Fatalf("expected the profile to be used when nothing was recorded")
+	}
+	got := withRecordedNetwork(cfg, &appstate.ClusterNetwork{NetworkMode: "existing", Network: "shared", Subnetwork: "shared-east", NetworkProject: "host"})
+	if got.Cluster.Placement != "" || got.Cluster.PrivateWorkers || got.ClusterNetworkProject() != "host" || got.Cluster.Subnetwork != "shared-east" {
+		t.Fatalf("expected the recorded settings to win, got %+v", got.Cluster)
+	}
+	if cfg.Cluster.Placement != "compact" || !cfg.Cluster.PrivateWorkers || cfg.Cluster.NetworkMode != "per_cluster" {
+		t.Fatalf("profile config must not be modified")
+	}
+	if recorded := recordedNetwork(cfg); recorded.Placement != "compact" || !recorded.PrivateWorkers || recorded.NetworkMode != "per_cluster" {
+		t.Fatalf("unexpected recorded settings: %+v", recorded)
+	}
+}
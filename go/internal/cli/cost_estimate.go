@@ -4,69 +4,116 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"cloud.google.com/go/compute/apiv1/computepb"
 
+	"gpunow/internal/config"
 	"gpunow/internal/gcp"
 	"gpunow/internal/pricing"
 	appstate "gpunow/internal/state"
 )
 
-func estimateClusterCreateCost(ctx context.Context, state *State, compute gcp.Compute, numInstances int, refresh bool, clusterConfig appstate.ClusterConfig) error {
-	machineType := strings.TrimSpace(state.Config.Instance.MachineType)
+// costGroup is a set of identical nodes priced together: the whole cluster,
+// or one of its pools.
+type costGroup struct {
+	Pool         string
+	MachineType  string
+	DiskSizeGB   int
+	NumInstances int
+}
+
+// costGroups splits the cluster into groups with the settings their nodes
+// are created with: a pool's machine_type wins over the cluster's, and its
+// disk_size_gb over a smaller cluster disk size.
+func costGroups(cfg *config.Config, numInstances int, clusterConfig appstate.ClusterConfig) []costGroup {
+	machineType := strings.TrimSpace(cfg.Instance.MachineType)
 	if override := strings.TrimSpace(clusterConfig.GCPMachineType); override != "" {
 		machineType = override
 	}
+	diskSizeGB := cfg.Disk.SizeGB
+	if clusterConfig.GCPDiskSizeGB > 0 {
+		diskSizeGB = clusterConfig.GCPDiskSizeGB
+	}
+	if len(clusterConfig.Pools) == 0 {
+		return []costGroup{{MachineType: machineType, DiskSizeGB: diskSizeGB, NumInstances: numInstances}}
+	}
+	groups := make([]costGroup, 0, len(clusterConfig.Pools))
+	for _, pool := range clusterConfig.Pools {
+		group := costGroup{Pool: pool.Name, MachineType: machineType, DiskSizeGB: diskSizeGB, NumInstances: pool.Count}
+		if spec, ok := cfg.Pool(pool.Name); ok {
+			if poolMachineType := strings.TrimSpace(spec.MachineType); poolMachineType != "" {
+				group.MachineType = poolMachineType
+			}
+			group.DiskSizeGB = max(group.DiskSizeGB, spec.DiskSizeGB)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func estimateClusterCreateCost(ctx context.Context, state *State, compute gcp.Compute, numInstances int, refresh bool, clusterConfig appstate.ClusterConfig) error {
+	groups := costGroups(state.Config, numInstances, clusterConfig)
+	machineTypes := []string{}
+	for _, group := range groups {
+		if !slices.Contains(machineTypes, group.MachineType) {
+			machineTypes = append(machineTypes, group.MachineType)
+		}
+	}
 	split := state.UI.StartLiveSplit()
 	if split != nil {
 		defer split.Stop()
 	}
-	progress := state.UI.TaskList("Estimating", []string{
-		fmt.Sprintf("Loading machine type %s", machineType),
-		"Resolving pricing data",
-	})
+	taskNames := []string{}
+	for _, machineType := range machineTypes {
+		taskNames = append(taskNames, fmt.Sprintf("Loading machine type %s", machineType))
+	}
+	resolveTask := len(taskNames)
+	progress := state.UI.TaskList("Estimating", append(taskNames, "Resolving pricing data"))
 	defer progress.Stop()
 
 	project := state.Config.Project.ID
 	zone := state.Config.Project.Zone
-	machineTypeCall := state.UI.APICall("compute.machineTypes.get", gcp.ZoneResource(project, zone, "machineTypes", machineType), "")
-	mt, err := compute.GetMachineType(ctx, &computepb.GetMachineTypeRequest{
-		Project:     project,
-		Zone:        zone,
-		MachineType: machineType,
-	})
-	machineTypeCall.Stop()
-	if err != nil {
-		progress.MarkWarning(0, fmt.Sprintf("Failed machineTypes/%s", machineType))
-		return fmt.Errorf("load machine type %s for cost estimation: %w", machineType, err)
-	}
-	progress.MarkDone(0, fmt.Sprintf("Loaded machineTypes/%s", machineType))
-
-	vcpu := mt.GetGuestCpus()
-	memoryMB := mt.GetMemoryMb()
-	if vcpu <= 0 || memoryMB <= 0 {
-		return fmt.Errorf("machine type %s did not return usable CPU/RAM specs", machineType)
-	}
-	gpuType, gpuCount, err := machineTypeGPU(mt)
-	if err != nil {
-		return err
+	specs := map[string]*computepb.MachineType{}
+	for i, machineType := range machineTypes {
+		machineTypeCall := state.UI.APICall("compute.machineTypes.get", gcp.ZoneResource(project, zone, "machineTypes", machineType), "")
+		mt, err := compute.GetMachineType(ctx, &computepb.GetMachineTypeRequest{
+			Project:     project,
+			Zone:        zone,
+			MachineType: machineType,
+		})
+		machineTypeCall.Stop()
+		if err != nil {
+			progress.MarkWarning(i, fmt.Sprintf("Failed machineTypes/%s", machineType))
+			return fmt.Errorf("load machine type %s for cost estimation: %w", machineType, err)
+		}
+		progress.MarkDone(i, fmt.Sprintf("Loaded machineTypes/%s", machineType))
+		if mt.GetGuestCpus() <= 0 || mt.GetMemoryMb() <= 0 {
+			return fmt.Errorf("machine type %s did not return usable CPU/RAM specs", machineType)
+		}
+		specs[machineType] = mt
 	}
 
 	maxRunHours := state.Config.Instance.MaxRunHours
 	if clusterConfig.GCPMaxRunHours > 0 {
 		maxRunHours = clusterConfig.GCPMaxRunHours
 	}
-	diskSizeGB := state.Config.Disk.SizeGB
-	if clusterConfig.GCPDiskSizeGB > 0 {
-		diskSizeGB = clusterConfig.GCPDiskSizeGB
+
+	extraDisks := []pricing.Disk{}
+	for _, extra := range state.Config.Disk.Extra {
+		if extra.LocalSSD() {
+			extraDisks = append(extraDisks, pricing.Disk{Type: extra.Type, SizeGB: extra.Count * config.LocalSSDSizeGB})
+			continue
+		}
+		extraDisks = append(extraDisks, pricing.Disk{Type: extra.Type, SizeGB: extra.SizeGB})
 	}
 
 	cachePath := filepath.Join(state.Home.StateDir, "pricing-cache.json")
 	cacheStore := pricing.NewCacheStore(cachePath)
 	catalog, err := pricing.NewCloudCatalog(ctx)
 	if err != nil {
-		progress.MarkWarning(1, "Failed to initialize Cloud Billing API client")
+		progress.MarkWarning(resolveTask, "Failed to initialize Cloud Billing API client")
 		return err
 	}
 	catalog.SetListObserver(func(action string, resource string) func() {
@@ -76,45 +123,73 @@ func estimateClusterCreateCost(ctx context.Context, state *State, compute gcp.Co
 		}
 	})
 
+	// Each group is estimated on its own. Without --refresh, SKUs resolved
+	// for one pool come from the cache for the next.
 	estimator := pricing.NewEstimator(cacheStore, catalog)
-	result, err := estimator.Estimate(ctx, pricing.Request{
-		Currency:          "USD",
-		Zone:              zone,
-		MachineType:       machineType,
-		VCPU:              int64(vcpu),
-		MemoryMB:          int64(memoryMB),
-		ProvisioningModel: state.Config.Instance.ProvisioningModel,
-		GPUType:           gpuType,
-		GPUCount:          gpuCount,
-		DiskType:          state.Config.Disk.Type,
-		DiskSizeGB:        diskSizeGB,
-		NumInstances:      numInstances,
-		MaxRunHours:       maxRunHours,
-		Refresh:           refresh,
-	})
-	if err != nil {
-		progress.MarkWarning(1, "Failed pricing lookup")
-		return fmt.Errorf("estimate cost: %w", err)
+	results := make([]*pricing.Result, len(groups))
+	fetched := false
+	for i, group := range groups {
+		mt := specs[group.MachineType]
+		gpuType, gpuCount, err := machineTypeGPU(mt)
+		if err != nil {
+			return err
+		}
+		result, err := estimator.Estimate(ctx, pricing.Request{
+			Currency:          "USD",
+			Zone:              zone,
+			MachineType:       group.MachineType,
+			VCPU:              int64(mt.GetGuestCpus()),
+			MemoryMB:          int64(mt.GetMemoryMb()),
+			ProvisioningModel: state.Config.Instance.ProvisioningModel,
+			GPUType:           gpuType,
+			GPUCount:          gpuCount,
+			DiskType:          state.Config.Disk.Type,
+			DiskSizeGB:        group.DiskSizeGB,
+			ExtraDisks:        extraDisks,
+			NumInstances:      group.NumInstances,
+			MaxRunHours:       maxRunHours,
+			Refresh:           refresh,
+		})
+		if err != nil {
+			progress.MarkWarning(resolveTask, "Failed pricing lookup")
+			return fmt.Errorf("estimate cost: %w", err)
+		}
+		fetched = fetched || result.FetchedSKUs
+		results[i] = result
 	}
-	if result.FetchedSKUs {
-		progress.MarkDone(1, "Loaded pricing catalog from Cloud Billing API")
+	if fetched {
+		progress.MarkDone(resolveTask, "Loaded pricing catalog from Cloud Billing API")
 	} else {
-		progress.MarkDone(1, "Using cached pricing data")
+		progress.MarkDone(resolveTask, "Using cached pricing data")
 	}
 
 	state.UI.Heading("Cost estimate")
-	state.UI.Infof("Instances: %d | Machine: %s | Zone: %s", numInstances, machineType, zone)
-	for _, component := range result.Components {
-		state.UI.Infof("%s: $%.6f per %s", component.Name, component.UnitPrice, component.UsageUnit)
-		state.UI.InfofIndent(1, "Quantity: %.2f %s per instance", component.QuantityPerInstance, component.QuantityUnit)
-		state.UI.InfofIndent(1, "Total: $%.4f/hour | $%.4f for %d hours", component.CostPerHour, component.CostForMaxRun, result.MaxRunHours)
-	}
-	state.UI.Successf("Estimated total (%s): $%.4f/hour", result.Currency, result.TotalPerHour)
-	state.UI.Successf("Estimated max-run total (%d hours): $%.4f", result.MaxRunHours, result.TotalForMaxRun)
-	if result.FetchedSKUs {
-		state.UI.Infof("Pricing source: Cloud Billing API (cache updated at %s)", result.CachePath)
+	indent := 0
+	if len(clusterConfig.Pools) == 0 {
+		state.UI.Infof("Instances: %d | Machine: %s | Zone: %s", numInstances, groups[0].MachineType, zone)
+	} else {
+		state.UI.Infof("Instances: %d | Zone: %s", numInstances, zone)
+		indent = 1
+	}
+	var totalPerHour, totalForMaxRun float64
+	for i, result := range results {
+		if group := groups[i]; group.Pool != "" {
+			state.UI.Infof("Pool %s: %d instances | Machine: %s", group.Pool, group.NumInstances, group.MachineType)
+		}
+		for _, component := range result.Components {
+			state.UI.InfofIndent(indent, "%s: $%.6f per %s", component.Name, component.UnitPrice, component.UsageUnit)
+			state.UI.InfofIndent(indent+1, "Quantity: %.2f %s per instance", component.QuantityPerInstance, component.QuantityUnit)
+			state.UI.InfofIndent(indent+1, "Total: $%.4f/hour | $%.4f for %d hours", component.CostPerHour, component.CostForMaxRun, result.MaxRunHours)
+		}
+		totalPerHour += result.TotalPerHour
+		totalForMaxRun += result.TotalForMaxRun
+	}
+	state.UI.Successf("Estimated total (%s): $%.4f/hour", results[0].Currency, totalPerHour)
+	state.UI.Successf("Estimated max-run total (%d hours): $%.4f", maxRunHours, totalForMaxRun)
+	if fetched {
+		state.UI.Infof("Pricing source: Cloud Billing API (cache updated at %s)", cacheStore.Path)
 	} else {
-		state.UI.Infof("Pricing source: local cache (%s)", result.CachePath)
+		state.UI.Infof("Pricing source: local cache (%s)", cacheStore.Path)
 	}
 	state.UI.Infof("Estimate excludes egress, discounts, credits, taxes, and license premiums.")
 	fmt.Fprintln(state.UI.Out)
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gpunow/internal/config"
+	appstate "gpunow/internal/state"
+)
+
+func TestCostGroupsPricePoolsWithTheirOwnSettings(t *testing.T) {
+	cfg, err := config.Load("default", filepath.Join("..", "..", "..", "profiles"))
+	if err != nil {
+		t.Fatalf("load default config: %v", err)
+	}
+
+	want := []costGroup{{MachineType: "g2-standard-16", DiskSizeGB: 200, NumInstances: 3}}
+	if got := costGroups(cfg, 3, appstate.ClusterConfig{}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected groups without pools: %+v, want %+v", got, want)
+	}
+
+	cfg.Cluster.Pools = []config.PoolConfig{
+		{Name: "head", Count: 1, MachineType: "n2-standard-8", DiskSizeGB: 100},
+		{Name: "gpu", Count: 2, MachineType: "g2-standard-48", DiskSizeGB: 500},
+		{Name: "cpu", Count: 1},
+	}
+	clusterConfig := appstate.ClusterConfig{Pools: profilePools(cfg), GCPMachineType: "g2-standard-24"}
+	want = []costGroup{
+		{Pool: "head", MachineType: "n2-standard-8", DiskSizeGB: 200, NumInstances: 1},
+		{Pool: "gpu", MachineType: "g2-standard-48", DiskSizeGB: 500, NumInstances: 2},
+		{Pool: "cpu", MachineType: "g2-standard-24", DiskSizeGB: 200, NumInstances: 1},
+	}
+	if got := costGroups(cfg, 4, clusterConfig); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected pool groups: %+v, want %+v", got, want)
+	}
+}
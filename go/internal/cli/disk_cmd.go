@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+func diskCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "disk",
+		Usage: "Manage the boot disks of cluster nodes",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "resize",
+				Usage:     "Grow node boot disks and their root filesystems in place",
+				ArgsUsage: "<cluster|cluster/index> --size-gb N",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "size-gb", Usage: "New boot disk size in GB"},
+				},
+				Action: diskResize,
+			},
+		},
+	}
+}
+
+func diskResize(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
+	if err != nil {
+		return err
+	}
+	clusterName, nodeNames, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	sizeGB, ok, err := parseIntFlagValue(c, "size-gb", "", "--size-gb", "--size-gb must be a positive integer")
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if !ok {
+		return usageError(c, "--size-gb is required")
+	}
+	if sizeGB <= 0 {
+		return usageError(c, "--size-gb must be a positive integer")
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	announceWithKey(state, selection, true)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.ResizeDisks(c.Context, clusterName, cluster.DiskResizeOptions{
+		SizeGB:          sizeGB,
+		Nodes:           nodeNames,
+		SSHUser:         strings.TrimSpace(state.Config.SSH.DefaultUser),
+		SSHPublicKey:    selectionKey(selection),
+		SSHIdentityFile: selectionIdentityPath(selection),
+	}); err != nil {
+		return err
+	}
+	// Only a whole-cluster resize changes the size new nodes are created with.
+	if nodeNames == nil && state.State != nil {
+		if err := state.State.RecordClusterDiskSize(clusterName, sizeGB, time.Now()); err != nil {
+			state.UI.Warnf("Failed to update state: %v", err)
+		}
+	}
+	state.UI.Successf("Resized boot disks of %s to %d GB", raw, sizeGB)
+	return nil
+}
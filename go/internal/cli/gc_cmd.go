@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+func gcCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "gc",
+		Usage: "Find and delete gpunow resources with no entry in local state",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dry-run", Usage: "List orphaned resources without deleting them"},
+			&cli.DurationFlag{Name: "older-than", Value: time.Hour, Usage: "Only collect resources created at least this long ago"},
+			&cli.BoolFlag{Name: "yes", Aliases: []string{"y"}, Usage: "Delete without asking for confirmation"},
+		},
+		Action: gcAction,
+	}
+}
+
+func gcAction(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	if state.State == nil {
+		return fmt.Errorf("state store is unavailable")
+	}
+	olderThan := c.Duration("older-than")
+	if olderThan < 0 {
+		return usageError(c, "--older-than must not be negative")
+	}
+	dryRun := c.Bool("dry-run") || hasBoolArg(c.Args().Slice(), "dry-run")
+	yes := c.Bool("yes") || hasBoolArg(c.Args().Slice(), "yes")
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	zones := state.Config.Zones()
+	networkProjects := []string{state.Config.ClusterNetworkProject()}
+	for _, name := range sortedKeys(data.Clusters) {
+		entry := data.Clusters[name]
+		if zone := entry.Zone; zone != "" && !slices.Contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+		if project := withRecordedNetwork(state.Config, entry.Config.Network).ClusterNetworkProject(); !slices.Contains(networkProjects, project) {
+			networkProjects = append(networkProjects, project)
+		}
+	}
+	announce(state)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	orphans, err := service.FindOrphans(c.Context, cluster.GCOptions{
+		Zones:           zones,
+		NetworkProjects: networkProjects,
+		OlderThan:       olderThan,
+		Now:             time.Now(),
+		KnownCluster: func(name string) bool {
+			entry := data.Clusters[name]
+			return entry != nil && entry.Status != "deleted"
+		},
+		KnownVM: func(name string) bool {
+			entry := data.VMs[name]
+			return entry != nil && entry.Status != "deleted"
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		state.UI.Successf("No orphaned resources older than %s", olderThan)
+		return nil
+	}
+
+	state.UI.Heading("Orphaned resources")
+	state.UI.Infof("%-18s %-36s %-16s %-16s %s", "KIND", "NAME", "CLUSTER", "LOCATION", "AGE")
+	for _, orphan := range orphans {
+		age := time.Since(orphan.CreatedAt).Round(time.Minute)
+		state.UI.Infof("%-18s %-36s %-16s %-16s %s", orphan.Kind, orphan.Name, emptyDash(orphan.Cluster), orphan.Location(), age)
+	}
+	if dryRun {
+		state.UI.Infof("Dry run: %d resources would be deleted", len(orphans))
+		return nil
+	}
+	if !yes {
+		if !confirm(c, fmt.Sprintf("Delete %d orphaned resources?", len(orphans))) {
+			state.UI.Infof("Nothing deleted")
+			return nil
+		}
+	}
+	if err := service.DeleteOrphans(c.Context, orphans); err != nil {
+		return err
+	}
+	state.UI.Successf("Deleted %d orphaned resources", len(orphans))
+	return nil
+}
+
+// confirm asks a yes/no question on the app's reader; anything but y/yes is
+// a no.
+func confirm(c *cli.Context, question string) bool {
+	fmt.Fprintf(c.App.Writer, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(c.App.Reader).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+	appstate "gpunow/internal/state"
+	"gpunow/internal/target"
+)
+
+func resetCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "reset",
+		Usage:     "Hard-reset running cluster nodes and wait for them to become ready",
+		ArgsUsage: "<cluster|cluster/index>",
+		Action:    resetNodes,
+	}
+}
+
+func resetNodes(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
+	if err != nil {
+		return err
+	}
+	clusterName, names, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	announceWithKey(state, selection, false)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.Reset(c.Context, clusterName, names, cluster.StartOptions{
+		SSHUser:         strings.TrimSpace(state.Config.SSH.DefaultUser),
+		SSHIdentityFile: selectionIdentityPath(selection),
+		OnStateChange:   clusterStateUpdateFn(state, clusterName),
+	}); err != nil {
+		return err
+	}
+	state.UI.Successf("Reset %s", raw)
+	return nil
+}
+
+// resolveNodeSelection splits a lifecycle command argument into the cluster
+// name and the selected node names; names is nil when the argument selects
+// the whole cluster.
+func resolveNodeSelection(state *State, raw string) (string, []string, error) {
+	clusters := map[string]*appstate.Cluster{}
+	if state != nil && state.State != nil {
+		data, err := state.State.Load()
+		if err != nil {
+			return "", nil, err
+		}
+		clusters = data.Clusters
+	}
+	return splitNodeSelection(raw, clusters)
+}
+
+// splitNodeSelection accepts a cluster name or a node selection
+// (cluster/2, cluster-2, cluster/1-3, cluster/gpu/0). A cluster recorded in
+// state wins over the cluster-N alias, so clusters whose names end in a
+// number keep addressing the whole cluster; the alias is only taken for
+// clusters in state. Selected indexes must lie within the recorded layout.
+func splitNodeSelection(raw string, clusters map[string]*appstate.Cluster) (string, []string, error) {
+	raw = strings.TrimSpace(raw)
+	if clusters[raw] != nil {
+		return raw, nil, nil
+	}
+	if !strings.Contains(raw, "/") {
+		parsed, err := target.ParseSelection(raw)
+		if err != nil || clusters[parsed.Cluster] == nil {
+			return raw, nil, nil
+		}
+		if err := checkSelectionLayout(parsed, clusters[parsed.Cluster]); err != nil {
+			return "", nil, err
+		}
+		return parsed.Cluster, parsed.Names(), nil
+	}
+	parsed, err := target.ParseSelection(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid target %s: %w", raw, err)
+	}
+	if entry := clusters[parsed.Cluster]; entry != nil {
+		if err := checkSelectionLayout(parsed, entry); err != nil {
+			return "", nil, err
+		}
+	}
+	return parsed.Cluster, parsed.Names(), nil
+}
+
+// checkSelectionLayout refuses indexes beyond the node count of the cluster,
+// or of the selected pool.
+func checkSelectionLayout(parsed target.Selection, entry *appstate.Cluster) error {
+	size := entry.NumInstances
+	if parsed.Pool != "" {
+		size = -1
+		for _, pool := range entry.Config.Pools {
+			if pool.Name == parsed.Pool {
+				size = pool.Count
+			}
+		}
+		if size < 0 {
+			return fmt.Errorf("cluster %s has no pool %s", parsed.Cluster, parsed.Pool)
+		}
+	}
+	for _, index := range parsed.Indices {
+		if index >= size {
+			return fmt.Errorf("invalid target %s: %s has %d nodes", parsed.Raw, strings.TrimSuffix(parsed.Cluster+"/"+parsed.Pool, "/"), size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	appstate "gpunow/internal/state"
+)
+
+func TestSplitNodeSelection(t *testing.T) {
+	known := map[string]*appstate.Cluster{
+		"train": {NumInstances: 4, Config: appstate.ClusterConfig{Pools: []appstate.ClusterPool{{Name: "cpu", Count: 1}, {Name: "gpu", Count: 3}}}},
+		"job-2": {NumInstances: 2},
+	}
+	cases := []struct {
+		raw     string
+		cluster string
+		names   []string
+	}{
+		{raw: "train", cluster: "train"},
+		{raw: "train/1-2", cluster: "train", names: []string{"train-1", "train-2"}},
+		{raw: "train-1", cluster: "train", names: []string{"train-1"}},
+		{raw: "train/gpu/0", cluster: "train", names: []string{"train-gpu-0"}},
+		// A recorded cluster wins over the cluster-N alias.
+		{raw: "job-2", cluster: "job-2"},
+		{raw: "job-2-0", cluster: "job-2", names: []string{"job-2-0"}},
+	}
+	for _, tc := range cases {
+		cluster, names, err := splitNodeSelection(tc.raw, known)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.raw, err)
+		}
+		if cluster != tc.cluster || !reflect.DeepEqual(names, tc.names) {
+			t.Fatalf("%s: got %s %v, want %s %v", tc.raw, cluster, names, tc.cluster, tc.names)
+		}
+	}
+	for _, raw := range []string{"train/2-1", "train/0-999999999", "train/4", "train/gpu/3", "train/tpu/0"} {
+		if _, _, err := splitNodeSelection(raw, known); err == nil {
+			t.Fatalf("%s: expected an error", raw)
+		}
+	}
+
+	// Without the cluster in state, cluster-N is taken as a cluster name.
+	cluster, names, err := splitNodeSelection("train-2", nil)
+	if err != nil || cluster != "train-2" || names != nil {
+		t.Fatalf("train-2 without state: got %s %v %v", cluster, names, err)
+	}
+}
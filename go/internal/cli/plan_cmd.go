@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+	appstate "gpunow/internal/state"
+)
+
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "plan",
+		Usage: "Preview the resources a start or stop would touch",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "start",
+				Usage:     "Preview a cluster start",
+				ArgsUsage: "<cluster>",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "num-instances", Aliases: []string{"n"}, Usage: "Number of instances (defaults to the count in state)"},
+					&cli.StringFlag{Name: "zones", Usage: "Comma-separated zones; only the first is planned"},
+				},
+				Action: planStart,
+			},
+			{
+				Name:      "stop",
+				Usage:     "Preview a cluster stop",
+				ArgsUsage: "<cluster>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "delete", Usage: "Preview deleting instances and cluster networking"},
+					&cli.BoolFlag{Name: "keep-disks", Usage: "Keep boot disks when deleting instances"},
+					&cli.BoolFlag{Name: "delete-disks", Usage: "Delete boot disks when deleting instances"},
+				},
+				Action: planStop,
+			},
+		},
+	}
+}
+
+func planStart(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	numInstances, numInstancesExplicit, err := parseNumInstancesValue(c)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if numInstancesExplicit && numInstances <= 0 {
+		return usageError(c, "--num-instances must be a positive integer")
+	}
+	clusterConfig := appstate.ClusterConfig{Pools: profilePools(state.Config)}
+	if state.State != nil {
+		data, err := state.State.Load()
+		if err != nil {
+			return err
+		}
+		if entry := data.Clusters[clusterName]; entry != nil {
+			clusterConfig = entry.Config
+			if !numInstancesExplicit {
+				numInstances = entry.NumInstances
+			}
+			applyRecordedCluster(state, entry)
+		}
+	}
+	if len(clusterConfig.Pools) > 0 {
+		if numInstancesExplicit {
+			return usageError(c, "--num-instances cannot be used with node pools")
+		}
+		numInstances = poolsTotal(clusterConfig.Pools)
+	}
+	if numInstances <= 0 {
+		return usageError(c, fmt.Sprintf("cluster %s is not in state; pass -n <num> to plan a new cluster", clusterName))
+	}
+	zones, err := parseZonesValue(c)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	announce(state)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	startOptions := applyClusterConfig(cluster.StartOptions{
+		NumInstances: numInstances,
+		Zones:        zones,
+	}, clusterConfig)
+	if startOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	plan, err := service.PlanStart(c.Context, clusterName, startOptions)
+	if err != nil {
+		return err
+	}
+	renderPlan(state, plan)
+	return nil
+}
+
+func planStop(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
+	deleteFlag := c.Bool("delete") || hasBoolArg(c.Args().Slice(), "delete")
+	keepDisksFlag := c.Bool("keep-disks") || hasBoolArg(c.Args().Slice(), "keep-disks")
+	deleteDisks := c.Bool("delete-disks") || hasBoolArg(c.Args().Slice(), "delete-disks")
+	clusterConfig := appstate.ClusterConfig{}
+	if state.State != nil {
+		data, err := state.State.Load()
+		if err != nil {
+			return err
+		}
+		if entry := data.Clusters[clusterName]; entry != nil {
+			clusterConfig = entry.Config
+		}
+	}
+	keepDisks, err := resolveStopKeepDisks(deleteFlag, keepDisksFlag, deleteDisks, clusterConfig.KeepDisks)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	announce(state)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	plan, err := service.PlanStop(c.Context, clusterName, cluster.StopOptions{
+		Delete:      deleteFlag,
+		KeepDisks:   keepDisks,
+		DeleteDisks: deleteDisks,
+	})
+	if err != nil {
+		return err
+	}
+	renderPlan(state, plan)
+	return nil
+}
+
+var planSymbols = map[string]string{
+	cluster.PlanCreate:    "+",
+	cluster.PlanPatch:     "~",
+	cluster.PlanStart:     ">",
+	cluster.PlanStop:      "#",
+	cluster.PlanDelete:    "-",
+	cluster.PlanUnchanged: "=",
+}
+
+func renderPlan(state *State, plan *cluster.Plan) {
+	state.UI.Heading(fmt.Sprintf("Plan for cluster %s (%s)", plan.Cluster, plan.Zone))
+	if len(plan.Changes) == 0 {
+		state.UI.Infof("No resources found for cluster %s", plan.Cluster)
+		return
+	}
+	for _, change := range plan.Changes {
+		line := fmt.Sprintf("%s %-9s %s/%s", planSymbols[change.Action], change.Action, change.Kind, change.Name)
+		if change.Detail != "" {
+			line = fmt.Sprintf("%s (%s)", line, change.Detail)
+		}
+		if change.Action == cluster.PlanUnchanged {
+			state.UI.Dimf("%s", state.UI.Indent(1, line))
+		} else {
+			state.UI.Infof("%s", line)
+		}
+		for _, diff := range change.Diffs {
+			state.UI.Detailf(2, "%s: %s => %s", diff.Field, emptyDash(diff.Old), emptyDash(diff.New))
+		}
+	}
+	if plan.CloudInitBytes > 0 {
+		state.UI.Infof("Rendered cloud-init: %.1f KiB", float64(plan.CloudInitBytes)/1024)
+	}
+	state.UI.Infof("Plan: %d to create, %d to patch, %d to start, %d to stop, %d to delete",
+		plan.Count(cluster.PlanCreate),
+		plan.Count(cluster.PlanPatch),
+		plan.Count(cluster.PlanStart),
+		plan.Count(cluster.PlanStop),
+		plan.Count(cluster.PlanDelete),
+	)
+}
+
+func emptyDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+	"gpunow/internal/parse"
+	appstate "gpunow/internal/state"
+)
+
+func portsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ports",
+		Usage: "Open, close and list the exposed ports of a cluster",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Open ports in the cluster firewall and in ufw on running nodes",
+				ArgsUsage: "<cluster> <port[-port][/tcp|/udp][@cidr[+cidr]]>...",
+				Action:    portsAdd,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Close ports in the cluster firewall and in ufw on running nodes",
+				ArgsUsage: "<cluster> <port[-port][/tcp|/udp][@cidr[+cidr]]>...",
+				Action:    portsRemove,
+			},
+			{
+				Name:      "list",
+				Usage:     "List the cluster's exposed ports",
+				ArgsUsage: "<cluster>",
+				Action:    portsList,
+			},
+		},
+	}
+}
+
+func portsAdd(c *cli.Context) error {
+	return changePorts(c, true)
+}
+
+func portsRemove(c *cli.Context) error {
+	return changePorts(c, false)
+}
+
+func changePorts(c *cli.Context, add bool) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if c.Args().Len() < 2 {
+		return usageError(c, "at least one port is required")
+	}
+	specs, err := parse.PortsCSV(strings.Join(c.Args().Slice()[1:], ","))
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	if err := cluster.CheckManagedPorts(specs); err != nil {
+		return usageError(c, err.Error())
+	}
+	entry, err := loadPortsCluster(c, state, clusterName)
+	if err != nil {
+		return err
+	}
+	current := clusterPorts(state, entry.Config)
+
+	var next, opened, closed []parse.PortSpec
+	if add {
+		next, opened = addPorts(current, specs)
+		if len(opened) == 0 {
+			state.UI.Infof("Cluster %s already exposes %s", clusterName, formatPortSpecs(specs))
+			return nil
+		}
+	} else {
+		next, closed, err = removePorts(current, specs)
+		if err != nil {
+			return usageError(c, err.Error())
+		}
+	}
+
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	user := strings.TrimSpace(state.Config.SSH.DefaultUser)
+	announceWithKey(state, selection, true)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	clusterConfig := entry.Config
+	clusterConfig.Ports = next
+	startOptions := applyClusterConfig(cluster.StartOptions{
+		NumInstances:    entry.NumInstances,
+		SSHUser:         user,
+		SSHPublicKey:    selectionKey(selection),
+		SSHIdentityFile: selectionIdentityPath(selection),
+	}, clusterConfig)
+	if startOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	err = service.ApplyPorts(c.Context, clusterName, cluster.PortsOptions{
+		Start: startOptions,
+		Open:  opened,
+		Close: closed,
+		// The firewall already matches next when ufw fails on a node, and
+		// later nodes get next through cloud-init.
+		OnFirewallsUpdated: func() {
+			if err := state.State.RecordClusterPorts(clusterName, next, time.Now()); err != nil {
+				state.UI.Warnf("Failed to update state: %v", err)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if add {
+		state.UI.Successf("Opened %s on cluster %s", formatPortSpecs(opened), clusterName)
+		return nil
+	}
+	state.UI.Successf("Closed %s on cluster %s", formatPortSpecs(closed), clusterName)
+	return nil
+}
+
+func portsList(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	entry, err := loadPortsCluster(c, state, clusterName)
+	if err != nil {
+		return err
+	}
+	source := "profile network.ports"
+	if len(entry.Config.Ports) > 0 {
+		source = "cluster state"
+	}
+	portRanges := state.Config.Network.PortSourceRanges
+	if len(entry.Config.PortSourceRanges) > 0 {
+		portRanges = entry.Config.PortSourceRanges
+	}
+	state.UI.Heading(fmt.Sprintf("Ports of %s (%s)", clusterName, source))
+	for _, spec := range clusterPorts(state, entry.Config) {
+		ranges := spec.SourceRanges
+		if len(ranges) == 0 {
+			ranges = portRanges
+		}
+		state.UI.Infof("%-16s from %s", spec.Range()+"/"+spec.Protocol, strings.Join(ranges, ", "))
+	}
+	return nil
+}
+
+func loadPortsCluster(c *cli.Context, state *State, clusterName string) (*appstate.Cluster, error) {
+	if state.State == nil {
+		return nil, fmt.Errorf("state store is unavailable")
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return nil, err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil {
+		return nil, usageError(c, fmt.Sprintf("cluster %s not found in state; run `gpunow create %s -n <num>` first", clusterName, clusterName))
+	}
+	applyRecordedCluster(state, entry)
+	return entry, nil
+}
+
+// clusterPorts returns the cluster's port set: the one recorded in state, or
+// the profile's network.ports until `gpunow ports` changes it.
+func clusterPorts(state *State, clusterConfig appstate.ClusterConfig) []parse.PortSpec {
+	if len(clusterConfig.Ports) > 0 {
+		return clusterConfig.Ports
+	}
+	return state.Config.Network.Ports
+}
+
+// addPorts appends the specs missing from current and returns the new set and
+// the specs actually added.
+func addPorts(current, specs []parse.PortSpec) ([]parse.PortSpec, []parse.PortSpec) {
+	next := append([]parse.PortSpec{}, current...)
+	added := []parse.PortSpec{}
+	for _, spec := range specs {
+		found := false
+		for _, existing := range next {
+			if existing.String() == spec.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			next = append(next, spec)
+			added = append(added, spec)
+		}
+	}
+	return next, added
+}
+
+// removePorts drops the entries of current matching specs and returns the new
+// set and the entries removed. A spec without source ranges matches an entry
+// with the same ports and protocol whatever its ranges.
+func removePorts(current, specs []parse.PortSpec) ([]parse.PortSpec, []parse.PortSpec, error) {
+	next := []parse.PortSpec{}
+	removed := []parse.PortSpec{}
+	matched := make([]bool, len(specs))
+	for _, existing := range current {
+		keep := true
+		for i, spec := range specs {
+			if portSpecMatches(existing, spec) {
+				matched[i] = true
+				keep = false
+			}
+		}
+		if keep {
+			next = append(next, existing)
+		} else {
+			removed = append(removed, existing)
+		}
+	}
+	for i, spec := range specs {
+		if !matched[i] {
+			return nil, nil, fmt.Errorf("port %s is not exposed", spec)
+		}
+	}
+	if len(next) == 0 {
+		return nil, nil, fmt.Errorf("a cluster needs at least one exposed port")
+	}
+	return next, removed, nil
+}
+
+func portSpecMatches(existing, spec parse.PortSpec) bool {
+	if existing.Range() != spec.Range() || existing.Protocol != spec.Protocol {
+		return false
+	}
+	return len(spec.SourceRanges) == 0 || existing.String() == spec.String()
+}
+
+func formatPortSpecs(specs []parse.PortSpec) string {
+	values := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		values = append(values, spec.String())
+	}
+	return strings.Join(values, ", ")
+}
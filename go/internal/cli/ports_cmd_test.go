@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"gpunow/internal/parse"
+)
+
+func mustPorts(t *testing.T, value string) []parse.PortSpec {
+	t.Helper()
+	ports, err := parse.PortsCSV(value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return ports
+}
+
+func TestAddPorts(t *testing.T) {
+	next, added := addPorts(mustPorts(t, "22,6006"), mustPorts(t, "6006/tcp,60000-61000/udp"))
+	if got := formatPortSpecs(next); got != "22/tcp, 6006/tcp, 60000-61000/udp" {
+		t.Fatalf("next = %s", got)
+	}
+	if got := formatPortSpecs(added); got != "60000-61000/udp" {
+		t.Fatalf("added = %s", got)
+	}
+}
+
+func TestRemovePorts(t *testing.T) {
+	current := mustPorts(t, "22,6006@10.8.0.0/16,8888")
+	next, removed, err := removePorts(current, mustPorts(t, "6006"))
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := formatPortSpecs(next); got != "22/tcp, 8888/tcp" {
+		t.Fatalf("next = %s", got)
+	}
+	if got := formatPortSpecs(removed); got != "6006/tcp@10.8.0.0/16" {
+		t.Fatalf("removed = %s", got)
+	}
+
+	if _, _, err := removePorts(current, mustPorts(t, "6006/udp")); err == nil {
+		t.Fatalf("expected error removing a port that is not exposed")
+	}
+	if _, _, err := removePorts(mustPorts(t, "22"), mustPorts(t, "22")); err == nil {
+		t.Fatalf("expected error removing the last port")
+	}
+}
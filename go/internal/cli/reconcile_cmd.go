@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+func reconcileCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "reconcile",
+		Usage:     "Report and fix drift between a live cluster and its profile",
+		ArgsUsage: "<cluster>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "apply", Usage: "Fix drift in place without restarting nodes"},
+		},
+		Action: reconcileCluster,
+	}
+}
+
+func reconcileCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if state.State == nil {
+		return fmt.Errorf("state store is unavailable")
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil || entry.NumInstances <= 0 {
+		return usageError(c, fmt.Sprintf("cluster %s not found in state; run `gpunow create %s -n <num>` first", clusterName, clusterName))
+	}
+	applyRecordedCluster(state, entry)
+	apply := c.Bool("apply") || hasBoolArg(c.Args().Slice(), "apply")
+	announce(state)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	startOptions := applyClusterConfig(cluster.StartOptions{NumInstances: entry.NumInstances}, entry.Config)
+	if startOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	result, reconcileErr := service.Reconcile(c.Context, clusterName, cluster.ReconcileOptions{
+		Start: startOptions,
+		Apply: apply,
+	})
+	if result == nil {
+		return reconcileErr
+	}
+	renderDrift(state, clusterName, result, apply)
+	return reconcileErr
+}
+
+func renderDrift(state *State, clusterName string, result *cluster.ReconcileResult, applied bool) {
+	if len(result.Drifts) == 0 {
+		state.UI.Successf("Cluster %s matches its profile", clusterName)
+		return
+	}
+	state.UI.Heading("Drift")
+	for _, drift := range result.Drifts {
+		status := "drift"
+		switch {
+		case drift.Fixed:
+			status = "fixed"
+		case drift.Note != "":
+			status = "manual"
+		}
+		state.UI.Infof("%-6s %s/%s %s: %s => %s", status, drift.Kind, drift.Name, drift.Field, emptyDash(drift.Live), emptyDash(drift.Want))
+		if drift.Note != "" {
+			state.UI.Detailf(2, "%s", drift.Note)
+		}
+	}
+	unfixed := result.Unfixed()
+	switch {
+	case len(unfixed) == 0:
+		state.UI.Successf("Fixed %d drifted fields", len(result.Drifts))
+	case applied:
+		state.UI.Warnf("%d of %d drifted fields remain", len(unfixed), len(result.Drifts))
+	default:
+		state.UI.Warnf("%d drifted fields; run `gpunow reconcile %s --apply` to fix", len(unfixed), clusterName)
+	}
+}
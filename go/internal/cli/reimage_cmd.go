@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+func reimageCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "reimage",
+		Usage:     "Recreate cluster nodes and their boot disks from the current image and setup",
+		ArgsUsage: "<cluster|cluster/index>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "rolling", Usage: "Recreate nodes in batches, waiting for each batch to be ready"},
+			&cli.IntFlag{Name: "batch", Usage: "With --rolling, number of nodes recreated at a time (default 1)"},
+		},
+		Action: reimageCluster,
+	}
+}
+
+func reimageCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	raw, err := requireArgWithHelp(c, 0, "cluster name or target")
+	if err != nil {
+		return err
+	}
+	clusterName, nodeNames, err := resolveNodeSelection(state, raw)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	rolling := c.Bool("rolling") || hasBoolArg(c.Args().Slice(), "rolling")
+	batch, batchExplicit, err := parseIntFlagValue(c, "batch", "", "--batch", "--batch must be a positive integer")
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	switch {
+	case batchExplicit && !rolling:
+		return usageError(c, "--batch requires --rolling")
+	case batchExplicit && batch <= 0:
+		return usageError(c, "--batch must be a positive integer")
+	case rolling && !batchExplicit:
+		batch = 1
+	}
+	if state.State == nil {
+		return fmt.Errorf("state store is unavailable")
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil || entry.NumInstances <= 0 {
+		return usageError(c, fmt.Sprintf("cluster %s not found in state; run `gpunow create %s -n <num>` first", clusterName, clusterName))
+	}
+	applyRecordedCluster(state, entry)
+
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	user := strings.TrimSpace(state.Config.SSH.DefaultUser)
+	if selection != nil && selection.Key != "" && user == "" {
+		return fmt.Errorf("ssh.default_user is required to set ssh keys")
+	}
+	announceWithKey(state, selection, true)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.Reimage(c.Context, clusterName, cluster.ReimageOptions{
+		Start: applyClusterConfig(cluster.StartOptions{
+			NumInstances:    entry.NumInstances,
+			SSHUser:         user,
+			SSHPublicKey:    selectionKey(selection),
+			SSHIdentityFile: selectionIdentityPath(selection),
+			OnStateChange:   clusterStateUpdateFn(state, clusterName),
+			OnImage:         clusterImageRecordFn(state, clusterName),
+		}, entry.Config),
+		Nodes:     nodeNames,
+		BatchSize: batch,
+	}); err != nil {
+		return err
+	}
+	state.UI.Successf("Reimaged %s with %s", raw, state.Config.Disk.Image)
+	return nil
+}
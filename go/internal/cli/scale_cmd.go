@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+	appstate "gpunow/internal/state"
+)
+
+func scaleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "scale",
+		Usage:     "Grow or shrink a running cluster",
+		ArgsUsage: "<cluster>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "num-instances", Aliases: []string{"n"}, Usage: "Target number of instances (of the pool with --pool)"},
+			&cli.StringFlag{Name: "pool", Usage: "Resize only this node pool"},
+		},
+		Action: scaleCluster,
+	}
+}
+
+func scaleCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	numInstances, numInstancesExplicit, err := parseNumInstancesValue(c)
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	poolName, _, err := parseStringFlagValue(c, "--pool", "pool")
+	if err != nil {
+		return usageError(c, err.Error())
+	}
+	poolName = strings.TrimSpace(poolName)
+	if !numInstancesExplicit || numInstances < 0 || (numInstances == 0 && poolName == "") {
+		return usageError(c, "--num-instances must be a positive integer")
+	}
+	if state.State == nil {
+		return fmt.Errorf("state store is unavailable")
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil {
+		return usageError(c, fmt.Sprintf("cluster %s not found in state; run `gpunow create %s -n <num>` first", clusterName, clusterName))
+	}
+	applyRecordedCluster(state, entry)
+	clusterConfig := entry.Config
+	var pools []appstate.ClusterPool
+	switch {
+	case poolName == "" && len(clusterConfig.Pools) > 0:
+		return usageError(c, fmt.Sprintf("cluster %s uses node pools; pass --pool <name>", clusterName))
+	case poolName != "" && len(clusterConfig.Pools) == 0:
+		return usageError(c, fmt.Sprintf("cluster %s has no node pools", clusterName))
+	case poolName != "":
+		pools, err = resizePool(clusterConfig.Pools, poolName, numInstances)
+		if err != nil {
+			return usageError(c, err.Error())
+		}
+		clusterConfig.Pools = pools
+	}
+
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	user := strings.TrimSpace(state.Config.SSH.DefaultUser)
+	if selection != nil && selection.Key != "" && user == "" {
+		return fmt.Errorf("ssh.default_user is required to set ssh keys")
+	}
+	announceWithKey(state, selection, true)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	scaleOptions := applyClusterConfig(cluster.StartOptions{
+		NumInstances:    numInstances,
+		SSHUser:         user,
+		SSHPublicKey:    selectionKey(selection),
+		SSHIdentityFile: selectionIdentityPath(selection),
+		OnStateChange:   clusterStateUpdateFn(state, clusterName),
+		OnImage:         clusterImageRecordFn(state, clusterName),
+	}, clusterConfig)
+	if scaleOptions.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	if err := service.Scale(c.Context, clusterName, scaleOptions); err != nil {
+		return err
+	}
+	if err := state.State.RecordClusterScale(clusterName, scaleOptions.NumInstances, pools, time.Now()); err != nil {
+		state.UI.Warnf("Failed to update state: %v", err)
+	}
+	if poolName != "" {
+		state.UI.Successf("Scaled pool %s of cluster %s to %d instances", poolName, clusterName, numInstances)
+		return nil
+	}
+	state.UI.Successf("Scaled cluster %s to %d instances", clusterName, numInstances)
+	return nil
+}
+
+// resizePool returns a copy of pools with the named pool set to count. The
+// first pool holds the master and cannot drop to zero.
+func resizePool(pools []appstate.ClusterPool, name string, count int) ([]appstate.ClusterPool, error) {
+	resized := append([]appstate.ClusterPool{}, pools...)
+	for i := range resized {
+		if resized[i].Name != name {
+			continue
+		}
+		if i == 0 && count == 0 {
+			return nil, fmt.Errorf("pool %s holds the master and needs at least one node", name)
+		}
+		resized[i].Count = count
+		return resized, nil
+	}
+	return nil, fmt.Errorf("pool %s not found; known pools: %s", name, strings.Join(poolNames(pools), ", "))
+}
+
+func poolNames(pools []appstate.ClusterPool) []string {
+	names := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		names = append(names, pool.Name)
+	}
+	return names
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/config"
+	appstate "gpunow/internal/state"
+)
+
+// applySourceRangeFlags stores --ssh-source-ranges, --port-source-ranges and
+// --my-ip in the cluster config. --my-ip restricts both rules to the caller's
+// address plus any ranges given explicitly; flags that are not set keep the
+// ranges already recorded for the cluster.
+func applySourceRangeFlags(c *cli.Context, cfg *config.Config, clusterConfig *appstate.ClusterConfig) error {
+	sshRanges, sshSet, err := parseCIDRListFlag(c, "ssh-source-ranges")
+	if err != nil {
+		return err
+	}
+	portRanges, portSet, err := parseCIDRListFlag(c, "port-source-ranges")
+	if err != nil {
+		return err
+	}
+	if c.Bool("my-ip") || hasBoolArg(c.Args().Slice(), "my-ip") {
+		myIP, err := lookupMyIP(c.Context, cfg.Network.MyIPURL)
+		if err != nil {
+			return fmt.Errorf("--my-ip: %w", err)
+		}
+		sshRanges = appendUnique(sshRanges, myIP)
+		portRanges = appendUnique(portRanges, myIP)
+		sshSet, portSet = true, true
+	}
+	if sshSet {
+		clusterConfig.SSHSourceRanges = sshRanges
+	}
+	if portSet {
+		clusterConfig.PortSourceRanges = portRanges
+	}
+	return nil
+}
+
+func parseCIDRListFlag(c *cli.Context, name string) ([]string, bool, error) {
+	raw, ok, err := parseStringFlagValue(c, "--"+name, name)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	ranges, err := parseCIDRList(raw)
+	if err != nil {
+		return nil, false, fmt.Errorf("--%s: %w", name, err)
+	}
+	return ranges, true, nil
+}
+
+// parseCIDRList parses comma-separated CIDRs. Bare addresses are accepted as
+// single-host ranges.
+func parseCIDRList(raw string) ([]string, error) {
+	ranges := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			ranges = appendUnique(ranges, hostCIDR(ip))
+			continue
+		}
+		if _, _, err := net.ParseCIDR(item); err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", item)
+		}
+		ranges = appendUnique(ranges, item)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("at least one CIDR is required")
+	}
+	return ranges, nil
+}
+
+// lookupMyIP fetches the caller's public address from lookupURL, which must
+// answer with the bare IP, and returns it as a single-host CIDR.
+func lookupMyIP(ctx context.Context, lookupURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned HTTP %d", lookupURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("%s returned %q, not an IP address", lookupURL, strings.TrimSpace(string(body)))
+	}
+	return hostCIDR(ip), nil
+}
+
+func hostCIDR(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String() + "/32"
+	}
+	return ip.String() + "/128"
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
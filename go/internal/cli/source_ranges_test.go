@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseCIDRList(t *testing.T) {
+	ranges, err := parseCIDRList("10.0.0.0/8, 203.0.113.7,,2001:db8::1,10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("ranges = %v, want %v", ranges, want)
+	}
+	for _, raw := range []string{"", "10.0.0.0/33", "office"} {
+		if _, err := parseCIDRList(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestLookupMyIP(t *testing.T) {
+	body := "198.51.100.4\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	got, err := lookupMyIP(context.Background(), server.URL)
+	if err != nil || got != "198.51.100.4/32" {
+		t.Fatalf("lookup = %q, %v", got, err)
+	}
+	body = "<html>blocked</html>"
+	if _, err := lookupMyIP(context.Background(), server.URL); err == nil {
+		t.Fatalf("expected error for non-IP response")
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+// startQuorum holds the partial-success settings shared by create --start
+// and start.
+type startQuorum struct {
+	MinReady     int
+	DeleteFailed bool
+}
+
+func parseStartQuorum(c *cli.Context, numInstances int) (startQuorum, error) {
+	quorum := startQuorum{}
+	minReady, minReadySet, err := parseIntFlagValue(c, "min-ready", "", "--min-ready", "--min-ready must be a positive integer")
+	if err != nil {
+		return quorum, err
+	}
+	if minReadySet {
+		if minReady <= 0 {
+			return quorum, fmt.Errorf("--min-ready must be a positive integer")
+		}
+		if numInstances > 0 && minReady > numInstances {
+			return quorum, fmt.Errorf("--min-ready (%d) cannot exceed --num-instances (%d)", minReady, numInstances)
+		}
+		quorum.MinReady = minReady
+	}
+	quorum.DeleteFailed = c.Bool("delete-failed") || hasBoolArg(c.Args().Slice(), "delete-failed")
+	if quorum.DeleteFailed && quorum.MinReady == 0 {
+		return quorum, fmt.Errorf("--delete-failed requires --min-ready")
+	}
+	return quorum, nil
+}
+
+// reportStartResult records the zone and subnet range used and per-node
+// failure reasons in state, dropping nodes removed by --delete-failed, and
+// prints a per-node summary when any node failed or a quorum was requested.
+func reportStartResult(state *State, clusterName string, result *cluster.StartResult, quorum startQuorum) {
+	if result == nil || len(result.Nodes) == 0 {
+		return
+	}
+	failed := result.Failed()
+	if state.State != nil && result.Zone != "" {
+		if err := state.State.RecordClusterZone(clusterName, result.Zone, time.Now()); err != nil {
+			state.UI.Warnf("Failed to update state: %v", err)
+		}
+	}
+	if state.State != nil && result.SubnetCIDR != "" {
+		if err := state.State.RecordClusterSubnetCIDR(clusterName, result.SubnetCIDR, time.Now()); err != nil {
+			state.UI.Warnf("Failed to update state: %v", err)
+		}
+	}
+	if result.Zone != "" && result.Zone != state.Config.Project.Zone {
+		state.UI.Infof("Cluster %s is running in fallback zone %s", clusterName, result.Zone)
+	}
+	if state.State != nil {
+		for _, node := range failed {
+			var err error
+			if node.Deleted {
+				err = state.State.RecordClusterInstanceDeleted(clusterName, node.Name, time.Now())
+			} else {
+				err = state.State.RecordClusterInstanceFailure(clusterName, node.Name, failureReason(node), time.Now())
+			}
+			if err != nil {
+				state.UI.Warnf("Failed to update state: %v", err)
+				break
+			}
+		}
+	}
+	if len(failed) == 0 && quorum.MinReady == 0 {
+		return
+	}
+
+	state.UI.Heading("Nodes")
+	state.UI.Infof("%-24s %-10s %-8s %s", "NAME", "STATE", "ELAPSED", "DETAIL")
+	for _, node := range result.Nodes {
+		if node.Name == "" {
+			continue
+		}
+		detail := ""
+		if node.Err != nil {
+			detail = failureReason(node)
+			if node.Deleted {
+				detail = fmt.Sprintf("%s (deleted)", detail)
+			}
+		}
+		state.UI.Infof("%-24s %-10s %-8s %s", node.Name, node.State, node.Elapsed.Round(time.Second), detail)
+	}
+	ready := result.ReadyCount()
+	if quorum.MinReady > 0 {
+		if ready >= quorum.MinReady {
+			state.UI.Successf("%d of %d nodes READY (min-ready %d)", ready, len(result.Nodes), quorum.MinReady)
+		} else {
+			state.UI.Warnf("%d of %d nodes READY (min-ready %d)", ready, len(result.Nodes), quorum.MinReady)
+		}
+	}
+}
+
+func failureReason(node cluster.NodeResult) string {
+	if node.Err == nil {
+		return ""
+	}
+	if errors.Is(node.Err, context.Canceled) {
+		return "canceled after another node failed"
+	}
+	return strings.TrimSpace(node.Err.Error())
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpunow/internal/cluster"
+	"gpunow/internal/config"
+	"gpunow/internal/lifecycle"
+	appstate "gpunow/internal/state"
+	"gpunow/internal/ui"
+)
+
+func TestReportStartResultRecordsFailedAndDeletedNodes(t *testing.T) {
+	cfg, err := config.Load("default", filepath.Join("..", "..", "..", "profiles"))
+	if err != nil {
+		t.Fatalf("load default config: %v", err)
+	}
+	store := appstate.New(t.TempDir())
+	if err := store.RecordClusterCreate("mix", "default", 3, appstate.ClusterConfig{}, time.Now()); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	// mix-2 got as far as PROVISIONING with addresses before it failed.
+	if err := store.RecordClusterInstanceState("mix", "mix-2", lifecycle.InstanceStateProvisioning, "203.0.113.2", "10.0.0.4", time.Now()); err != nil {
+		t.Fatalf("record instance state: %v", err)
+	}
+	state := &State{Config: cfg, UI: &ui.UI{Out: io.Discard, Err: io.Discard}, State: store}
+	result := &cluster.StartResult{Nodes: []cluster.NodeResult{
+		{Name: "mix-0", Index: 0, State: lifecycle.InstanceStateReady},
+		{Name: "mix-1", Index: 1, State: lifecycle.InstanceStateFailed, Err: errors.New("boot failed")},
+		{Name: "mix-2", Index: 2, State: lifecycle.InstanceStateFailed, Err: errors.New("boot failed"), Deleted: true},
+	}}
+
+	reportStartResult(state, "mix", result, startQuorum{MinReady: 1, DeleteFailed: true})
+
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	instances := data.Clusters["mix"].Instances
+	if kept := instances["mix-1"]; kept == nil || kept.State != lifecycle.InstanceStateFailed || kept.FailureReason != "boot failed" {
+		t.Fatalf("expected mix-1 recorded FAILED, got %+v", kept)
+	}
+	// mix-2 is still part of the 3-node layout, so its entry is rebuilt as
+	// TERMINATED without the addresses of the deleted instance.
+	if deleted := instances["mix-2"]; deleted == nil || deleted.State != lifecycle.InstanceStateTerminated || deleted.ExternalIP != "" || deleted.InternalIP != "" {
+		t.Fatalf("expected deleted mix-2 recorded TERMINATED, got %+v", deleted)
+	}
+}
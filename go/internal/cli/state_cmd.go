@@ -145,5 +145,8 @@ func clusterConfigSummary(cfg appstate.ClusterConfig) string {
 	if cfg.KeepDisks {
 		items = append(items, "keep-disks=true")
 	}
+	if cfg.SubnetCIDR != "" {
+		items = append(items, fmt.Sprintf("subnet=%s", cfg.SubnetCIDR))
+	}
 	return strings.Join(items, ", ")
 }
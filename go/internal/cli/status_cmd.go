@@ -145,15 +145,10 @@ func statusSync(c *cli.Context) error {
 		if clusterInstances[clusterName] == nil {
 			clusterInstances[clusterName] = map[string]*appstate.ClusterInstance{}
 		}
-		index := parseInstanceIndex(clusterName, inst.GetName())
-		if rawIndex, ok := instLabels["cluster_index"]; ok {
-			if parsed, err := strconv.Atoi(strings.TrimSpace(rawIndex)); err == nil && parsed >= 0 {
-				index = parsed
-			}
-		}
 		clusterInstances[clusterName][inst.GetName()] = &appstate.ClusterInstance{
 			Name:       inst.GetName(),
-			Index:      index,
+			Index:      liveInstanceIndex(clusterName, inst),
+			Pool:       instLabels["cluster_pool"],
 			State:      lifecycle.FromComputeStatus(inst.GetStatus()),
 			ExternalIP: externalIPFromInstance(inst),
 			InternalIP: internalIPFromInstance(inst),
@@ -189,7 +184,7 @@ func statusSync(c *cli.Context) error {
 		if _, ok := foundClusters[name]; ok {
 			continue
 		}
-		instances, err := listInstancesByFilter(c.Context, compute, state.Config.Project.ID, state.Config.Project.Zone, fmt.Sprintf("labels.cluster = %q", name))
+		instances, err := listInstancesByFilter(c.Context, compute, state.Config.Project.ID, clusterZone(state, entry), fmt.Sprintf("labels.cluster = %q", name))
 		if err != nil {
 			return err
 		}
@@ -201,7 +196,8 @@ func statusSync(c *cli.Context) error {
 				agg.observe(inst.GetStatus())
 				instanceMap[inst.GetName()] = &appstate.ClusterInstance{
 					Name:       inst.GetName(),
-					Index:      parseInstanceIndex(name, inst.GetName()),
+					Index:      liveInstanceIndex(name, inst),
+					Pool:       inst.GetLabels()["cluster_pool"],
 					State:      lifecycle.FromComputeStatus(inst.GetStatus()),
 					ExternalIP: externalIPFromInstance(inst),
 					InternalIP: internalIPFromInstance(inst),
@@ -327,6 +323,9 @@ func renderStatus(state *State, data *appstate.Data, instancesByCluster map[stri
 				instanceCount = len(entry.Instances)
 			}
 			state.UI.InfofIndent(1, "Instances: %d", instanceCount)
+			if len(entry.Config.Pools) > 0 {
+				state.UI.InfofIndent(1, "Pools: %s", poolSummary(entry.Config.Pools))
+			}
 			if overrideSummary := clusterConfigSummary(entry.Config); overrideSummary != "" {
 				state.UI.InfofIndent(1, "Overrides: %s", overrideSummary)
 			}
@@ -335,9 +334,15 @@ func renderStatus(state *State, data *appstate.Data, instancesByCluster map[stri
 				if instance.ExternalIP != "" {
 					line = fmt.Sprintf("%s %s", line, instance.ExternalIP)
 				}
+				if instance.ExternalIPv6 != "" {
+					line = fmt.Sprintf("%s %s", line, instance.ExternalIPv6)
+				}
 				if instance.InternalIP != "" {
 					line = fmt.Sprintf("%s [%s]", line, instance.InternalIP)
 				}
+				if instance.Failure != "" {
+					line = fmt.Sprintf("%s: %s", line, instance.Failure)
+				}
 				state.UI.InfofIndent(1, "%s", line)
 			}
 			if entry.LastAction != "" {
@@ -354,8 +359,12 @@ type statusInstanceLine struct {
 	Name       string
 	State      string
 	ExternalIP string
-	InternalIP string
-	Index      int
+	// ExternalIPv6 is shown next to ExternalIP on dual-stack nodes.
+	ExternalIPv6 string
+	InternalIP   string
+	Index        int
+	Pool         string
+	Failure      string
 }
 
 func renderedInstances(entry *appstate.Cluster, live []*computepb.Instance) []statusInstanceLine {
@@ -370,6 +379,8 @@ func renderedInstances(entry *appstate.Cluster, live []*computepb.Instance) []st
 			ExternalIP: instance.ExternalIP,
 			InternalIP: instance.InternalIP,
 			Index:      instance.Index,
+			Pool:       instance.Pool,
+			Failure:    instance.FailureReason,
 		}
 		if line.State == "" {
 			line.State = lifecycle.InstanceStateTerminated
@@ -385,7 +396,8 @@ func renderedInstances(entry *appstate.Cluster, live []*computepb.Instance) []st
 		if line.Name == "" {
 			line.Name = name
 			line.State = lifecycle.FromComputeStatus(inst.GetStatus())
-			line.Index = parseInstanceIndex(entry.Name, name)
+			line.Index = liveInstanceIndex(entry.Name, inst)
+			line.Pool = inst.GetLabels()["cluster_pool"]
 		}
 		if line.ExternalIP == "" {
 			line.ExternalIP = externalIPFromInstance(inst)
@@ -393,6 +405,9 @@ func renderedInstances(entry *appstate.Cluster, live []*computepb.Instance) []st
 		if line.InternalIP == "" {
 			line.InternalIP = internalIPFromInstance(inst)
 		}
+		if ipv6 := externalIPv6FromInstance(inst); ipv6 != line.ExternalIP {
+			line.ExternalIPv6 = ipv6
+		}
 		byName[name] = line
 	}
 
@@ -409,7 +424,17 @@ func renderedInstances(entry *appstate.Cluster, live []*computepb.Instance) []st
 		}
 		out = append(out, line)
 	}
+	poolRank := map[string]int{}
+	for i, pool := range entry.Config.Pools {
+		poolRank[pool.Name] = i
+	}
 	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pool != out[j].Pool {
+			if poolRank[out[i].Pool] != poolRank[out[j].Pool] {
+				return poolRank[out[i].Pool] < poolRank[out[j].Pool]
+			}
+			return out[i].Pool < out[j].Pool
+		}
 		if out[i].Index != out[j].Index {
 			if out[i].Index < 0 {
 				return false
@@ -430,6 +455,8 @@ type clusterStatusAgg struct {
 	anyTerminated  bool
 	anyStarting    bool
 	anyTerminating bool
+	anySuspending  bool
+	anySuspended   bool
 }
 
 func (c *clusterStatusAgg) observe(status string) {
@@ -440,6 +467,10 @@ func (c *clusterStatusAgg) observe(status string) {
 		c.anyTerminated = true
 	case lifecycle.InstanceStateTerminating:
 		c.anyTerminating = true
+	case lifecycle.InstanceStateSuspending:
+		c.anySuspending = true
+	case lifecycle.InstanceStateSuspended:
+		c.anySuspended = true
 	default:
 		c.anyStarting = true
 	}
@@ -452,15 +483,21 @@ func (c *clusterStatusAgg) status() string {
 	if c.anyTerminating {
 		return lifecycle.InstanceStateTerminating
 	}
+	if c.anySuspending {
+		return lifecycle.InstanceStateSuspending
+	}
 	if c.anyStarting {
 		return lifecycle.InstanceStateStarting
 	}
-	if c.anyReady && !c.anyTerminated {
+	if c.anyReady && !c.anyTerminated && !c.anySuspended {
 		return lifecycle.InstanceStateReady
 	}
 	if c.anyReady {
 		return lifecycle.InstanceStateStarting
 	}
+	if c.anySuspended {
+		return lifecycle.InstanceStateSuspended
+	}
 	return lifecycle.InstanceStateTerminated
 }
 
@@ -477,6 +514,33 @@ func parseInstanceIndex(clusterName, instanceName string) int {
 	return idx
 }
 
+// liveInstanceIndex returns a node's index from its cluster_index label,
+// falling back to the <cluster>-<index> name.
+func liveInstanceIndex(clusterName string, inst *computepb.Instance) int {
+	if rawIndex, ok := inst.GetLabels()["cluster_index"]; ok {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(rawIndex)); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return parseInstanceIndex(clusterName, inst.GetName())
+}
+
+func poolSummary(pools []appstate.ClusterPool) string {
+	parts := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		parts = append(parts, fmt.Sprintf("%s=%d", pool.Name, pool.Count))
+	}
+	return strings.Join(parts, " ")
+}
+
+// clusterZone returns the zone recorded for a cluster, or project.zone.
+func clusterZone(state *State, entry *appstate.Cluster) string {
+	if entry != nil && entry.Zone != "" {
+		return entry.Zone
+	}
+	return state.Config.Project.Zone
+}
+
 func existingCluster(data *appstate.Data, name string) *appstate.Cluster {
 	if data == nil || data.Clusters == nil {
 		return &appstate.Cluster{}
@@ -529,7 +593,7 @@ func clusterInstancesForStatus(ctx context.Context, state *State, compute gcp.Co
 		if entry == nil || entry.Status == "deleted" {
 			continue
 		}
-		instances, err := listInstancesByFilter(ctx, compute, state.Config.Project.ID, state.Config.Project.Zone, fmt.Sprintf("labels.cluster = %q", name))
+		instances, err := listInstancesByFilter(ctx, compute, state.Config.Project.ID, clusterZone(state, entry), fmt.Sprintf("labels.cluster = %q", name))
 		if err != nil {
 			return nil, err
 		}
@@ -545,10 +609,21 @@ func externalIPFromInstance(inst *computepb.Instance) string {
 		return ""
 	}
 	iface := inst.GetNetworkInterfaces()[0]
-	if len(iface.GetAccessConfigs()) == 0 {
+	if len(iface.GetAccessConfigs()) > 0 && iface.GetAccessConfigs()[0].GetNatIP() != "" {
+		return iface.GetAccessConfigs()[0].GetNatIP()
+	}
+	return externalIPv6FromInstance(inst)
+}
+
+func externalIPv6FromInstance(inst *computepb.Instance) string {
+	if inst == nil || len(inst.GetNetworkInterfaces()) == 0 {
+		return ""
+	}
+	iface := inst.GetNetworkInterfaces()[0]
+	if len(iface.GetIpv6AccessConfigs()) == 0 {
 		return ""
 	}
-	return iface.GetAccessConfigs()[0].GetNatIP()
+	return iface.GetIpv6AccessConfigs()[0].GetExternalIpv6()
 }
 
 func internalIPFromInstance(inst *computepb.Instance) string {
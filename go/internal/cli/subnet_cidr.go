@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	appstate "gpunow/internal/state"
+)
+
+// applySubnetCIDRFlag stores --subnet-cidr in the cluster config, normalized
+// to its network address.
+func applySubnetCIDRFlag(c *cli.Context, clusterConfig *appstate.ClusterConfig) error {
+	raw, ok, err := parseStringFlagValue(c, "--subnet-cidr", "subnet-cidr")
+	if err != nil || !ok {
+		return err
+	}
+	cidr, err := parseSubnetCIDR(raw)
+	if err != nil {
+		return fmt.Errorf("--subnet-cidr: %w", err)
+	}
+	clusterConfig.SubnetCIDR = cidr
+	return nil
+}
+
+func parseSubnetCIDR(raw string) (string, error) {
+	ip, ipNet, err := net.ParseCIDR(strings.TrimSpace(raw))
+	if err != nil || ip.To4() == nil {
+		return "", fmt.Errorf("invalid IPv4 CIDR %q", raw)
+	}
+	if prefix, _ := ipNet.Mask.Size(); prefix < 8 || prefix > 29 {
+		return "", fmt.Errorf("prefix length of %s must be between /8 and /29", raw)
+	}
+	return ipNet.String(), nil
+}
+
+// reservedSubnetCIDRs returns the subnet ranges state records for clusters
+// other than clusterName.
+func reservedSubnetCIDRs(state *State, clusterName string) ([]string, error) {
+	if state.State == nil {
+		return nil, nil
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return nil, err
+	}
+	reserved := []string{}
+	for name, entry := range data.Clusters {
+		if name == clusterName || entry == nil || entry.Config.SubnetCIDR == "" {
+			continue
+		}
+		reserved = append(reserved, entry.Config.SubnetCIDR)
+	}
+	sort.Strings(reserved)
+	return reserved, nil
+}
@@ -0,0 +1,15 @@
+package cli
+
+import "testing"
+
+func TestParseSubnetCIDR(t *testing.T) {
+	got, err := parseSubnetCIDR(" 10.210.3.7/24 ")
+	if err != nil || got != "10.210.3.0/24" {
+		t.Fatalf("parseSubnetCIDR = %q, %v", got, err)
+	}
+	for _, bad := range []string{"", "10.0.0.0", "10.0.0.0/30", "10.0.0.0/7", "fd00::/64"} {
+		if _, err := parseSubnetCIDR(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
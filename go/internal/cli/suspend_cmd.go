@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+)
+
+func suspendCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "suspend",
+		Usage:     "Suspend a running cluster, keeping memory and processes",
+		ArgsUsage: "<cluster>",
+		Action:    suspendCluster,
+	}
+}
+
+func resumeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "resume",
+		Usage:     "Resume a suspended cluster",
+		ArgsUsage: "<cluster>",
+		Action:    resumeCluster,
+	}
+}
+
+func suspendCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
+	announce(state)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.Suspend(c.Context, clusterName, cluster.SuspendOptions{
+		OnStateChange: clusterStateUpdateFn(state, clusterName),
+	}); err != nil {
+		return err
+	}
+	recordClusterSuspend(state, clusterName, true)
+	state.UI.Successf("Suspended cluster %s; `gpunow resume %s` brings it back", clusterName, clusterName)
+	return nil
+}
+
+func resumeCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if err := useRecordedCluster(state, clusterName); err != nil {
+		return err
+	}
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	announceWithKey(state, selection, false)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	if err := service.Resume(c.Context, clusterName, cluster.StartOptions{
+		SSHUser:         strings.TrimSpace(state.Config.SSH.DefaultUser),
+		SSHIdentityFile: selectionIdentityPath(selection),
+		OnStateChange:   clusterStateUpdateFn(state, clusterName),
+	}); err != nil {
+		return err
+	}
+	recordClusterSuspend(state, clusterName, false)
+	state.UI.Successf("Resumed cluster %s", clusterName)
+	return nil
+}
+
+func recordClusterSuspend(state *State, clusterName string, suspended bool) {
+	if state.State == nil {
+		return
+	}
+	if err := state.State.RecordClusterSuspend(clusterName, suspended, time.Now()); err != nil {
+		state.UI.Warnf("Failed to update state: %v", err)
+	}
+}
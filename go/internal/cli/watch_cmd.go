@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gpunow/internal/cluster"
+	"gpunow/internal/lifecycle"
+	appstate "gpunow/internal/state"
+)
+
+func watchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "Watch a cluster and recover preempted nodes",
+		ArgsUsage: "<cluster>",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: "interval", Value: 30 * time.Second, Usage: "Polling interval"},
+			&cli.BoolFlag{Name: "once", Usage: "Poll and recover once, then exit"},
+		},
+		Action: watchCluster,
+	}
+}
+
+func watchCluster(c *cli.Context) error {
+	state, err := GetState(c)
+	if err != nil {
+		return err
+	}
+	clusterName, err := requireArgWithHelp(c, 0, "cluster name")
+	if err != nil {
+		return err
+	}
+	if state.State == nil {
+		return fmt.Errorf("state store is unavailable")
+	}
+	data, err := state.State.Load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil || entry.NumInstances <= 0 {
+		return usageError(c, fmt.Sprintf("cluster %s not found in state; run `gpunow create %s -n <num>` first", clusterName, clusterName))
+	}
+	applyRecordedCluster(state, entry)
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return usageError(c, "--interval must be positive")
+	}
+	once := c.Bool("once") || hasBoolArg(c.Args().Slice(), "once")
+
+	selection, err := resolveSSHSelection(state)
+	if err != nil {
+		return err
+	}
+	user := strings.TrimSpace(state.Config.SSH.DefaultUser)
+	if selection != nil && selection.Key != "" && user == "" {
+		return fmt.Errorf("ssh.default_user is required to set ssh keys")
+	}
+	announceWithKey(state, selection, true)
+
+	compute, err := state.ComputeClient(c.Context)
+	if err != nil {
+		return err
+	}
+
+	service := cluster.NewService(compute, state.Config, state.UI, state.Logger)
+	options := cluster.WatchOptions{
+		Start: applyClusterConfig(cluster.StartOptions{
+			NumInstances:    entry.NumInstances,
+			SSHUser:         user,
+			SSHPublicKey:    selectionKey(selection),
+			SSHIdentityFile: selectionIdentityPath(selection),
+			OnStateChange:   clusterStateUpdateFn(state, clusterName),
+			OnImage:         clusterImageRecordFn(state, clusterName),
+		}, entry.Config),
+		Interval:      interval,
+		ShouldRecover: expectedRunningFn(state, clusterName),
+		OnRecovery: func(event cluster.RecoveryEvent) {
+			recordRecovery(state, clusterName, event)
+		},
+	}
+	if options.Start.ReservedSubnetCIDRs, err = reservedSubnetCIDRs(state, clusterName); err != nil {
+		return err
+	}
+	if once {
+		_, err := service.RecoverOnce(c.Context, clusterName, options)
+		return err
+	}
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return service.Watch(ctx, clusterName, options)
+}
+
+// expectedRunningFn reports whether state still expects a node to be up.
+// Nodes stopped through gpunow are TERMINATING or TERMINATED in state and are
+// left alone; nodes that were READY (or mid-recovery) are recovered.
+func expectedRunningFn(state *State, clusterName string) func(name string) bool {
+	return func(name string) bool {
+		data, err := state.State.Load()
+		if err != nil {
+			state.UI.Warnf("Failed to read state: %v", err)
+			return false
+		}
+		entry := data.Clusters[clusterName]
+		if entry == nil || entry.Status == "deleted" {
+			return false
+		}
+		instance := entry.Instances[name]
+		if instance == nil {
+			return false
+		}
+		switch lifecycle.NormalizeInstanceState(instance.State) {
+		case lifecycle.InstanceStateReady, lifecycle.InstanceStateStarting, lifecycle.InstanceStateProvisioning:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func recordRecovery(state *State, clusterName string, event cluster.RecoveryEvent) {
+	recovery := appstate.ClusterRecovery{
+		Instance:  event.Instance,
+		Index:     event.Index,
+		Action:    event.Action,
+		Reason:    event.Reason,
+		StartedAt: event.StartedAt.UTC().Format(time.RFC3339),
+	}
+	if event.Err != nil {
+		recovery.Error = event.Err.Error()
+		state.UI.Warnf("Failed to %s %s (%s): %v", event.Action, event.Instance, event.Reason, event.Err)
+	} else {
+		state.UI.Successf("Recovered %s (%s, %s) in %s", event.Instance, event.Reason, event.Action, event.Duration.Round(time.Second))
+	}
+	if err := state.State.RecordClusterRecovery(clusterName, recovery, time.Now()); err != nil {
+		state.UI.Warnf("Failed to update state: %v", err)
+	}
+}
@@ -7,11 +7,28 @@ import (
 )
 
 const (
-	setupPlaceholder = "{{SETUP_SH}}"
-	zshrcPlaceholder = "{{ZSHRC}}"
+	setupPlaceholder      = "{{SETUP_SH}}"
+	zshrcPlaceholder      = "{{ZSHRC}}"
+	diskMountsPlaceholder = "{{DISK_MOUNTS}}"
+	ufwRulesPlaceholder   = "{{UFW_RULES}}"
 )
 
-func Render(templatePath, setupPath, zshrcPath string) (string, error) {
+// Mount is a filesystem the node formats and mounts at boot. Devices holds
+// the /dev/disk/by-id paths backing it; more than one is striped into a
+// RAID 0 array named after Name. ReadOnly mounts a single device as is,
+// without ever formatting it.
+type Mount struct {
+	Name     string
+	Path     string
+	Devices  []string
+	ReadOnly bool
+}
+
+// Render fills the template placeholders with the setup script, the zshrc,
+// a mount script for mounts and one "ufw allow" line per ufwRules entry. A
+// template without {{DISK_MOUNTS}} or {{UFW_RULES}} is only accepted when
+// there is nothing to mount or allow.
+func Render(templatePath, setupPath, zshrcPath string, mounts []Mount, ufwRules []string) (string, error) {
 	tpl, err := os.ReadFile(templatePath)
 	if err != nil {
 		return "", fmt.Errorf("read cloud-init template: %w", err)
@@ -25,12 +42,76 @@ func Render(templatePath, setupPath, zshrcPath string) (string, error) {
 		return "", fmt.Errorf("read zshrc: %w", err)
 	}
 
+	if len(mounts) > 0 && !strings.Contains(string(tpl), diskMountsPlaceholder) {
+		return "", fmt.Errorf("cloud-init template %s has no %s placeholder for disk.extra mounts", templatePath, diskMountsPlaceholder)
+	}
+
+	if len(ufwRules) > 0 && !strings.Contains(string(tpl), ufwRulesPlaceholder) {
+		return "", fmt.Errorf("cloud-init template %s has no %s placeholder for the cluster's ports", templatePath, ufwRulesPlaceholder)
+	}
+	allows := make([]string, 0, len(ufwRules))
+	for _, rule := range ufwRules {
+		allows = append(allows, "ufw allow "+rule)
+	}
+
 	lines := strings.Split(string(tpl), "\n")
 	lines = replacePlaceholder(lines, setupPlaceholder, string(setup))
 	lines = replacePlaceholder(lines, zshrcPlaceholder, string(zshrc))
+	lines = replacePlaceholder(lines, diskMountsPlaceholder, MountScript(mounts))
+	lines = replacePlaceholder(lines, ufwRulesPlaceholder, strings.Join(allows, "\n"))
 	return strings.Join(lines, "\n"), nil
 }
 
+// mountFunction formats a device on first use and mounts it. Local SSDs
+// come back blank after a stop, so the check runs on every boot. Read-only
+// disks are shared with other nodes and mounted as they are; noload (ext3
+// and ext4) and norecovery (XFS) skip journal replay, which would otherwise
+// write to the device. Other filesystems mount with plain ro.
+const mountFunction = `mount_disk() {
+  local name="$1" target="$2"
+  shift 2
+  mountpoint -q "$target" && return 0
+  local device="$1"
+  if [ "$#" -gt 1 ]; then
+    device="/dev/md/$name"
+    [ -e "$device" ] || mdadm --create "$device" --level=0 --raid-devices="$#" "$@" --force --run
+  fi
+  blkid "$device" >/dev/null 2>&1 || mkfs.ext4 -F -m 0 "$device"
+  mkdir -p "$target"
+  mount -o discard,defaults "$device" "$target"
+  chmod 1777 "$target"
+}
+
+mount_readonly() {
+  local target="$1" device="$2"
+  mountpoint -q "$target" && return 0
+  mkdir -p "$target"
+  local options=ro
+  case "$(blkid -o value -s TYPE "$device" || true)" in
+    ext3|ext4) options=ro,noload ;;
+    xfs) options=ro,norecovery ;;
+  esac
+  mount -o "$options" "$device" "$target"
+}`
+
+// MountScript returns the shell script that mounts mounts at boot.
+func MountScript(mounts []Mount) string {
+	var sb strings.Builder
+	sb.WriteString("#!/usr/bin/env bash\nset -euo pipefail\n")
+	if len(mounts) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("\n" + mountFunction + "\n\n")
+	for _, mount := range mounts {
+		if mount.ReadOnly {
+			fmt.Fprintf(&sb, "mount_readonly %s %s\n", mount.Path, mount.Devices[0])
+			continue
+		}
+		fmt.Fprintf(&sb, "mount_disk %s %s %s\n", mount.Name, mount.Path, strings.Join(mount.Devices, " "))
+	}
+	return sb.String()
+}
+
 func replacePlaceholder(lines []string, placeholder string, content string) []string {
 	if placeholder == "" {
 		return lines
@@ -42,6 +123,9 @@ func replacePlaceholder(lines []string, placeholder string, content string) []st
 			out = append(out, line)
 			continue
 		}
+		if content == "" {
+			continue
+		}
 		indent := line[:strings.Index(line, placeholder)]
 		for _, contentLine := range contentLines {
 			out = append(out, indent+contentLine)
@@ -27,7 +27,7 @@ func TestRender(t *testing.T) {
 		t.Fatalf("write zshrc: %v", err)
 	}
 
-	rendered, err := Render(tplPath, setupPath, zshrcPath)
+	rendered, err := Render(tplPath, setupPath, zshrcPath, nil, nil)
 	if err != nil {
 		t.Fatalf("render: %v", err)
 	}
@@ -38,3 +38,91 @@ func TestRender(t *testing.T) {
 		t.Fatalf("rendered content missing zshrc lines:\n%s", rendered)
 	}
 }
+
+func TestRenderDiskMounts(t *testing.T) {
+	tmp := t.TempDir()
+	tplPath := filepath.Join(tmp, "cloud-init.yaml")
+	setupPath := filepath.Join(tmp, "setup.sh")
+	zshrcPath := filepath.Join(tmp, "zshrc")
+	for path, content := range map[string]string{setupPath: "echo setup\n", zshrcPath: "export A=1\n", tplPath: "write_files:\n  - content: |\n      {{SETUP_SH}}\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	mounts := []Mount{{Name: "scratch", Path: "/scratch", Devices: []string{"/dev/disk/by-id/google-local-nvme-ssd-0", "/dev/disk/by-id/google-local-nvme-ssd-1"}}}
+
+	if _, err := Render(tplPath, setupPath, zshrcPath, nil, nil); err != nil {
+		t.Fatalf("render without mounts: %v", err)
+	}
+	if _, err := Render(tplPath, setupPath, zshrcPath, mounts, nil); err == nil || !strings.Contains(err.Error(), "{{DISK_MOUNTS}}") {
+		t.Fatalf("expected missing placeholder error, got %v", err)
+	}
+
+	tpl := "write_files:\n  - content: |\n      {{DISK_MOUNTS}}\n"
+	if err := os.WriteFile(tplPath, []byte(tpl), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	rendered, err := Render(tplPath, setupPath, zshrcPath, mounts, nil)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(rendered, "      mount_disk scratch /scratch /dev/disk/by-id/google-local-nvme-ssd-0 /dev/disk/by-id/google-local-nvme-ssd-1") {
+		t.Fatalf("rendered content missing mount line:\n%s", rendered)
+	}
+	if !strings.Contains(rendered, "      #!/usr/bin/env bash") {
+		t.Fatalf("rendered content missing indented script:\n%s", rendered)
+	}
+	readOnly := MountScript([]Mount{{Name: "dataset", Path: "/mnt/dataset", Devices: []string{"/dev/disk/by-id/google-dataset"}, ReadOnly: true}})
+	if !strings.Contains(readOnly, "\nmount_readonly /mnt/dataset /dev/disk/by-id/google-dataset\n") || strings.Contains(readOnly, "\nmount_disk dataset") {
+		t.Fatalf("expected a read-only mount without formatting, got:\n%s", readOnly)
+	}
+	for _, want := range []string{
+		`blkid -o value -s TYPE "$device"`,
+		"ext3|ext4) options=ro,noload ;;",
+		"xfs) options=ro,norecovery ;;",
+		`mount -o "$options" "$device" "$target"`,
+	} {
+		if !strings.Contains(readOnly, want) {
+			t.Fatalf("expected the read-only mount to pick its options by filesystem (%s), got:\n%s", want, readOnly)
+		}
+	}
+	if script := MountScript(nil); strings.Contains(script, "mount_disk") {
+		t.Fatalf("expected a no-op script without mounts, got:\n%s", script)
+	}
+}
+
+func TestRenderUFWRules(t *testing.T) {
+	tmp := t.TempDir()
+	tplPath := filepath.Join(tmp, "cloud-init.yaml")
+	setupPath := filepath.Join(tmp, "setup.sh")
+	zshrcPath := filepath.Join(tmp, "zshrc")
+	tpl := "runcmd:\n  - |\n    ufw allow 22/tcp\n    {{UFW_RULES}}\n    ufw --force enable\n"
+	for path, content := range map[string]string{setupPath: "echo setup\n", zshrcPath: "export A=1\n", tplPath: "runcmd: []\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	rules := []string{"6006/tcp", "from 10.8.0.0/16 to any port 60000:61000 proto udp"}
+	if _, err := Render(tplPath, setupPath, zshrcPath, nil, rules); err == nil || !strings.Contains(err.Error(), "{{UFW_RULES}}") {
+		t.Fatalf("expected missing placeholder error, got %v", err)
+	}
+
+	if err := os.WriteFile(tplPath, []byte(tpl), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	rendered, err := Render(tplPath, setupPath, zshrcPath, nil, rules)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	want := "    ufw allow 22/tcp\n    ufw allow 6006/tcp\n    ufw allow from 10.8.0.0/16 to any port 60000:61000 proto udp\n    ufw --force enable\n"
+	if !strings.Contains(rendered, want) {
+		t.Fatalf("rendered content missing ufw rules:\n%s", rendered)
+	}
+	rendered, err = Render(tplPath, setupPath, zshrcPath, nil, nil)
+	if err != nil {
+		t.Fatalf("render without rules: %v", err)
+	}
+	if !strings.Contains(rendered, "    ufw allow 22/tcp\n    ufw --force enable\n") {
+		t.Fatalf("expected the placeholder line to be dropped:\n%s", rendered)
+	}
+}
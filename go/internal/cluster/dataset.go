@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/cloudinit"
+	"gpunow/internal/config"
+	"gpunow/internal/gcp"
+)
+
+// datasetDisk returns the disk named by cluster.shared_dataset_disk, or ""
+// when it is not set. A snapshot source returns the snapshot URL too and a
+// disk named <network_name_prefix>-dataset-<snapshot>, shared by every
+// cluster in the zone.
+func (s *Service) datasetDisk() (disk, snapshot string) {
+	source := strings.TrimSpace(s.Config.Cluster.SharedDatasetDisk)
+	if source == "" {
+		return "", ""
+	}
+	if !strings.Contains(source, "snapshots/") {
+		return source, ""
+	}
+	name := gcp.ShortName(source)
+	snapshot = source
+	if !strings.HasPrefix(source, "projects/") && !strings.HasPrefix(source, "https://") {
+		snapshot = gcp.GlobalResource(s.Config.Project.ID, "snapshots", name)
+	}
+	return fmt.Sprintf("%s-dataset-%s", s.Config.Cluster.NetworkNamePrefix, name), snapshot
+}
+
+// datasetMounts returns the disk.extra mounts plus the read-only dataset
+// mount when cluster.shared_dataset_disk is set.
+func (s *Service) datasetMounts() []cloudinit.Mount {
+	mounts := s.Builder.Mounts()
+	if disk, _ := s.datasetDisk(); disk != "" {
+		mounts = append(mounts, cloudinit.Mount{
+			Name:     config.DatasetDeviceName,
+			Path:     config.DatasetMountPath,
+			Devices:  []string{"/dev/disk/by-id/google-" + config.DatasetDeviceName},
+			ReadOnly: true,
+		})
+	}
+	return mounts
+}
+
+// ensureDatasetDisk checks that the shared dataset disk exists in the
+// cluster zone, creating it from its snapshot when it has one. The disk is
+// never labeled as managed, so neither stop --delete nor gc removes it.
+func (s *Service) ensureDatasetDisk(ctx context.Context, network clusterNetwork) error {
+	project := network.Project
+	zone := network.Zone
+	name := network.DatasetDisk
+
+	getCall := s.api("compute.disks.get", gcp.ZoneResource(project, zone, "disks", name), "")
+	_, err := s.Compute.GetDisk(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: name})
+	getCall.Stop()
+	if err == nil {
+		return nil
+	}
+	if !gcp.IsNotFound(err) {
+		return err
+	}
+	if network.DatasetSnapshot == "" {
+		return missingDatasetDisk(network)
+	}
+
+	call := s.api("compute.disks.insert", gcp.ZoneResource(project, zone, "disks", name), fmt.Sprintf("Creating dataset disk %s", name))
+	op, err := s.Compute.InsertDisk(ctx, &computepb.InsertDiskRequest{
+		Project: project,
+		Zone:    zone,
+		DiskResource: &computepb.Disk{
+			Name:           proto.String(name),
+			SourceSnapshot: proto.String(network.DatasetSnapshot),
+			Type:           proto.String(gcp.ZoneResource(project, zone, "diskTypes", s.Config.Disk.Type)),
+		},
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+// missingDatasetDisk is the error for a dataset disk without a snapshot to
+// create it from.
+func missingDatasetDisk(network clusterNetwork) error {
+	return fmt.Errorf("cluster.shared_dataset_disk %s does not exist in %s", network.DatasetDisk, network.Zone)
+}
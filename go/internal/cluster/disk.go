@@ -0,0 +1,159 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/ui"
+	"gpunow/internal/validate"
+)
+
+type DiskResizeOptions struct {
+	SizeGB int
+	// Nodes limits the resize to these instances; empty resizes every node.
+	Nodes []string
+	// SSHUser, SSHPublicKey and SSHIdentityFile reach running nodes to grow
+	// the root filesystem.
+	SSHUser         string
+	SSHPublicKey    string
+	SSHIdentityFile string
+}
+
+// growRootFilesystem grows the partition holding / and its filesystem to
+// the size of the disk, then prints the new size. growpart exits 1 when the
+// partition already fills the disk.
+var growRootFilesystem = []string{
+	`root=$(findmnt -no SOURCE /)`,
+	`sudo growpart /dev/$(lsblk -no PKNAME "$root") $(cat /sys/class/block/$(basename "$root")/partition) || [ $? -eq 1 ]`,
+	`case $(findmnt -no FSTYPE /) in xfs) sudo xfs_growfs / ;; *) sudo resize2fs "$root" ;; esac >/dev/null`,
+	`df -h --output=size / | tail -1`,
+}
+
+// ResizeDisks grows the boot disk of every node (named after the instance)
+// to opts.SizeGB and, on running nodes, the root filesystem over SSH.
+// Stopped nodes grow their filesystem on the next boot. Disks cannot shrink;
+// a disk already at the size only gets its filesystem grown.
+func (s *Service) ResizeDisks(ctx context.Context, clusterName string, opts DiskResizeOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.SizeGB <= 0 {
+		return fmt.Errorf("size-gb must be >= 1")
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances found for cluster %s", clusterName)
+	}
+	masterIP := clusterMasterIP(instances)
+	if len(opts.Nodes) > 0 {
+		if instances, err = selectInstances(clusterName, instances, opts.Nodes); err != nil {
+			return err
+		}
+	}
+	running := []*computepb.Instance{}
+	for _, inst := range instances {
+		if inst.GetStatus() == "RUNNING" {
+			running = append(running, inst)
+		}
+	}
+	if len(running) > 0 && opts.SSHUser == "" {
+		return fmt.Errorf("ssh.default_user is required to grow filesystems on running nodes")
+	}
+
+	// Every disk is checked before any is resized, so a disk that would
+	// shrink leaves the whole cluster untouched.
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	sizes := make([]int64, len(instances))
+	for i, inst := range instances {
+		getCall := s.api("compute.disks.get", gcp.ZoneResource(project, zone, "disks", inst.GetName()), "")
+		disk, err := s.Compute.GetDisk(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: inst.GetName()})
+		getCall.Stop()
+		if err != nil {
+			return fmt.Errorf("get boot disk of %s: %w", inst.GetName(), err)
+		}
+		if disk.GetSizeGb() > int64(opts.SizeGB) {
+			return fmt.Errorf("boot disk of %s is %d GB; disks cannot shrink", inst.GetName(), disk.GetSizeGb())
+		}
+		sizes[i] = disk.GetSizeGb()
+	}
+
+	taskNames := make([]string, len(instances))
+	for i, inst := range instances {
+		taskNames[i] = fmt.Sprintf("disk %s", inst.GetName())
+	}
+	progress := s.UI.TaskList("Resizing disks", taskNames)
+	defer progress.Stop()
+
+	if err := s.ensureSSHKeys(ctx, running, opts.SSHUser, opts.SSHPublicKey); err != nil {
+		return err
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for idx, inst := range instances {
+		group.Go(func() error {
+			if err := s.resizeNodeDisk(groupCtx, inst, sizes[idx], masterIP, opts, progress, idx); err != nil {
+				progress.MarkWarning(idx, fmt.Sprintf("Failed %s: %v", inst.GetName(), err))
+				return fmt.Errorf("resize disk of %s: %w", inst.GetName(), err)
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// resizeNodeDisk grows the boot disk of inst from size to opts.SizeGB, then
+// its root filesystem when it is running.
+func (s *Service) resizeNodeDisk(ctx context.Context, inst *computepb.Instance, size int64, masterIP string, opts DiskResizeOptions, progress *ui.TaskList, progressIndex int) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	name := inst.GetName()
+
+	if size < int64(opts.SizeGB) {
+		call := s.api("compute.disks.resize", gcp.ZoneResource(project, zone, "disks", name), fmt.Sprintf("Resizing %s to %d GB", name, opts.SizeGB))
+		op, err := s.Compute.ResizeDisk(ctx, &computepb.ResizeDiskRequest{
+			Project: project,
+			Zone:    zone,
+			Disk:    name,
+			DisksResizeRequestResource: &computepb.DisksResizeRequest{
+				SizeGb: proto.Int64(int64(opts.SizeGB)),
+			},
+		})
+		if err != nil {
+			call.Stop()
+			return err
+		}
+		if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+			return err
+		}
+	}
+
+	if inst.GetStatus() != "RUNNING" {
+		progress.MarkDone(progressIndex, fmt.Sprintf("Resized %s to %d GB; the filesystem grows on next boot", name, opts.SizeGB))
+		return nil
+	}
+	target, jumpHost, err := guestSSHTarget(inst, masterIP, opts.SSHUser, opts.SSHIdentityFile)
+	if err != nil {
+		return err
+	}
+	output, err := s.runGuestSSH(ctx, guestSSHArgs(target, jumpHost, growRootFilesystem))
+	s.Logger.Debug("grow root filesystem finished", zap.String("instance", name), zap.String("output", output), zap.Error(err))
+	if err != nil {
+		return fmt.Errorf("grow filesystem: %w", err)
+	}
+	message := fmt.Sprintf("Resized %s to %d GB", name, opts.SizeGB)
+	if line := lastLine(output); line != "" {
+		message = fmt.Sprintf("%s (/ is %s)", message, line)
+	}
+	progress.MarkDone(progressIndex, message)
+	return nil
+}
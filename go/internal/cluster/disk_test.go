@@ -0,0 +1,234 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/config"
+	"gpunow/internal/labels"
+)
+
+func TestResizeDisksGrowsDiskAndFilesystem(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	var mu sync.Mutex
+	commands := []string{}
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, strings.Join(args, " "))
+		return "500G\n", nil
+	}
+	if _, err := service.Start(context.Background(), "data", StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, name := range []string{"data-0", "data-1", "data-2"} {
+		compute.disks[zone+"/"+name] = &computepb.Disk{Name: proto.String(name), SizeGb: proto.Int64(200)}
+	}
+	if err := service.Stop(context.Background(), "data", StopOptions{Nodes: []string{"data-2"}}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	opts := DiskResizeOptions{SizeGB: 500}
+	if err := service.ResizeDisks(context.Background(), "data", opts); err == nil || !strings.Contains(err.Error(), "ssh.default_user") {
+		t.Fatalf("expected missing ssh user error, got %v", err)
+	}
+	opts.SSHUser = "mo"
+	compute.calls = nil
+	if err := service.ResizeDisks(context.Background(), "data", opts); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	want := []string{"resizeDisk data-0", "resizeDisk data-1", "resizeDisk data-2"}
+	if got := compute.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	if got := compute.disks[zone+"/data-1"].GetSizeGb(); got != 500 {
+		t.Fatalf("data-1 disk size = %d", got)
+	}
+	// The stopped node grows its filesystem on the next boot.
+	if len(commands) != 2 || !strings.Contains(commands[0], "growpart") {
+		t.Fatalf("expected growpart on the two running nodes, got %q", commands)
+	}
+
+	// A rerun only grows filesystems; shrinking is refused.
+	compute.calls = nil
+	if err := service.ResizeDisks(context.Background(), "data", DiskResizeOptions{SizeGB: 500, SSHUser: "mo", Nodes: []string{"data-1"}}); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if got := compute.Calls(); len(got) != 0 {
+		t.Fatalf("expected no resize calls on rerun, got %v", got)
+	}
+	err := service.ResizeDisks(context.Background(), "data", DiskResizeOptions{SizeGB: 100, SSHUser: "mo"})
+	if err == nil || !strings.Contains(err.Error(), "cannot shrink") {
+		t.Fatalf("expected shrink error, got %v", err)
+	}
+
+	// One disk that would shrink stops the run before any disk is resized.
+	compute.disks[zone+"/data-2"].SizeGb = proto.Int64(800)
+	compute.calls = nil
+	err = service.ResizeDisks(context.Background(), "data", DiskResizeOptions{SizeGB: 600, SSHUser: "mo"})
+	if err == nil || !strings.Contains(err.Error(), "data-2") {
+		t.Fatalf("expected shrink error for data-2, got %v", err)
+	}
+	if got := compute.Calls(); len(got) != 0 {
+		t.Fatalf("expected no resize calls, got %v", got)
+	}
+}
+
+func TestPoolDiskSizeKeepsRecordedResize(t *testing.T) {
+	service := newTestService(t, newFakeCompute())
+	service.Config.Cluster.Pools = []config.PoolConfig{{Name: "gpu", Count: 1, DiskSizeGB: 300}}
+	node := clusterNode{Cluster: "data", Name: "data-gpu-0", Pool: "gpu"}
+	if got := service.nodeOptions(node, StartOptions{}).DiskSizeGB; got != 300 {
+		t.Fatalf("pool disk size = %d, want 300", got)
+	}
+	if got := service.nodeOptions(node, StartOptions{DiskSizeGB: 500}).DiskSizeGB; got != 500 {
+		t.Fatalf("resized disk size = %d, want 500", got)
+	}
+}
+
+func TestStartAttachesAndMountsExtraDisks(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	service.Config.Disk.Extra = []config.ExtraDiskConfig{
+		{Name: "data", Type: "pd-balanced", SizeGB: 1000, KeepOnDelete: true, MountPath: "/data"},
+		{Name: "scratch", Type: config.LocalSSDType, Count: 2, Interface: "NVME", MountPath: "/scratch"},
+	}
+	// data-1 kept its data disk from an earlier node.
+	compute.disks[zone+"/data-1-data"] = &computepb.Disk{Name: proto.String("data-1-data"), SizeGb: proto.Int64(1000)}
+
+	if _, err := service.Start(context.Background(), "data", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, name := range []string{"data-0", "data-1"} {
+		inst := compute.instances[zone+"/"+name]
+		disks := inst.GetDisks()
+		if len(disks) != 4 {
+			t.Fatalf("%s: expected boot, data and two local SSDs, got %d disks", name, len(disks))
+		}
+		data := disks[1]
+		if data.GetDeviceName() != "data" || data.GetAutoDelete() {
+			t.Fatalf("%s: unexpected data disk %v", name, data)
+		}
+		if name == "data-1" && (data.GetSource() == "" || data.GetInitializeParams() != nil) {
+			t.Fatalf("expected the existing data-1-data disk to be reattached, got %v", data)
+		}
+		if name == "data-0" && (data.GetInitializeParams().GetDiskName() != "data-0-data" || data.GetInitializeParams().GetLabels()[labels.KeepKey] != labels.KeepValue) {
+			t.Fatalf("expected data-0-data to be created with the keep label, got %v", data)
+		}
+		for _, ssd := range disks[2:] {
+			if ssd.GetType() != "SCRATCH" || ssd.GetInterface() != "NVME" || !ssd.GetAutoDelete() {
+				t.Fatalf("%s: unexpected local SSD %v", name, ssd)
+			}
+		}
+		userData := ""
+		for _, item := range inst.GetMetadata().GetItems() {
+			if item.GetKey() == "user-data" {
+				userData = item.GetValue()
+			}
+		}
+		for _, want := range []string{
+			"mount_disk data /data /dev/disk/by-id/google-data",
+			"mount_disk scratch /scratch /dev/disk/by-id/google-local-nvme-ssd-0 /dev/disk/by-id/google-local-nvme-ssd-1",
+		} {
+			if !strings.Contains(userData, want) {
+				t.Fatalf("%s: user-data missing %q", name, want)
+			}
+		}
+	}
+}
+
+func TestStopAndSuspendDiscardLocalSSDs(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "plain", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start plain: %v", err)
+	}
+	service.Config.Disk.Extra = []config.ExtraDiskConfig{
+		{Name: "scratch", Type: config.LocalSSDType, Count: 1, Interface: "NVME", MountPath: "/scratch"},
+	}
+	if _, err := service.Start(context.Background(), "ssd", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start ssd: %v", err)
+	}
+
+	if err := service.Stop(context.Background(), "plain", StopOptions{}); err != nil {
+		t.Fatalf("stop plain: %v", err)
+	}
+	if err := service.Stop(context.Background(), "ssd", StopOptions{}); err != nil {
+		t.Fatalf("stop ssd: %v", err)
+	}
+	compute.instances[zone+"/ssd-0"].Status = proto.String("RUNNING")
+	if err := service.Suspend(context.Background(), "ssd", SuspendOptions{}); err != nil {
+		t.Fatalf("suspend ssd: %v", err)
+	}
+	if want := []string{"stop ssd-0", "suspend ssd-0"}; !reflect.DeepEqual(compute.discards, want) {
+		t.Fatalf("discards = %v, want %v", compute.discards, want)
+	}
+}
+
+func TestSharedDatasetDiskIsAttachedReadOnlyAndKept(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	service.Config.Cluster.SharedDatasetDisk = "imagenet"
+
+	if _, err := service.Start(context.Background(), "data", StartOptions{NumInstances: 2}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing dataset disk error, got %v", err)
+	}
+
+	service.Config.Cluster.SharedDatasetDisk = "snapshots/imagenet-v2"
+	if _, err := service.Start(context.Background(), "data", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	datasetDisk := compute.disks[zone+"/gpunow-dataset-imagenet-v2"]
+	if datasetDisk == nil || !strings.HasSuffix(datasetDisk.GetSourceSnapshot(), "/global/snapshots/imagenet-v2") {
+		t.Fatalf("expected the dataset disk to be created from the snapshot, got %v", datasetDisk)
+	}
+	if len(datasetDisk.GetLabels()) != 0 {
+		t.Fatalf("dataset disk must not carry managed labels: %v", datasetDisk.GetLabels())
+	}
+	for _, name := range []string{"data-0", "data-1"} {
+		inst := compute.instances[zone+"/"+name]
+		disks := inst.GetDisks()
+		dataset := disks[len(disks)-1]
+		if dataset.GetDeviceName() != "dataset" || dataset.GetMode() != "READ_ONLY" || dataset.GetAutoDelete() || !strings.HasSuffix(dataset.GetSource(), "/disks/gpunow-dataset-imagenet-v2") {
+			t.Fatalf("%s: unexpected dataset disk %v", name, dataset)
+		}
+		userData := ""
+		for _, item := range inst.GetMetadata().GetItems() {
+			if item.GetKey() == "user-data" {
+				userData = item.GetValue()
+			}
+		}
+		if !strings.Contains(userData, "mount_readonly /mnt/dataset /dev/disk/by-id/google-dataset") {
+			t.Fatalf("%s: user-data missing the dataset mount", name)
+		}
+	}
+
+	compute.calls = nil
+	if err := service.Stop(context.Background(), "data", StopOptions{Delete: true, DeleteDisks: true}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	for _, call := range compute.Calls() {
+		if strings.Contains(call, "dataset") {
+			t.Fatalf("stop --delete touched the dataset disk: %v", compute.Calls())
+		}
+	}
+	for _, autoDelete := range compute.autoDeletes {
+		if strings.HasSuffix(autoDelete, "/dataset") {
+			t.Fatalf("dataset disk was set to auto-delete: %v", compute.autoDeletes)
+		}
+	}
+	if compute.disks[zone+"/gpunow-dataset-imagenet-v2"] == nil {
+		t.Fatalf("dataset disk was deleted")
+	}
+}
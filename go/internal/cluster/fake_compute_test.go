@@ -0,0 +1,649 @@
+package cluster
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/googleapis/gax-go/v2"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/config"
+	"gpunow/internal/gcp"
+	"gpunow/internal/ui"
+)
+
+// fakeCompute is an in-memory gcp.Compute covering the instance, disk,
+// network, subnetwork, firewall, router and resource policy calls used by cluster
+// lifecycle code. Instances are keyed by zone so zone fallback can be exercised. Calls
+// it does not implement panic through the nil embedded interface.
+type fakeCompute struct {
+	gcp.Compute
+
+	mu        sync.Mutex
+	instances map[string]*computepb.Instance
+	resources map[string]bool
+	firewalls map[string]*computepb.Firewall
+	subnets   map[string]*computepb.Subnetwork
+	disks     map[string]*computepb.Disk
+	routers   map[string]*computepb.Router
+	exhausted map[string]bool
+	calls     []string
+	// labelRaces makes that many SetInstanceLabels calls see a label change
+	// by someone else first, so their fingerprint is stale.
+	labelRaces int
+	// autoDeletes lists the SetDiskAutoDelete calls that turned auto-delete
+	// on, as instance/device.
+	autoDeletes []string
+	// discards lists the stop and suspend calls that discard local SSD
+	// data, as "action name".
+	discards []string
+	// unsuspendable lists the instances whose suspend GCE refuses, as for
+	// GPU machine configurations.
+	unsuspendable map[string]bool
+}
+
+// fakeCreated is the creation timestamp of every fake resource.
+const fakeCreated = "2024-01-01T00:00:00Z"
+
+func newFakeCompute() *fakeCompute {
+	return &fakeCompute{
+		instances: map[string]*computepb.Instance{},
+		resources: map[string]bool{},
+		firewalls: map[string]*computepb.Firewall{},
+		subnets:   map[string]*computepb.Subnetwork{},
+		disks:     map[string]*computepb.Disk{},
+		routers:   map[string]*computepb.Router{},
+		exhausted: map[string]bool{},
+	}
+}
+
+func (f *fakeCompute) put(zone string, inst *computepb.Instance) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inst.Zone = proto.String(zone)
+	f.instances[zone+"/"+inst.GetName()] = inst
+}
+
+func (f *fakeCompute) get(zone, name string) *computepb.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.instances[zone+"/"+name]
+}
+
+func (f *fakeCompute) record(call string) {
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeCompute) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := append([]string{}, f.calls...)
+	sort.Strings(calls)
+	return calls
+}
+
+func (f *fakeCompute) Status(zone, name string) string {
+	if inst := f.get(zone, name); inst != nil {
+		return inst.GetStatus()
+	}
+	return ""
+}
+
+func (f *fakeCompute) GetInstance(ctx context.Context, req *computepb.GetInstanceRequest) (*computepb.Instance, error) {
+	inst := f.get(req.GetZone(), req.GetInstance())
+	if inst == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return proto.Clone(inst).(*computepb.Instance), nil
+}
+
+func (f *fakeCompute) ListInstances(ctx context.Context, req *computepb.ListInstancesRequest) gcp.InstanceIterator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []*computepb.Instance{}
+	for _, inst := range f.instances {
+		if inst.GetZone() != req.GetZone() {
+			continue
+		}
+		if fakeFilterMatches(req.GetFilter(), inst.GetLabels()) {
+			items = append(items, proto.Clone(inst).(*computepb.Instance))
+		}
+	}
+	return &fakeIterator[*computepb.Instance]{items: items}
+}
+
+var fakeLabelFilter = regexp.MustCompile(`labels\.(\w+) = "([^"]*)"`)
+
+// fakeFilterMatches evaluates the `labels.<key> = "<value>"` filters gpunow
+// sends to list calls.
+func fakeFilterMatches(filter string, labels map[string]string) bool {
+	for _, match := range fakeLabelFilter.FindAllStringSubmatch(filter, -1) {
+		if labels[match[1]] != match[2] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeCompute) StartInstance(ctx context.Context, req *computepb.StartInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("start " + req.GetInstance())
+	inst := f.instances[req.GetZone()+"/"+req.GetInstance()]
+	if inst == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	inst.Status = proto.String("RUNNING")
+	inst.NetworkInterfaces = fakeInterfaces()
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) StopInstance(ctx context.Context, req *computepb.StopInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("stop " + req.GetInstance())
+	if req.GetDiscardLocalSsd() {
+		f.discards = append(f.discards, "stop "+req.GetInstance())
+	}
+	inst := f.instances[req.GetZone()+"/"+req.GetInstance()]
+	if inst == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	inst.Status = proto.String("TERMINATED")
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) ResetInstance(ctx context.Context, req *computepb.ResetInstanceRequest) (gcp.Operation, error) {
+	return f.setStatus("reset", req.GetZone(), req.GetInstance(), "RUNNING")
+}
+
+func (f *fakeCompute) SuspendInstance(ctx context.Context, req *computepb.SuspendInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	if f.unsuspendable[req.GetInstance()] {
+		f.record("suspend " + req.GetInstance())
+		f.mu.Unlock()
+		return nil, &googleapi.Error{Code: 400, Message: "Instance with GPUs cannot be suspended"}
+	}
+	if req.GetDiscardLocalSsd() {
+		f.discards = append(f.discards, "suspend "+req.GetInstance())
+	}
+	f.mu.Unlock()
+	return f.setStatus("suspend", req.GetZone(), req.GetInstance(), "SUSPENDED")
+}
+
+func (f *fakeCompute) ResumeInstance(ctx context.Context, req *computepb.ResumeInstanceRequest) (gcp.Operation, error) {
+	return f.setStatus("resume", req.GetZone(), req.GetInstance(), "RUNNING")
+}
+
+func (f *fakeCompute) setStatus(action, zone, name, status string) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(action + " " + name)
+	inst := f.instances[zone+"/"+name]
+	if inst == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	inst.Status = proto.String(status)
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inst := proto.Clone(req.GetInstanceResource()).(*computepb.Instance)
+	f.record("insert " + req.GetZone() + "/" + inst.GetName())
+	if f.exhausted[req.GetZone()] {
+		return nil, &googleapi.Error{Code: 503, Message: "ZONE_RESOURCE_POOL_EXHAUSTED"}
+	}
+	key := req.GetZone() + "/" + inst.GetName()
+	if f.instances[key] != nil {
+		return nil, &googleapi.Error{Code: 409}
+	}
+	inst.Zone = proto.String(req.GetZone())
+	inst.Status = proto.String("RUNNING")
+	public := len(inst.GetNetworkInterfaces()) > 0 && len(inst.GetNetworkInterfaces()[0].GetAccessConfigs()) > 0
+	var ipv6 []*computepb.AccessConfig
+	if len(inst.GetNetworkInterfaces()) > 0 {
+		ipv6 = inst.GetNetworkInterfaces()[0].GetIpv6AccessConfigs()
+	}
+	inst.NetworkInterfaces = fakeInterfaces()
+	if !public {
+		inst.NetworkInterfaces[0].AccessConfigs = nil
+	}
+	for _, config := range ipv6 {
+		config.ExternalIpv6 = proto.String("2600:1900:4000:1::2")
+	}
+	inst.NetworkInterfaces[0].Ipv6AccessConfigs = ipv6
+	inst.CreationTimestamp = proto.String(fakeCreated)
+	f.instances[key] = inst
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) DeleteInstance(ctx context.Context, req *computepb.DeleteInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("delete " + req.GetZone() + "/" + req.GetInstance())
+	delete(f.instances, req.GetZone()+"/"+req.GetInstance())
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetDiskAutoDelete(ctx context.Context, req *computepb.SetDiskAutoDeleteInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.GetAutoDelete() {
+		f.autoDeletes = append(f.autoDeletes, req.GetInstance()+"/"+req.GetDeviceName())
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetInstanceTags(ctx context.Context, req *computepb.SetTagsInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("setTags " + req.GetInstance())
+	if inst := f.instances[req.GetZone()+"/"+req.GetInstance()]; inst != nil {
+		inst.Tags = req.GetTagsResource()
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetInstanceLabels(ctx context.Context, req *computepb.SetLabelsInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("setLabels " + req.GetInstance())
+	inst := f.instances[req.GetZone()+"/"+req.GetInstance()]
+	if inst == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	if f.labelRaces > 0 {
+		f.labelRaces--
+		inst.LabelFingerprint = proto.String(inst.GetLabelFingerprint() + "x")
+	}
+	if req.GetInstancesSetLabelsRequestResource().GetLabelFingerprint() != inst.GetLabelFingerprint() {
+		return nil, &googleapi.Error{Code: 412, Message: "Labels fingerprint either invalid or resource labels have changed"}
+	}
+	inst.Labels = req.GetInstancesSetLabelsRequestResource().GetLabels()
+	inst.LabelFingerprint = proto.String(inst.GetLabelFingerprint() + "+")
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetInstanceMachineType(ctx context.Context, req *computepb.SetMachineTypeInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("setMachineType " + req.GetInstance())
+	if inst := f.instances[req.GetZone()+"/"+req.GetInstance()]; inst != nil {
+		inst.MachineType = proto.String(req.GetInstancesSetMachineTypeRequestResource().GetMachineType())
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("setMetadata " + req.GetInstance())
+	if inst := f.instances[req.GetZone()+"/"+req.GetInstance()]; inst != nil {
+		inst.Metadata = req.GetMetadataResource()
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) SetInstanceScheduling(ctx context.Context, req *computepb.SetSchedulingInstanceRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("setScheduling " + req.GetInstance())
+	if inst := f.instances[req.GetZone()+"/"+req.GetInstance()]; inst != nil {
+		inst.Scheduling = req.GetSchedulingResource()
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) GetDisk(ctx context.Context, req *computepb.GetDiskRequest) (*computepb.Disk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	disk := f.disks[req.GetZone()+"/"+req.GetDisk()]
+	if disk == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return proto.Clone(disk).(*computepb.Disk), nil
+}
+
+func (f *fakeCompute) ListDisks(ctx context.Context, req *computepb.ListDisksRequest) gcp.Iterator[*computepb.Disk] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []*computepb.Disk{}
+	for key, disk := range f.disks {
+		if strings.HasPrefix(key, req.GetZone()+"/") && fakeFilterMatches(req.GetFilter(), disk.GetLabels()) {
+			items = append(items, proto.Clone(disk).(*computepb.Disk))
+		}
+	}
+	return &fakeIterator[*computepb.Disk]{items: items}
+}
+
+func (f *fakeCompute) InsertDisk(ctx context.Context, req *computepb.InsertDiskRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	disk := proto.Clone(req.GetDiskResource()).(*computepb.Disk)
+	f.record("insertDisk " + req.GetZone() + "/" + disk.GetName())
+	f.disks[req.GetZone()+"/"+disk.GetName()] = disk
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) ResizeDisk(ctx context.Context, req *computepb.ResizeDiskRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("resizeDisk " + req.GetDisk())
+	disk := f.disks[req.GetZone()+"/"+req.GetDisk()]
+	if disk == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	disk.SizeGb = proto.Int64(req.GetDisksResizeRequestResource().GetSizeGb())
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) DeleteDisk(ctx context.Context, req *computepb.DeleteDiskRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("deleteDisk " + req.GetZone() + "/" + req.GetDisk())
+	delete(f.disks, req.GetZone()+"/"+req.GetDisk())
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) has(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.resources[key] {
+		return &googleapi.Error{Code: 404}
+	}
+	return nil
+}
+
+func (f *fakeCompute) set(key string, present bool) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if present {
+		f.resources[key] = true
+	} else {
+		delete(f.resources, key)
+	}
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) Resources() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := []string{}
+	for key := range f.resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeCompute) GetNetwork(ctx context.Context, req *computepb.GetNetworkRequest) (*computepb.Network, error) {
+	return &computepb.Network{Name: proto.String(req.GetNetwork())}, f.has("networks/" + req.GetNetwork())
+}
+
+func (f *fakeCompute) InsertNetwork(ctx context.Context, req *computepb.InsertNetworkRequest) (gcp.Operation, error) {
+	return f.set("networks/"+req.GetNetworkResource().GetName(), true)
+}
+
+func (f *fakeCompute) GetSubnetwork(ctx context.Context, req *computepb.GetSubnetworkRequest) (*computepb.Subnetwork, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	subnet := f.subnets[req.GetRegion()+"/"+req.GetSubnetwork()]
+	if subnet == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return proto.Clone(subnet).(*computepb.Subnetwork), nil
+}
+
+func (f *fakeCompute) InsertSubnetwork(ctx context.Context, req *computepb.InsertSubnetworkRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	subnet := proto.Clone(req.GetSubnetworkResource()).(*computepb.Subnetwork)
+	subnet.CreationTimestamp = proto.String(fakeCreated)
+	if subnet.GetStackType() == "IPV4_IPV6" {
+		subnet.ExternalIpv6Prefix = proto.String(fakeIPv6Prefix)
+	}
+	f.subnets[req.GetRegion()+"/"+subnet.GetName()] = subnet
+	f.mu.Unlock()
+	return f.set("subnetworks/"+req.GetRegion()+"/"+subnet.GetName(), true)
+}
+
+// fakeIPv6Prefix is the external IPv6 range the fake assigns dual-stack subnets.
+const fakeIPv6Prefix = "2600:1900:4000:1::/64"
+
+func (f *fakeCompute) PatchSubnetwork(ctx context.Context, req *computepb.PatchSubnetworkRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	key := req.GetRegion() + "/" + req.GetSubnetwork()
+	subnet := f.subnets[key]
+	if subnet == nil {
+		f.mu.Unlock()
+		return nil, &googleapi.Error{Code: 404}
+	}
+	patch := req.GetSubnetworkResource()
+	if patch.StackType != nil {
+		subnet.StackType = proto.String(patch.GetStackType())
+		subnet.Ipv6AccessType = proto.String(patch.GetIpv6AccessType())
+		if patch.GetStackType() == "IPV4_IPV6" {
+			subnet.ExternalIpv6Prefix = proto.String(fakeIPv6Prefix)
+		}
+	}
+	f.mu.Unlock()
+	return f.set("subnetworks/"+key, true)
+}
+
+func (f *fakeCompute) ListSubnetworks(ctx context.Context, req *computepb.ListSubnetworksRequest) gcp.Iterator[*computepb.Subnetwork] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []*computepb.Subnetwork{}
+	for key, subnet := range f.subnets {
+		if strings.HasPrefix(key, req.GetRegion()+"/") {
+			items = append(items, proto.Clone(subnet).(*computepb.Subnetwork))
+		}
+	}
+	return &fakeIterator[*computepb.Subnetwork]{items: items}
+}
+
+func (f *fakeCompute) DeleteSubnetwork(ctx context.Context, req *computepb.DeleteSubnetworkRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	delete(f.subnets, req.GetRegion()+"/"+req.GetSubnetwork())
+	f.mu.Unlock()
+	return f.set("subnetworks/"+req.GetRegion()+"/"+req.GetSubnetwork(), false)
+}
+
+func (f *fakeCompute) DeleteNetwork(ctx context.Context, req *computepb.DeleteNetworkRequest) (gcp.Operation, error) {
+	return f.set("networks/"+req.GetNetwork(), false)
+}
+
+func (f *fakeCompute) ListNetworks(ctx context.Context, req *computepb.ListNetworksRequest) gcp.Iterator[*computepb.Network] {
+	items := []*computepb.Network{}
+	for _, key := range f.Resources() {
+		if name, ok := strings.CutPrefix(key, "networks/"); ok {
+			items = append(items, &computepb.Network{Name: proto.String(name), CreationTimestamp: proto.String(fakeCreated)})
+		}
+	}
+	return &fakeIterator[*computepb.Network]{items: items}
+}
+
+func (f *fakeCompute) GetFirewall(ctx context.Context, req *computepb.GetFirewallRequest) (*computepb.Firewall, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rule := f.firewalls[req.GetFirewall()]
+	if rule == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return proto.Clone(rule).(*computepb.Firewall), nil
+}
+
+func (f *fakeCompute) InsertFirewall(ctx context.Context, req *computepb.InsertFirewallRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rule := proto.Clone(req.GetFirewallResource()).(*computepb.Firewall)
+	rule.CreationTimestamp = proto.String(fakeCreated)
+	rule.SelfLink = proto.String(gcp.GlobalResource(req.GetProject(), "firewalls", rule.GetName()))
+	f.firewalls[rule.GetName()] = rule
+	f.resources["firewalls/"+rule.GetName()] = true
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) PatchFirewall(ctx context.Context, req *computepb.PatchFirewallRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("patchFirewall " + req.GetFirewall())
+	rule := proto.Clone(req.GetFirewallResource()).(*computepb.Firewall)
+	rule.SelfLink = proto.String(gcp.GlobalResource(req.GetProject(), "firewalls", req.GetFirewall()))
+	f.firewalls[req.GetFirewall()] = rule
+	return fakeOperation{}, nil
+}
+
+func (f *fakeCompute) ListFirewalls(ctx context.Context, req *computepb.ListFirewallsRequest) gcp.Iterator[*computepb.Firewall] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []*computepb.Firewall{}
+	for _, rule := range f.firewalls {
+		if rule.SelfLink != nil && !strings.HasPrefix(rule.GetSelfLink(), "projects/"+req.GetProject()+"/") {
+			continue
+		}
+		items = append(items, proto.Clone(rule).(*computepb.Firewall))
+	}
+	return &fakeIterator[*computepb.Firewall]{items: items}
+}
+
+func (f *fakeCompute) DeleteFirewall(ctx context.Context, req *computepb.DeleteFirewallRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	delete(f.firewalls, req.GetFirewall())
+	f.mu.Unlock()
+	return f.set("firewalls/"+req.GetFirewall(), false)
+}
+
+func (f *fakeCompute) GetResourcePolicy(ctx context.Context, req *computepb.GetResourcePolicyRequest) (*computepb.ResourcePolicy, error) {
+	return &computepb.ResourcePolicy{Name: proto.String(req.GetResourcePolicy())}, f.has("resourcePolicies/" + req.GetRegion() + "/" + req.GetResourcePolicy())
+}
+
+func (f *fakeCompute) InsertResourcePolicy(ctx context.Context, req *computepb.InsertResourcePolicyRequest) (gcp.Operation, error) {
+	return f.set("resourcePolicies/"+req.GetRegion()+"/"+req.GetResourcePolicyResource().GetName(), true)
+}
+
+func (f *fakeCompute) DeleteResourcePolicy(ctx context.Context, req *computepb.DeleteResourcePolicyRequest) (gcp.Operation, error) {
+	return f.set("resourcePolicies/"+req.GetRegion()+"/"+req.GetResourcePolicy(), false)
+}
+
+func (f *fakeCompute) ListResourcePolicies(ctx context.Context, req *computepb.ListResourcePoliciesRequest) gcp.Iterator[*computepb.ResourcePolicy] {
+	items := []*computepb.ResourcePolicy{}
+	for _, key := range f.Resources() {
+		if name, ok := strings.CutPrefix(key, "resourcePolicies/"+req.GetRegion()+"/"); ok {
+			items = append(items, &computepb.ResourcePolicy{Name: proto.String(name), CreationTimestamp: proto.String(fakeCreated)})
+		}
+	}
+	return &fakeIterator[*computepb.ResourcePolicy]{items: items}
+}
+
+func (f *fakeCompute) GetRouter(ctx context.Context, req *computepb.GetRouterRequest) (*computepb.Router, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	router := f.routers[req.GetRegion()+"/"+req.GetRouter()]
+	if router == nil {
+		return nil, &googleapi.Error{Code: 404}
+	}
+	return proto.Clone(router).(*computepb.Router), nil
+}
+
+func (f *fakeCompute) InsertRouter(ctx context.Context, req *computepb.InsertRouterRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	router := proto.Clone(req.GetRouterResource()).(*computepb.Router)
+	router.CreationTimestamp = proto.String(fakeCreated)
+	f.routers[req.GetRegion()+"/"+router.GetName()] = router
+	f.mu.Unlock()
+	return f.set("routers/"+req.GetRegion()+"/"+router.GetName(), true)
+}
+
+func (f *fakeCompute) DeleteRouter(ctx context.Context, req *computepb.DeleteRouterRequest) (gcp.Operation, error) {
+	f.mu.Lock()
+	delete(f.routers, req.GetRegion()+"/"+req.GetRouter())
+	f.mu.Unlock()
+	return f.set("routers/"+req.GetRegion()+"/"+req.GetRouter(), false)
+}
+
+func (f *fakeCompute) ListRouters(ctx context.Context, req *computepb.ListRoutersRequest) gcp.Iterator[*computepb.Router] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []*computepb.Router{}
+	for key, router := range f.routers {
+		if strings.HasPrefix(key, req.GetRegion()+"/") {
+			items = append(items, proto.Clone(router).(*computepb.Router))
+		}
+	}
+	return &fakeIterator[*computepb.Router]{items: items}
+}
+
+func fakeInterfaces() []*computepb.NetworkInterface {
+	return []*computepb.NetworkInterface{{
+		NetworkIP:     proto.String("10.0.0.2"),
+		AccessConfigs: []*computepb.AccessConfig{{NatIP: proto.String("203.0.113.10")}},
+	}}
+}
+
+type fakeIterator[T any] struct {
+	items []T
+}
+
+func (it *fakeIterator[T]) Next() (T, error) {
+	var zero T
+	if len(it.items) == 0 {
+		return zero, iterator.Done
+	}
+	item := it.items[0]
+	it.items = it.items[1:]
+	return item, nil
+}
+
+type fakeOperation struct{}
+
+func (fakeOperation) Poll(ctx context.Context, opts ...gax.CallOption) error { return nil }
+func (fakeOperation) Wait(ctx context.Context, opts ...gax.CallOption) error { return nil }
+func (fakeOperation) Done() bool                                             { return true }
+func (fakeOperation) Proto() *computepb.Operation {
+	return &computepb.Operation{Status: computepb.Operation_DONE.Enum(), Progress: proto.Int32(100)}
+}
+
+// newTestService returns a Service backed by compute and the default profile,
+// with quiet output and a readiness probe that reports ready immediately.
+func newTestService(t *testing.T, compute gcp.Compute) *Service {
+	t.Helper()
+	cfg, err := config.Load("default", filepath.Join("..", "..", "..", "profiles"))
+	if err != nil {
+		t.Fatalf("load default config: %v", err)
+	}
+	service := NewService(compute, cfg, &ui.UI{Out: io.Discard, Err: io.Discard}, zap.NewNop())
+	service.probe = func(ctx context.Context, probeURL string, onState func(string)) (string, error) {
+		return "ready", nil
+	}
+	return service
+}
+
+// fakeClusterInstance returns an instance labeled as node index of cluster.
+func fakeClusterInstance(service *Service, cluster string, index int, status string) *computepb.Instance {
+	node := service.clusterNodes(cluster, []int{index})[0]
+	return &computepb.Instance{
+		Name:   proto.String(node.Name),
+		Status: proto.String(status),
+		Labels: map[string]string{
+			"cluster":       cluster,
+			"cluster_index": strings.TrimPrefix(node.Name, cluster+"-"),
+			"cluster_role":  node.Role,
+		},
+		Tags:              &computepb.Tags{Items: service.clusterTags(cluster, node.Master())},
+		NetworkInterfaces: fakeInterfaces(),
+	}
+}
@@ -0,0 +1,178 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/parse"
+)
+
+func TestFirewallSourceRangesFollowAllowlist(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Network.SSHSourceRanges = []string{"203.0.113.0/24"}
+
+	if _, err := service.Start(context.Background(), "locked", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if got := compute.firewalls["gpunow-locked-ssh"].GetSourceRanges(); !reflect.DeepEqual(got, []string{"203.0.113.0/24"}) {
+		t.Fatalf("ssh source ranges = %v", got)
+	}
+	if got := compute.firewalls["gpunow-locked-ports"].GetSourceRanges(); !reflect.DeepEqual(got, []string{"0.0.0.0/0"}) {
+		t.Fatalf("ports source ranges = %v", got)
+	}
+
+	compute.calls = nil
+	if _, err := service.Start(context.Background(), "locked", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if len(compute.Calls()) != 0 {
+		t.Fatalf("expected unchanged firewalls to be left alone, got %v", compute.Calls())
+	}
+
+	opts := StartOptions{NumInstances: 1, SSHSourceRanges: []string{"198.51.100.4/32"}, PortSourceRanges: []string{"198.51.100.4/32"}}
+	if _, err := service.Start(context.Background(), "locked", opts); err != nil {
+		t.Fatalf("restart with override: %v", err)
+	}
+	want := []string{"patchFirewall gpunow-locked-ports", "patchFirewall gpunow-locked-ssh"}
+	if !reflect.DeepEqual(compute.Calls(), want) {
+		t.Fatalf("calls = %v, want %v", compute.Calls(), want)
+	}
+	if got := compute.firewalls["gpunow-locked-ssh"].GetSourceRanges(); !reflect.DeepEqual(got, []string{"198.51.100.4/32"}) {
+		t.Fatalf("ssh source ranges after override = %v", got)
+	}
+}
+
+func TestPortSpecsBuildPerSourceRules(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ports, err := parse.PortsCSV("22,8000-8100/tcp,60000-61000/udp,6006@10.8.0.0/16")
+	if err != nil {
+		t.Fatalf("parse ports: %v", err)
+	}
+	service.Config.Network.Ports = ports
+
+	if _, err := service.Start(context.Background(), "rich", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if got := formatAllowed(compute.firewalls["gpunow-rich-ports"].GetAllowed()); got != "tcp:22,tcp:34223,tcp:8000-8100,udp:60000-61000" {
+		t.Fatalf("ports rule allowed = %s", got)
+	}
+	sourceRule := compute.firewalls["gpunow-rich-ports-1"]
+	if got := formatAllowed(sourceRule.GetAllowed()); got != "tcp:6006" {
+		t.Fatalf("source rule allowed = %s", got)
+	}
+	if got := sourceRule.GetSourceRanges(); !reflect.DeepEqual(got, []string{"10.8.0.0/16"}) {
+		t.Fatalf("source rule ranges = %v", got)
+	}
+
+	service.Config.Network.Ports = ports[:3]
+	plan, err := service.PlanStart(context.Background(), "rich", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if action := planActions(plan)["firewalls/gpunow-rich-ports-1"]; action != PlanDelete {
+		t.Fatalf("expected plan to delete the stale source rule, got %q", action)
+	}
+	if _, err := service.Start(context.Background(), "rich", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if _, ok := compute.firewalls["gpunow-rich-ports-1"]; ok {
+		t.Fatalf("expected start to remove the stale source rule")
+	}
+
+	service.Config.Network.Ports = ports
+	if _, err := service.Start(context.Background(), "rich", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart with source rule: %v", err)
+	}
+	if err := service.Stop(context.Background(), "rich", StopOptions{Delete: true}); err != nil {
+		t.Fatalf("stop --delete: %v", err)
+	}
+	if _, ok := compute.firewalls["gpunow-rich-ports-1"]; ok {
+		t.Fatalf("expected stop --delete to remove the source rule")
+	}
+}
+
+func TestDualStackClusterAddsIPv6Rules(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Network.StackType = "IPV4_IPV6"
+
+	if _, err := service.Start(context.Background(), "dual", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, subnet := range compute.subnets {
+		if subnet.GetStackType() != "IPV4_IPV6" || subnet.GetIpv6AccessType() != "EXTERNAL" {
+			t.Fatalf("subnet %s stack type = %q", subnet.GetName(), subnet.GetStackType())
+		}
+	}
+	for _, name := range []string{"gpunow-dual-ssh-v6", "gpunow-dual-ports-v6"} {
+		if got := compute.firewalls[name].GetSourceRanges(); !reflect.DeepEqual(got, []string{"::/0"}) {
+			t.Fatalf("%s source ranges = %v", name, got)
+		}
+	}
+	internal := compute.firewalls["gpunow-dual-internal-v6"]
+	if got := internal.GetSourceRanges(); !reflect.DeepEqual(got, []string{fakeIPv6Prefix}) {
+		t.Fatalf("internal-v6 source ranges = %v", got)
+	}
+	if got := compute.firewalls["gpunow-dual-ssh"].GetSourceRanges(); !reflect.DeepEqual(got, []string{"0.0.0.0/0"}) {
+		t.Fatalf("ssh source ranges = %v", got)
+	}
+	iface := compute.get(service.Config.Project.Zone, "dual-0").GetNetworkInterfaces()[0]
+	if len(iface.GetIpv6AccessConfigs()) != 1 || iface.GetIpv6AccessConfigs()[0].GetType() != "DIRECT_IPV6" {
+		t.Fatalf("expected an external IPv6 access config, got %v", iface.GetIpv6AccessConfigs())
+	}
+
+	service.Config.Network.StackType = "IPV4_ONLY"
+	if _, err := service.Start(context.Background(), "dual", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	for _, name := range []string{"gpunow-dual-internal-v6", "gpunow-dual-ssh-v6", "gpunow-dual-ports-v6"} {
+		if _, ok := compute.firewalls[name]; ok {
+			t.Fatalf("expected %s to be removed once the cluster is IPv4-only", name)
+		}
+	}
+}
+
+func TestDualStackPatchesExistingSubnet(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	if _, err := service.Start(context.Background(), "grow", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	service.Config.Network.StackType = "IPV4_IPV6"
+	plan, err := service.PlanStart(context.Background(), "grow", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if action := planActions(plan)["subnetworks/gpunow-grow-subnet"]; action != PlanPatch {
+		t.Fatalf("expected plan to patch the subnet, got %q", action)
+	}
+	compute.calls = nil
+	if _, err := service.Start(context.Background(), "grow", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if _, ok := compute.firewalls["gpunow-grow-internal-v6"]; !ok {
+		t.Fatalf("expected internal-v6 rule after enabling IPv6, calls %v", compute.Calls())
+	}
+}
+
+func TestSplitSourceRanges(t *testing.T) {
+	rule := &computepb.Firewall{Name: proto.String("r")}
+	sibling := splitSourceRanges(rule, []string{"10.0.0.0/8", "2001:db8::/32"}, false)
+	if !reflect.DeepEqual(rule.GetSourceRanges(), []string{"10.0.0.0/8"}) || sibling == nil || sibling.GetName() != "r-v6" ||
+		!reflect.DeepEqual(sibling.GetSourceRanges(), []string{"2001:db8::/32"}) {
+		t.Fatalf("mixed ranges split into %v and %v", rule.GetSourceRanges(), sibling)
+	}
+	if sibling := splitSourceRanges(rule, []string{"0.0.0.0/0"}, false); sibling != nil {
+		t.Fatalf("expected no IPv6 rule for an IPv4-only cluster, got %v", sibling)
+	}
+	if sibling := splitSourceRanges(rule, []string{"2001:db8::/32"}, true); sibling != nil || rule.GetSourceRanges()[0] != "2001:db8::/32" {
+		t.Fatalf("expected IPv6-only ranges to stay on the rule, got %v and %v", rule.GetSourceRanges(), sibling)
+	}
+}
@@ -0,0 +1,340 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/labels"
+	"gpunow/internal/ui"
+)
+
+// Orphan kinds in the order they are deleted: instances release disks,
+// firewalls, policies, routers and subnets before the network can go.
+var orphanKinds = []string{"instances", "disks", "firewalls", "resourcePolicies", "routers", "subnetworks", "networks"}
+
+type GCOptions struct {
+	// Zones to scan for instances and disks; their regions are scanned for
+	// subnetworks, routers and resource policies.
+	Zones []string
+	// OlderThan skips resources created more recently, so runs in progress
+	// are not collected.
+	OlderThan time.Duration
+	Now       time.Time
+	// KnownCluster reports whether a cluster still exists in local state.
+	KnownCluster func(name string) bool
+	// KnownVM reports whether a standalone VM still exists in local state.
+	KnownVM func(name string) bool
+	// NetworkProjects are Shared VPC host projects whose firewall rules are
+	// scanned besides those of project.id.
+	NetworkProjects []string
+}
+
+// Orphan is a gpunow resource with no cluster or VM in local state.
+type Orphan struct {
+	Kind    string
+	Name    string
+	Zone    string
+	Region  string
+	Cluster string
+	// Project is set for firewall rules in a network project other than
+	// project.id.
+	Project   string
+	CreatedAt time.Time
+}
+
+// Location returns the zone, region or "global" the orphan lives in.
+func (o Orphan) Location() string {
+	switch {
+	case o.Zone != "":
+		return o.Zone
+	case o.Region != "":
+		return o.Region
+	default:
+		return "global"
+	}
+}
+
+// FindOrphans lists managed instances and unattached disks (by the
+// gpunow=0xfe label, skipping disks labeled gpunow_keep=true) and cluster networks, subnets, firewalls, NAT routers
+// and placement policies (by the network naming scheme), and returns those whose cluster
+// or VM is not in local state, sorted in deletion order.
+func (s *Service) FindOrphans(ctx context.Context, opts GCOptions) ([]Orphan, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	project := s.Config.Project.ID
+	orphans := []Orphan{}
+	add := func(orphan Orphan, created string) {
+		createdAt, err := time.Parse(time.RFC3339, created)
+		if err != nil || opts.Now.Sub(createdAt) < opts.OlderThan {
+			return
+		}
+		orphan.CreatedAt = createdAt
+		orphans = append(orphans, orphan)
+	}
+	orphaned := func(clusterName, vmName string) bool {
+		if clusterName != "" {
+			return opts.KnownCluster == nil || !opts.KnownCluster(clusterName)
+		}
+		return opts.KnownVM == nil || !opts.KnownVM(vmName)
+	}
+
+	regions := []string{}
+	seenRegions := map[string]bool{}
+	for _, zone := range opts.Zones {
+		region, err := gcp.RegionFromZone(zone)
+		if err != nil {
+			return nil, err
+		}
+		if !seenRegions[region] {
+			seenRegions[region] = true
+			regions = append(regions, region)
+		}
+
+		instances, err := gcp.Collect(s.Compute.ListInstances(ctx, &computepb.ListInstancesRequest{
+			Project: project,
+			Zone:    zone,
+			Filter:  proto.String(labels.Filter()),
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("list instances in %s: %w", zone, err)
+		}
+		for _, inst := range instances {
+			clusterName := inst.GetLabels()["cluster"]
+			if orphaned(clusterName, inst.GetName()) {
+				add(Orphan{Kind: "instances", Name: inst.GetName(), Zone: zone, Cluster: clusterName}, inst.GetCreationTimestamp())
+			}
+		}
+
+		disks, err := gcp.Collect(s.Compute.ListDisks(ctx, &computepb.ListDisksRequest{
+			Project: project,
+			Zone:    zone,
+			Filter:  proto.String(labels.Filter()),
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("list disks in %s: %w", zone, err)
+		}
+		for _, disk := range disks {
+			// Kept disk.extra disks outlive their node on purpose.
+			if len(disk.GetUsers()) > 0 || disk.GetLabels()[labels.KeepKey] == labels.KeepValue {
+				continue
+			}
+			clusterName := disk.GetLabels()["cluster"]
+			if orphaned(clusterName, disk.GetName()) {
+				add(Orphan{Kind: "disks", Name: disk.GetName(), Zone: zone, Cluster: clusterName}, disk.GetCreationTimestamp())
+			}
+		}
+	}
+
+	networks, err := gcp.Collect(s.Compute.ListNetworks(ctx, &computepb.ListNetworksRequest{Project: project}))
+	if err != nil {
+		return nil, fmt.Errorf("list networks: %w", err)
+	}
+	for _, network := range networks {
+		if clusterName := s.networkCluster(network.GetName()); clusterName != "" && orphaned(clusterName, "") {
+			add(Orphan{Kind: "networks", Name: network.GetName(), Cluster: clusterName}, network.GetCreationTimestamp())
+		}
+	}
+
+	firewallProjects := []string{project}
+	for _, networkProject := range opts.NetworkProjects {
+		if networkProject != "" && !slices.Contains(firewallProjects, networkProject) {
+			firewallProjects = append(firewallProjects, networkProject)
+		}
+	}
+	for _, firewallProject := range firewallProjects {
+		firewalls, err := gcp.Collect(s.Compute.ListFirewalls(ctx, &computepb.ListFirewallsRequest{Project: firewallProject}))
+		if err != nil {
+			return nil, fmt.Errorf("list firewalls in %s: %w", firewallProject, err)
+		}
+		for _, rule := range firewalls {
+			// Rules on an existing network are only recognizable by name.
+			clusterName := s.networkCluster(path.Base(rule.GetNetwork()))
+			if clusterName == "" {
+				clusterName = s.ruleCluster(rule.GetName())
+			}
+			if clusterName == "" || !orphaned(clusterName, "") {
+				continue
+			}
+			orphan := Orphan{Kind: "firewalls", Name: rule.GetName(), Cluster: clusterName}
+			if firewallProject != project {
+				orphan.Project = firewallProject
+			}
+			add(orphan, rule.GetCreationTimestamp())
+		}
+	}
+
+	for _, region := range regions {
+		subnets, err := gcp.Collect(s.Compute.ListSubnetworks(ctx, &computepb.ListSubnetworksRequest{Project: project, Region: region}))
+		if err != nil {
+			return nil, fmt.Errorf("list subnetworks in %s: %w", region, err)
+		}
+		for _, subnet := range subnets {
+			if clusterName := s.networkCluster(path.Base(subnet.GetNetwork())); clusterName != "" && orphaned(clusterName, "") {
+				add(Orphan{Kind: "subnetworks", Name: subnet.GetName(), Region: region, Cluster: clusterName}, subnet.GetCreationTimestamp())
+			}
+		}
+
+		policies, err := gcp.Collect(s.Compute.ListResourcePolicies(ctx, &computepb.ListResourcePoliciesRequest{Project: project, Region: region}))
+		if err != nil {
+			return nil, fmt.Errorf("list resource policies in %s: %w", region, err)
+		}
+		for _, policy := range policies {
+			networkName, ok := strings.CutSuffix(policy.GetName(), "-placement")
+			if !ok {
+				continue
+			}
+			if clusterName := s.networkCluster(networkName); clusterName != "" && orphaned(clusterName, "") {
+				add(Orphan{Kind: "resourcePolicies", Name: policy.GetName(), Region: region, Cluster: clusterName}, policy.GetCreationTimestamp())
+			}
+		}
+
+		routers, err := gcp.Collect(s.Compute.ListRouters(ctx, &computepb.ListRoutersRequest{Project: project, Region: region}))
+		if err != nil {
+			return nil, fmt.Errorf("list routers in %s: %w", region, err)
+		}
+		for _, router := range routers {
+			if clusterName := s.networkCluster(path.Base(router.GetNetwork())); clusterName != "" && orphaned(clusterName, "") {
+				add(Orphan{Kind: "routers", Name: router.GetName(), Region: region, Cluster: clusterName}, router.GetCreationTimestamp())
+			}
+		}
+	}
+
+	rank := map[string]int{}
+	for i, kind := range orphanKinds {
+		rank[kind] = i
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		if rank[orphans[i].Kind] != rank[orphans[j].Kind] {
+			return rank[orphans[i].Kind] < rank[orphans[j].Kind]
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans, nil
+}
+
+// networkCluster returns the cluster a network name belongs to under the
+// <network_name_prefix>-<cluster> scheme, or "" when it does not match.
+func (s *Service) networkCluster(networkName string) string {
+	clusterName, ok := strings.CutPrefix(networkName, s.Config.Cluster.NetworkNamePrefix+"-")
+	if !ok {
+		return ""
+	}
+	return clusterName
+}
+
+// ruleCluster returns the cluster a firewall rule belongs to under the
+// <network_name_prefix>-<cluster>-{internal,ssh,ports,ports-<n>}[-v6] naming
+// scheme, or "" when it does not match.
+func (s *Service) ruleCluster(ruleName string) string {
+	base, _ := strings.CutSuffix(ruleName, "-v6")
+	if i := strings.LastIndex(base, "-ports-"); i >= 0 {
+		if _, err := strconv.Atoi(base[i+len("-ports-"):]); err == nil {
+			base = base[:i+len("-ports")]
+		}
+	}
+	for _, suffix := range []string{"-internal", "-ssh", "-ports"} {
+		if networkName, ok := strings.CutSuffix(base, suffix); ok {
+			return s.networkCluster(networkName)
+		}
+	}
+	return ""
+}
+
+// DeleteOrphans deletes orphans kind by kind in deletion order; orphans of
+// the same kind are deleted in parallel. Resources already gone are skipped.
+func (s *Service) DeleteOrphans(ctx context.Context, orphans []Orphan) error {
+	if len(orphans) == 0 {
+		return nil
+	}
+	split := s.UI.StartLiveSplit()
+	if split != nil {
+		defer split.Stop()
+	}
+	taskNames := make([]string, 0, len(orphans))
+	for _, orphan := range orphans {
+		taskNames = append(taskNames, fmt.Sprintf("%s/%s", orphan.Kind, orphan.Name))
+	}
+	progress := s.UI.TaskList("Deleting", taskNames)
+	defer progress.Stop()
+
+	for _, kind := range orphanKinds {
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i, orphan := range orphans {
+			if orphan.Kind != kind {
+				continue
+			}
+			i, orphan := i, orphan
+			group.Go(func() error {
+				if err := s.deleteOrphan(groupCtx, orphan, progress, i); err != nil {
+					return fmt.Errorf("delete %s/%s: %w", orphan.Kind, orphan.Name, err)
+				}
+				progress.MarkDone(i, fmt.Sprintf("Deleted %s/%s", orphan.Kind, orphan.Name))
+				return nil
+			})
+		}
+		if err := group.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) deleteOrphan(ctx context.Context, orphan Orphan, progress *ui.TaskList, index int) error {
+	project := s.Config.Project.ID
+	switch orphan.Kind {
+	case "instances":
+		zoneService := s.inZone(orphan.Zone)
+		inst, err := zoneService.getInstance(ctx, orphan.Name)
+		if err != nil || inst == nil {
+			return err
+		}
+		return zoneService.deleteNode(ctx, inst, true, nil, progress, index)
+	case "disks":
+		call := s.api("compute.disks.delete", gcp.ZoneResource(project, orphan.Zone, "disks", orphan.Name), fmt.Sprintf("Deleting disk %s", orphan.Name))
+		op, err := s.Compute.DeleteDisk(ctx, &computepb.DeleteDiskRequest{Project: project, Zone: orphan.Zone, Disk: orphan.Name})
+		if err != nil {
+			call.Stop()
+			if gcp.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		return s.wait(ctx, call, op)
+	case "firewalls":
+		if orphan.Project != "" {
+			project = orphan.Project
+		}
+		call := s.api("compute.firewalls.delete", gcp.GlobalResource(project, "firewalls", orphan.Name), fmt.Sprintf("Deleting firewall %s", orphan.Name))
+		op, err := s.Compute.DeleteFirewall(ctx, &computepb.DeleteFirewallRequest{Project: project, Firewall: orphan.Name})
+		if err != nil {
+			call.Stop()
+			if gcp.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		return s.wait(ctx, call, op)
+	case "resourcePolicies":
+		return s.deletePlacementPolicy(ctx, project, orphan.Region, orphan.Name)
+	case "routers":
+		return s.deleteRouter(ctx, project, orphan.Region, orphan.Name)
+	case "subnetworks":
+		return s.deleteSubnetwork(ctx, project, orphan.Region, orphan.Name)
+	case "networks":
+		return s.deleteNetwork(ctx, project, orphan.Name)
+	default:
+		return fmt.Errorf("unknown resource kind %s", orphan.Kind)
+	}
+}
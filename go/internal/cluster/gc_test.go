@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/labels"
+)
+
+func TestFindAndDeleteOrphans(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+	zone := service.Config.Project.Zone
+	for _, name := range []string{"kept", "lost"} {
+		if _, err := service.Start(ctx, name, StartOptions{NumInstances: 2}); err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+	}
+	compute.disks[zone+"/lost-2"] = &computepb.Disk{
+		Name:              proto.String("lost-2"),
+		Labels:            labels.EnsureManaged(map[string]string{"cluster": "lost"}),
+		CreationTimestamp: proto.String(fakeCreated),
+	}
+
+	// A keep_on_delete data disk outlives its node on purpose.
+	compute.disks[zone+"/lost-0-data"] = &computepb.Disk{
+		Name:              proto.String("lost-0-data"),
+		Labels:            labels.EnsureManaged(map[string]string{"cluster": "lost", labels.KeepKey: labels.KeepValue}),
+		CreationTimestamp: proto.String(fakeCreated),
+	}
+
+	opts := GCOptions{
+		Zones:        []string{zone},
+		OlderThan:    time.Hour,
+		Now:          time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		KnownCluster: func(name string) bool { return name == "kept" },
+	}
+	orphans, err := service.FindOrphans(ctx, opts)
+	if err != nil {
+		t.Fatalf("find orphans: %v", err)
+	}
+	got := []string{}
+	for _, orphan := range orphans {
+		if orphan.Cluster != "lost" {
+			t.Fatalf("unexpected orphan %+v", orphan)
+		}
+		got = append(got, orphan.Kind+"/"+orphan.Name)
+	}
+	want := []string{
+		"instances/lost-0",
+		"instances/lost-1",
+		"disks/lost-2",
+		"firewalls/gpunow-lost-internal",
+		"firewalls/gpunow-lost-ports",
+		"firewalls/gpunow-lost-ssh",
+		"subnetworks/gpunow-lost-subnet",
+		"networks/gpunow-lost",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("orphans = %v, want %v", got, want)
+	}
+
+	opts.OlderThan = 48 * time.Hour
+	if recent, err := service.FindOrphans(ctx, opts); err != nil || len(recent) != 0 {
+		t.Fatalf("expected age threshold to skip everything, got %v (%v)", recent, err)
+	}
+
+	if err := service.DeleteOrphans(ctx, orphans); err != nil {
+		t.Fatalf("delete orphans: %v", err)
+	}
+	opts.OlderThan = 0
+	if remaining, err := service.FindOrphans(ctx, opts); err != nil || len(remaining) != 0 {
+		t.Fatalf("expected no orphans after delete, got %v (%v)", remaining, err)
+	}
+	if compute.Status(zone, "kept-0") != "RUNNING" || compute.has("networks/gpunow-kept") != nil {
+		t.Fatalf("known cluster must be left alone: %v", compute.Resources())
+	}
+}
+
+func TestFindOrphansOnSharedNetwork(t *testing.T) {
+	compute := newFakeCompute()
+	service := existingNetworkService(t, compute)
+	service.Config.Cluster.NetworkProject = "host-project"
+	ctx := context.Background()
+	if _, err := service.Start(ctx, "tenant", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	opts := GCOptions{
+		Zones:           []string{service.Config.Project.Zone},
+		NetworkProjects: []string{"host-project"},
+		Now:             time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	orphans, err := service.FindOrphans(ctx, opts)
+	if err != nil {
+		t.Fatalf("find orphans: %v", err)
+	}
+	rules := []string{}
+	for _, orphan := range orphans {
+		if orphan.Kind == "firewalls" {
+			if orphan.Cluster != "tenant" || orphan.Project != "host-project" {
+				t.Fatalf("unexpected firewall orphan %+v", orphan)
+			}
+			rules = append(rules, orphan.Name)
+		}
+	}
+	want := []string{"gpunow-tenant-internal", "gpunow-tenant-ports", "gpunow-tenant-ssh"}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("firewall orphans = %v, want %v", rules, want)
+	}
+	if err := service.DeleteOrphans(ctx, orphans); err != nil {
+		t.Fatalf("delete orphans: %v", err)
+	}
+	if len(compute.firewalls) != 0 {
+		t.Fatalf("expected the rules on the shared network to be deleted, got %d", len(compute.firewalls))
+	}
+}
+
+func TestRuleCluster(t *testing.T) {
+	service := newTestService(t, newFakeCompute())
+	tests := map[string]string{
+		"gpunow-train-internal":   "train",
+		"gpunow-train-ssh-v6":     "train",
+		"gpunow-a-b-ports":        "a-b",
+		"gpunow-train-ports-2-v6": "train",
+		"gpunow-train-web":        "",
+		"other-train-ssh":         "",
+	}
+	for name, want := range tests {
+		if got := service.ruleCluster(name); got != want {
+			t.Errorf("ruleCluster(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
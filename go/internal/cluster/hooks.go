@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// runPreStopHooks runs hooks.pre_stop over SSH on every running node of
+// instances in parallel, each bounded by hooks.pre_stop_timeout_seconds (0
+// means the default, 600).
+// Private workers are reached through masterIP. A failure on any node fails
+// the stop unless opts.Force is set.
+func (s *Service) runPreStopHooks(ctx context.Context, instances []*computepb.Instance, masterIP string, opts StopOptions) error {
+	commands := s.Config.Hooks.PreStop
+	if len(commands) == 0 {
+		return nil
+	}
+	running := []*computepb.Instance{}
+	for _, inst := range instances {
+		if inst.GetStatus() == "RUNNING" {
+			running = append(running, inst)
+		}
+	}
+	if len(running) == 0 {
+		return nil
+	}
+	if opts.SSHUser == "" {
+		return s.preStopFailed(opts, fmt.Errorf("ssh.default_user is required to run hooks.pre_stop"))
+	}
+
+	taskNames := make([]string, len(running))
+	for i, inst := range running {
+		taskNames[i] = fmt.Sprintf("pre_stop %s", inst.GetName())
+	}
+	progress := s.UI.TaskList("Running pre-stop hooks", taskNames)
+	defer progress.Stop()
+
+	if err := s.ensureSSHKeys(ctx, running, opts.SSHUser, opts.SSHPublicKey); err != nil {
+		return s.preStopFailed(opts, err)
+	}
+
+	// Every hook runs to the end; their failures are reported together.
+	timeout := time.Duration(s.Config.Hooks.PreStopTimeoutSeconds) * time.Second
+	errs := make([]error, len(running))
+	var group errgroup.Group
+	for i, inst := range running {
+		name := inst.GetName()
+		group.Go(func() error {
+			output, err := s.runPreStopHook(ctx, inst, masterIP, opts, timeout)
+			if err != nil {
+				progress.MarkWarning(i, fmt.Sprintf("pre_stop failed on %s: %v", name, err))
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return nil
+			}
+			message := fmt.Sprintf("Ran pre_stop on %s", name)
+			if line := lastLine(output); line != "" {
+				message = fmt.Sprintf("%s: %s", message, line)
+			}
+			progress.MarkDone(i, message)
+			return nil
+		})
+	}
+	_ = group.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return s.preStopFailed(opts, err)
+	}
+	return nil
+}
+
+func (s *Service) runPreStopHook(ctx context.Context, inst *computepb.Instance, masterIP string, opts StopOptions, timeout time.Duration) (string, error) {
+	target, jumpHost, err := guestSSHTarget(inst, masterIP, opts.SSHUser, opts.SSHIdentityFile)
+	if err != nil {
+		return "", err
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := s.runGuestSSH(hookCtx, guestSSHArgs(target, jumpHost, s.Config.Hooks.PreStop))
+	s.Logger.Debug("pre_stop hook finished", zap.String("instance", inst.GetName()), zap.String("output", output), zap.Error(err))
+	if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("timed out after %s", timeout)
+	}
+	return output, err
+}
+
+// preStopFailed returns err, or warns and returns nil when opts.Force is set.
+func (s *Service) preStopFailed(opts StopOptions, err error) error {
+	if !opts.Force {
+		return fmt.Errorf("pre_stop hooks failed (rerun with --force to stop anyway): %w", err)
+	}
+	s.UI.Warnf("Ignoring failed pre_stop hooks because of --force: %v", err)
+	return nil
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/gcp"
+)
+
+func TestPreStopHooksGateStop(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Cluster.PrivateWorkers = true
+	service.Config.Hooks.PreStop = []string{"gsutil -m rsync -r /ckpt gs://bucket/ckpt"}
+	var mu sync.Mutex
+	commands := []string{}
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		command := strings.Join(args, " ")
+		commands = append(commands, command)
+		if strings.Contains(command, "-J ") {
+			return "", fmt.Errorf("exit status 1")
+		}
+		return "synced\n", nil
+	}
+	if _, err := service.Start(context.Background(), "hooks", StartOptions{NumInstances: 2, SSHUser: "mo"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	zone := service.Config.Project.Zone
+
+	if err := service.Stop(context.Background(), "hooks", StopOptions{Delete: true}); err == nil || !strings.Contains(err.Error(), "ssh.default_user") {
+		t.Fatalf("expected missing ssh user error, got %v", err)
+	}
+	opts := StopOptions{Delete: true, SSHUser: "mo"}
+	if err := service.Stop(context.Background(), "hooks", opts); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected failing hook to abort the stop, got %v", err)
+	}
+	if compute.get(zone, "hooks-0") == nil || compute.get(zone, "hooks-1") == nil {
+		t.Fatalf("expected nodes to survive a failed pre_stop hook")
+	}
+	if len(commands) != 2 || !strings.HasSuffix(commands[0], "gsutil -m rsync -r /ckpt gs://bucket/ckpt") {
+		t.Fatalf("expected the hook to run on both nodes, got %q", commands)
+	}
+
+	opts.Force = true
+	if err := service.Stop(context.Background(), "hooks", opts); err != nil {
+		t.Fatalf("stop --force: %v", err)
+	}
+	if compute.get(zone, "hooks-0") != nil || compute.get(zone, "hooks-1") != nil {
+		t.Fatalf("expected --force to delete the nodes")
+	}
+}
+
+func TestPreStopHooksSkipStoppedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Hooks.PreStop = []string{"sync"}
+	calls := 0
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		calls++
+		return "", nil
+	}
+	if _, err := service.Start(context.Background(), "idle", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := service.Stop(context.Background(), "idle", StopOptions{SSHUser: "mo"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := service.Stop(context.Background(), "idle", StopOptions{Delete: true}); err != nil {
+		t.Fatalf("delete stopped cluster: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the hook to run once on the running node, got %d", calls)
+	}
+}
+
+// metadataFailure refuses every metadata write, as for a node whose SSH key
+// cannot be added.
+type metadataFailure struct {
+	*fakeCompute
+}
+
+func (f metadataFailure) SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (gcp.Operation, error) {
+	return nil, fmt.Errorf("permission denied")
+}
+
+func TestPreStopHooksForceSkipsSSHKeyFailure(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Hooks.PreStop = []string{"sync"}
+	ran := false
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		ran = true
+		return "", nil
+	}
+	if _, err := service.Start(context.Background(), "keys", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	service.Compute = metadataFailure{compute}
+
+	opts := StopOptions{SSHUser: "mo", SSHPublicKey: "ssh-ed25519 AAAA mo"}
+	if err := service.Stop(context.Background(), "keys", opts); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected the key failure to abort the stop, got %v", err)
+	}
+	opts.Force = true
+	if err := service.Stop(context.Background(), "keys", opts); err != nil {
+		t.Fatalf("stop --force: %v", err)
+	}
+	if ran {
+		t.Fatalf("expected no hooks to run without the SSH key")
+	}
+	if got := compute.Status(service.Config.Project.Zone, "keys-0"); got != "TERMINATED" {
+		t.Fatalf("status = %s", got)
+	}
+}
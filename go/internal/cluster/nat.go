@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+)
+
+// routerName returns the Cloud Router name for a cluster network, or "" when
+// cluster.private_workers is not set. Existing networks are expected to
+// provide their own NAT.
+func (s *Service) routerName(networkName string) string {
+	if !s.Config.Cluster.PrivateWorkers || s.Config.Cluster.NetworkMode == "existing" {
+		return ""
+	}
+	return fmt.Sprintf("%s-router", networkName)
+}
+
+// ensureRouter creates the regional Cloud Router with a NAT that gives
+// private workers egress. Existing routers are left unchanged.
+func (s *Service) ensureRouter(ctx context.Context, network clusterNetwork) error {
+	project := network.NetworkProject
+	region := network.Region
+	name := network.RouterName
+
+	getCall := s.api("compute.routers.get", gcp.RegionResource(project, region, "routers", name), "")
+	_, err := s.Compute.GetRouter(ctx, &computepb.GetRouterRequest{
+		Project: project,
+		Region:  region,
+		Router:  name,
+	})
+	getCall.Stop()
+	if err == nil {
+		return nil
+	}
+	if !gcp.IsNotFound(err) {
+		return err
+	}
+
+	call := s.api("compute.routers.insert", gcp.RegionResource(project, region, "routers", name), fmt.Sprintf("Creating router %s", name))
+	op, err := s.Compute.InsertRouter(ctx, &computepb.InsertRouterRequest{
+		Project: project,
+		Region:  region,
+		RouterResource: &computepb.Router{
+			Name:    proto.String(name),
+			Network: proto.String(network.NetworkURL),
+			Nats: []*computepb.RouterNat{{
+				Name:                          proto.String(network.NATName),
+				NatIpAllocateOption:           proto.String("AUTO_ONLY"),
+				SourceSubnetworkIpRangesToNat: proto.String("ALL_SUBNETWORKS_ALL_IP_RANGES"),
+			}},
+		},
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+// deleteRouter deletes a cluster router and its NAT. A router that is already
+// gone is not an error.
+func (s *Service) deleteRouter(ctx context.Context, project, region, name string) error {
+	call := s.api("compute.routers.delete", gcp.RegionResource(project, region, "routers", name), fmt.Sprintf("Deleting router %s", name))
+	op, err := s.Compute.DeleteRouter(ctx, &computepb.DeleteRouterRequest{
+		Project: project,
+		Region:  region,
+		Router:  name,
+	})
+	if err != nil {
+		call.Stop()
+		if gcp.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrivateWorkersProbeThroughMaster(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Cluster.PrivateWorkers = true
+	zone := service.Config.Project.Zone
+	var mu sync.Mutex
+	probed := map[string]int{}
+	service.probe = func(ctx context.Context, probeURL string, onState func(string)) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		probed[probeURL]++
+		return "ready", nil
+	}
+
+	if _, err := service.Start(context.Background(), "quiet", StartOptions{NumInstances: 3, SSHUser: "mo"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	network, err := service.resolveClusterNetwork("quiet")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	router := compute.routers[network.Region+"/gpunow-quiet-router"]
+	if router == nil || len(router.GetNats()) != 1 || router.GetNats()[0].GetName() != "gpunow-quiet-nat" {
+		t.Fatalf("expected router with NAT, got %v", router)
+	}
+	if external, _ := instanceIPs(compute.get(zone, "quiet-0")); external == "" {
+		t.Fatalf("expected master to keep its public IP")
+	}
+	for _, name := range []string{"quiet-1", "quiet-2"} {
+		if external, _ := instanceIPs(compute.get(zone, name)); external != "" {
+			t.Fatalf("expected %s to have no public IP, got %s", name, external)
+		}
+	}
+	if probed[readinessURL("203.0.113.10")] != 1 || probed[readinessURL("10.0.0.2")] != 2 {
+		t.Fatalf("expected master probed directly and workers at their internal IP, got %v", probed)
+	}
+
+	if err := service.Stop(context.Background(), "quiet", StopOptions{Delete: true}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if len(compute.routers) != 0 {
+		t.Fatalf("expected router to be deleted, got %v", compute.Resources())
+	}
+}
+
+func TestPrivateWorkersRequireSSHUser(t *testing.T) {
+	service := newTestService(t, newFakeCompute())
+	service.Config.Cluster.PrivateWorkers = true
+
+	_, err := service.Start(context.Background(), "quiet", StartOptions{NumInstances: 2})
+	if err == nil || !strings.Contains(err.Error(), "ssh.default_user") {
+		t.Fatalf("expected missing ssh user error, got %v", err)
+	}
+}
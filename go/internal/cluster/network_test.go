@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+)
+
+func existingNetworkService(t *testing.T, compute *fakeCompute) *Service {
+	t.Helper()
+	service := newTestService(t, compute)
+	service.Config.Cluster.NetworkMode = "existing"
+	service.Config.Cluster.Network = "shared"
+	service.Config.Cluster.Subnetwork = "shared-gpu"
+	region, err := gcp.RegionFromZone(service.Config.Project.Zone)
+	if err != nil {
+		t.Fatalf("region: %v", err)
+	}
+	compute.set("networks/shared", true)
+	if _, err := compute.InsertSubnetwork(context.Background(), &computepb.InsertSubnetworkRequest{
+		Region:             region,
+		SubnetworkResource: &computepb.Subnetwork{Name: proto.String("shared-gpu"), IpCidrRange: proto.String("10.20.0.0/20")},
+	}); err != nil {
+		t.Fatalf("seed subnet: %v", err)
+	}
+	return service
+}
+
+func TestExistingNetworkModeKeepsSharedNetwork(t *testing.T) {
+	compute := newFakeCompute()
+	service := existingNetworkService(t, compute)
+	before := compute.Resources()
+
+	plan, err := service.PlanStart(context.Background(), "shared", StartOptions{NumInstances: 2})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	actions := planActions(plan)
+	if actions["networks/shared"] != PlanUnchanged || actions["subnetworks/shared-gpu"] != PlanUnchanged {
+		t.Fatalf("expected the shared network to be left alone, got %v", actions)
+	}
+
+	if _, err := service.Start(context.Background(), "shared", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, resource := range compute.Resources() {
+		if strings.HasPrefix(resource, "networks/") && resource != "networks/shared" {
+			t.Fatalf("unexpected network %s", resource)
+		}
+		if strings.HasPrefix(resource, "subnetworks/") && !strings.HasSuffix(resource, "/shared-gpu") {
+			t.Fatalf("unexpected subnetwork %s", resource)
+		}
+	}
+	internal := compute.firewalls["gpunow-shared-internal"]
+	if internal == nil {
+		t.Fatalf("expected the internal rule to be named after the cluster")
+	}
+	if !strings.HasSuffix(internal.GetNetwork(), "/networks/shared") {
+		t.Fatalf("internal rule network = %s", internal.GetNetwork())
+	}
+	if len(internal.GetSourceRanges()) != 0 || !reflect.DeepEqual(internal.GetSourceTags(), internal.GetTargetTags()) {
+		t.Fatalf("expected the internal rule to match cluster tags, got ranges %v tags %v", internal.GetSourceRanges(), internal.GetSourceTags())
+	}
+
+	if err := service.Stop(context.Background(), "shared", StopOptions{Delete: true}); err != nil {
+		t.Fatalf("stop --delete: %v", err)
+	}
+	if !reflect.DeepEqual(compute.Resources(), before) {
+		t.Fatalf("resources after delete = %v, want %v", compute.Resources(), before)
+	}
+	if len(compute.firewalls) != 0 {
+		t.Fatalf("expected cluster firewalls to be deleted, got %d", len(compute.firewalls))
+	}
+}
+
+func TestExistingNetworkModeRequiresSubnet(t *testing.T) {
+	compute := newFakeCompute()
+	service := existingNetworkService(t, compute)
+	service.Config.Cluster.Subnetwork = "missing"
+
+	_, err := service.Start(context.Background(), "shared", StartOptions{NumInstances: 1})
+	if err == nil || !strings.Contains(err.Error(), "cluster.subnetwork missing not found") {
+		t.Fatalf("expected missing subnetwork error, got %v", err)
+	}
+	if _, err := service.PlanStart(context.Background(), "shared", StartOptions{NumInstances: 1}); err == nil {
+		t.Fatalf("expected plan to fail on a missing subnetwork")
+	}
+}
+
+func TestExistingNetworkModeKeepsRulesOfOtherNetworks(t *testing.T) {
+	compute := newFakeCompute()
+	service := existingNetworkService(t, compute)
+	// Same name scheme, different network in the same project.
+	compute.firewalls["gpunow-shared-ports-1"] = &computepb.Firewall{
+		Name:    proto.String("gpunow-shared-ports-1"),
+		Network: proto.String(gcp.GlobalResource(service.Config.Project.ID, "networks", "legacy")),
+	}
+
+	if _, err := service.Start(context.Background(), "shared", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if compute.firewalls["gpunow-shared-ports-1"] == nil {
+		t.Fatalf("a rule on another network must not be treated as stale")
+	}
+}
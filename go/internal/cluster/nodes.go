@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/lifecycle"
+	"gpunow/internal/ui"
+	"gpunow/internal/validate"
+)
+
+// StartNodes starts, creates or resumes the named nodes of the cluster and
+// waits for their readiness, leaving every other node alone. names must be
+// part of the layout in opts.
+func (s *Service) StartNodes(ctx context.Context, clusterName string, names []string, opts StartOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no nodes selected")
+	}
+	layout, err := s.layoutNodes(clusterName, opts)
+	if err != nil {
+		return err
+	}
+	byName := map[string]clusterNode{}
+	for _, node := range layout {
+		byName[node.Name] = node
+	}
+	nodes := []clusterNode{}
+	for _, name := range names {
+		node, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%s is not a node of cluster %s (%d instances)", name, clusterName, len(layout))
+		}
+		nodes = append(nodes, node)
+	}
+	if s.Config.Cluster.PrivateWorkers && len(layout) > 1 && strings.TrimSpace(opts.SSHUser) == "" {
+		return fmt.Errorf("cluster.private_workers probes workers through the master over SSH; set ssh.default_user")
+	}
+
+	split := s.UI.StartLiveSplit()
+	if split != nil {
+		defer split.Stop()
+	}
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts)
+	if err != nil {
+		return err
+	}
+	cloudInits, err := s.renderCloudInits(nodes, opts)
+	if err != nil {
+		return err
+	}
+	taskNames := network.taskNames()
+	resourceTaskCount := len(taskNames)
+	for _, node := range nodes {
+		taskNames = append(taskNames, fmt.Sprintf("instance %s", node.Name))
+	}
+	progress := s.UI.TaskList("Starting", taskNames)
+	defer progress.Stop()
+
+	if err := s.ensureClusterNetwork(ctx, clusterName, network, opts, progress); err != nil {
+		return err
+	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	readiness := s.newClusterReadiness(layout[0].Name, opts, progress)
+	for i, node := range nodes {
+		progressIndex := resourceTaskCount + i
+		group.Go(func() error {
+			return s.startNode(groupCtx, node, network, cloudInits[node.Pool], opts, progress, progressIndex, readiness)
+		})
+	}
+	return group.Wait()
+}
+
+// Reset hard-resets the named running nodes, or every node when names is
+// empty, and waits for their sentinels again. Like a power cycle, memory
+// and running processes are lost; disks are kept. Nodes that are not
+// running are skipped.
+func (s *Service) Reset(ctx context.Context, clusterName string, names []string, opts StartOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances found for cluster %s", clusterName)
+	}
+	masterName := ""
+	for _, inst := range instances {
+		if isMaster(inst) {
+			masterName = inst.GetName()
+		}
+	}
+	if len(names) > 0 {
+		if instances, err = selectInstances(clusterName, instances, names); err != nil {
+			return err
+		}
+	}
+
+	progress := s.UI.TaskList("Resetting", instanceNames(instances))
+	defer progress.Stop()
+	readiness := s.newClusterReadiness(masterName, opts, progress)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for idx, inst := range instances {
+		group.Go(func() error {
+			return s.resetNode(groupCtx, inst, opts, progress, idx, readiness)
+		})
+	}
+	return group.Wait()
+}
+
+func (s *Service) resetNode(ctx context.Context, inst *computepb.Instance, opts StartOptions, progress *ui.TaskList, progressIndex int, readiness *clusterReadiness) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	name := inst.GetName()
+	if inst.GetStatus() != "RUNNING" {
+		progress.MarkWarning(progressIndex, fmt.Sprintf("Skipped %s (%s); use gpunow start", name, inst.GetStatus()))
+		return nil
+	}
+
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateStarting, "", "")
+	call := s.api("compute.instances.reset", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Resetting %s", name))
+	op, err := s.Compute.ResetInstance(ctx, &computepb.ResetInstanceRequest{
+		Project:  project,
+		Zone:     zone,
+		Instance: name,
+	})
+	if err != nil {
+		call.Stop()
+		return fmt.Errorf("reset %s: %w", name, err)
+	}
+	if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+		return err
+	}
+	refreshed, err := s.getInstance(ctx, name)
+	if err != nil {
+		return err
+	}
+	externalIP, internalIP := instanceIPs(refreshed)
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
+	if err := readiness.Wait(ctx, name, externalIP, internalIP, progressIndex); err != nil {
+		return err
+	}
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+	progress.MarkDone(progressIndex, fmt.Sprintf("Reset %s", name))
+	return nil
+}
+
+// selectInstances returns the instances named in names, in that order. A
+// name without a matching instance is an error.
+func selectInstances(clusterName string, instances []*computepb.Instance, names []string) ([]*computepb.Instance, error) {
+	byName := instancesByName(instances)
+	selected := make([]*computepb.Instance, 0, len(names))
+	for _, name := range names {
+		inst := byName[name]
+		if inst == nil {
+			return nil, fmt.Errorf("instance %s not found in cluster %s", name, clusterName)
+		}
+		selected = append(selected, inst)
+	}
+	return selected, nil
+}
+
+func isMaster(inst *computepb.Instance) bool {
+	return inst.GetLabels()["cluster_role"] == "master"
+}
+
+// clusterMasterIP returns the external IP of the running master, or "" when
+// the master is not running.
+func clusterMasterIP(instances []*computepb.Instance) string {
+	for _, inst := range instances {
+		if isMaster(inst) && inst.GetStatus() == "RUNNING" {
+			externalIP, _ := instanceIPs(inst)
+			return externalIP
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gpunow/internal/lifecycle"
+)
+
+func TestStopSelectedNodesKeepsClusterResources(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "part", StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	resources := compute.Resources()
+	firewalls := len(compute.firewalls)
+
+	if err := service.Stop(context.Background(), "part", StopOptions{Nodes: []string{"part-1"}}); err != nil {
+		t.Fatalf("stop part-1: %v", err)
+	}
+	if got := compute.Status(zone, "part-1"); got != "TERMINATED" {
+		t.Fatalf("part-1 status = %s", got)
+	}
+	if got := compute.Status(zone, "part-2"); got != "RUNNING" {
+		t.Fatalf("expected part-2 to keep running, got %s", got)
+	}
+
+	if err := service.Stop(context.Background(), "part", StopOptions{Nodes: []string{"part-2"}, Delete: true}); err != nil {
+		t.Fatalf("delete part-2: %v", err)
+	}
+	if compute.get(zone, "part-2") != nil {
+		t.Fatalf("expected part-2 to be deleted")
+	}
+	if !reflect.DeepEqual(compute.Resources(), resources) || len(compute.firewalls) != firewalls {
+		t.Fatalf("expected a partial delete to keep the cluster network, got %v", compute.Resources())
+	}
+
+	err := service.Stop(context.Background(), "part", StopOptions{Nodes: []string{"part-0"}, Delete: true})
+	if err == nil || !strings.Contains(err.Error(), "master") {
+		t.Fatalf("expected deleting the master alone to fail, got %v", err)
+	}
+	err = service.Stop(context.Background(), "part", StopOptions{Nodes: []string{"part-7"}})
+	if err == nil || !strings.Contains(err.Error(), "part-7") {
+		t.Fatalf("expected unknown node error, got %v", err)
+	}
+}
+
+func TestStartNodesTouchesOnlySelectedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "some", StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := service.Stop(context.Background(), "some", StopOptions{Nodes: []string{"some-1"}}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := service.Stop(context.Background(), "some", StopOptions{Nodes: []string{"some-2"}, Delete: true}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	compute.calls = nil
+	if err := service.StartNodes(context.Background(), "some", []string{"some-1", "some-2"}, StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start nodes: %v", err)
+	}
+	want := []string{"insert " + zone + "/some-2", "start some-1"}
+	if got := compute.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+
+	err := service.StartNodes(context.Background(), "some", []string{"some-5"}, StartOptions{NumInstances: 3})
+	if err == nil || !strings.Contains(err.Error(), "not a node") {
+		t.Fatalf("expected out-of-layout error, got %v", err)
+	}
+}
+
+func TestResetRunningNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	if _, err := service.Start(context.Background(), "jolt", StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := service.Stop(context.Background(), "jolt", StopOptions{Nodes: []string{"jolt-2"}}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	var mu sync.Mutex
+	states := map[string][]string{}
+	record := func(name, state, externalIP, internalIP string) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[name] = append(states[name], state)
+	}
+	compute.calls = nil
+	if err := service.Reset(context.Background(), "jolt", nil, StartOptions{OnStateChange: record}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if got := compute.Calls(); !reflect.DeepEqual(got, []string{"reset jolt-0", "reset jolt-1"}) {
+		t.Fatalf("expected only running nodes to be reset, got %v", got)
+	}
+	want := []string{lifecycle.InstanceStateStarting, lifecycle.InstanceStateProvisioning, lifecycle.InstanceStateReady}
+	if !reflect.DeepEqual(states["jolt-1"], want) {
+		t.Fatalf("reset states = %v, want %v", states["jolt-1"], want)
+	}
+
+	compute.calls = nil
+	if err := service.Reset(context.Background(), "jolt", []string{"jolt-1"}, StartOptions{}); err != nil {
+		t.Fatalf("reset jolt-1: %v", err)
+	}
+	if got := compute.Calls(); !reflect.DeepEqual(got, []string{"reset jolt-1"}) {
+		t.Fatalf("calls = %v", got)
+	}
+}
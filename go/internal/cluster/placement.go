@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+)
+
+const placementCompact = "compact"
+
+// placementPolicyName returns the resource policy name for a cluster network,
+// or "" when cluster.placement is not configured.
+func (s *Service) placementPolicyName(networkName string) string {
+	if s.Config.Cluster.Placement != placementCompact {
+		return ""
+	}
+	return fmt.Sprintf("%s-placement", networkName)
+}
+
+// resourcePolicies returns the policy URLs attached to new cluster instances.
+func (n clusterNetwork) resourcePolicies() []string {
+	if n.PlacementURL == "" {
+		return nil
+	}
+	return []string{n.PlacementURL}
+}
+
+// ensurePlacementPolicy creates the regional compact placement policy for the
+// cluster if it does not exist yet. Existing policies are left unchanged.
+func (s *Service) ensurePlacementPolicy(ctx context.Context, network clusterNetwork) error {
+	project := network.Project
+	region := network.Region
+	name := network.PlacementPolicy
+
+	getCall := s.api("compute.resourcePolicies.get", gcp.RegionResource(project, region, "resourcePolicies", name), "")
+	_, err := s.Compute.GetResourcePolicy(ctx, &computepb.GetResourcePolicyRequest{
+		Project:        project,
+		Region:         region,
+		ResourcePolicy: name,
+	})
+	getCall.Stop()
+	if err == nil {
+		return nil
+	}
+	if !gcp.IsNotFound(err) {
+		return err
+	}
+
+	call := s.api("compute.resourcePolicies.insert", gcp.RegionResource(project, region, "resourcePolicies", name), fmt.Sprintf("Creating placement policy %s", name))
+	op, err := s.Compute.InsertResourcePolicy(ctx, &computepb.InsertResourcePolicyRequest{
+		Project: project,
+		Region:  region,
+		ResourcePolicyResource: &computepb.ResourcePolicy{
+			Name: proto.String(name),
+			GroupPlacementPolicy: &computepb.ResourcePolicyGroupPlacementPolicy{
+				Collocation: proto.String("COLLOCATED"),
+			},
+		},
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+// deletePlacementPolicy deletes a cluster placement policy. A policy that is
+// already gone is not an error.
+func (s *Service) deletePlacementPolicy(ctx context.Context, project, region, name string) error {
+	call := s.api("compute.resourcePolicies.delete", gcp.RegionResource(project, region, "resourcePolicies", name), fmt.Sprintf("Deleting placement policy %s", name))
+	op, err := s.Compute.DeleteResourcePolicy(ctx, &computepb.DeleteResourcePolicyRequest{
+		Project:        project,
+		Region:         region,
+		ResourcePolicy: name,
+	})
+	if err != nil {
+		call.Stop()
+		if gcp.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompactPlacementPolicyLifecycle(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Cluster.Placement = "compact"
+	zone := service.Config.Project.Zone
+
+	if _, err := service.Start(context.Background(), "nccl", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	network, err := service.resolveClusterNetwork("nccl")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	if network.PlacementPolicy != "gpunow-nccl-placement" {
+		t.Fatalf("unexpected policy name %q", network.PlacementPolicy)
+	}
+	policyKey := "resourcePolicies/" + network.Region + "/" + network.PlacementPolicy
+	if compute.has(policyKey) != nil {
+		t.Fatalf("expected placement policy, got %v", compute.Resources())
+	}
+	for _, name := range []string{"nccl-0", "nccl-1"} {
+		policies := compute.get(zone, name).GetResourcePolicies()
+		if len(policies) != 1 || policies[0] != network.PlacementURL {
+			t.Fatalf("expected %s to use %s, got %v", name, network.PlacementURL, policies)
+		}
+	}
+
+	if err := service.Stop(context.Background(), "nccl", StopOptions{Delete: true}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if compute.has(policyKey) == nil {
+		t.Fatalf("expected placement policy to be deleted, got %v", compute.Resources())
+	}
+}
+
+func TestPlacementDisabledByDefault(t *testing.T) {
+	service := newTestService(t, newFakeCompute())
+	network, err := service.resolveClusterNetwork("plain")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	if network.PlacementPolicy != "" || network.resourcePolicies() != nil {
+		t.Fatalf("expected no placement policy, got %+v", network)
+	}
+}
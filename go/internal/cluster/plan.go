@@ -0,0 +1,357 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/validate"
+)
+
+const (
+	PlanCreate    = "create"
+	PlanPatch     = "patch"
+	PlanStart     = "start"
+	PlanStop      = "stop"
+	PlanDelete    = "delete"
+	PlanUnchanged = "unchanged"
+)
+
+// Plan lists what a start or stop would do to each cluster resource, without
+// changing anything.
+type Plan struct {
+	Cluster string
+	Zone    string
+	// CloudInitBytes is the size of the largest rendered cloud-init
+	// user-data across pools; zero for stop plans.
+	CloudInitBytes int
+	Changes        []PlanChange
+}
+
+type PlanChange struct {
+	Action string
+	// Kind is the compute collection, e.g. "networks" or "instances".
+	Kind   string
+	Name   string
+	Detail string
+	Diffs  []FieldDiff
+}
+
+type FieldDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Count returns the number of changes with action.
+func (p *Plan) Count(action string) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *Plan) add(action, kind, name string) *PlanChange {
+	p.Changes = append(p.Changes, PlanChange{Action: action, Kind: kind, Name: name})
+	return &p.Changes[len(p.Changes)-1]
+}
+
+// PlanStart resolves the same resources Start would touch in the first
+// candidate zone and reports what would be created, patched or started.
+// Zone fallback is not simulated.
+func (s *Service) PlanStart(ctx context.Context, clusterName string, opts StartOptions) (*Plan, error) {
+	if !validate.IsResourceName(clusterName) {
+		return nil, fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.NumInstances <= 0 {
+		return nil, fmt.Errorf("num-instances must be >= 1")
+	}
+	planner := s
+	if len(opts.Zones) > 0 {
+		planner = s.inZone(opts.Zones[0])
+	}
+	return planner.planStart(ctx, clusterName, opts)
+}
+
+func (s *Service) planStart(ctx context.Context, clusterName string, opts StartOptions) (*Plan, error) {
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.layoutNodes(clusterName, opts)
+	if err != nil {
+		return nil, err
+	}
+	cloudInits, err := s.renderCloudInits(nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Cluster: clusterName, Zone: network.Zone}
+	for _, cloudInit := range cloudInits {
+		plan.CloudInitBytes = max(plan.CloudInitBytes, len(cloudInit))
+	}
+	project := network.Project
+	networkProject := network.NetworkProject
+
+	if network.Existing {
+		if err := s.checkExistingNetwork(ctx, network); err != nil {
+			return nil, err
+		}
+		plan.add(PlanUnchanged, "networks", network.NetworkName).Detail = "existing"
+		plan.add(PlanUnchanged, "subnetworks", network.SubnetName).Detail = "existing"
+	} else {
+		_, err = s.Compute.GetNetwork(ctx, &computepb.GetNetworkRequest{Project: networkProject, Network: network.NetworkName})
+		if err := planPresence(plan, err, "networks", network.NetworkName); err != nil {
+			return nil, err
+		}
+		subnet, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: networkProject, Region: network.Region, Subnetwork: network.SubnetName})
+		if err := planPresence(plan, err, "subnetworks", network.SubnetName); err != nil {
+			return nil, err
+		}
+		change := &plan.Changes[len(plan.Changes)-1]
+		change.Detail = network.SubnetCIDR
+		if err == nil && network.DualStack && subnet.GetStackType() != "IPV4_IPV6" {
+			change.Action = PlanPatch
+			change.Diffs = []FieldDiff{{Field: "stackType", Old: subnet.GetStackType(), New: "IPV4_IPV6"}}
+		}
+	}
+
+	firewalls := s.clusterFirewalls(clusterName, network, opts)
+	for _, desired := range firewalls {
+		existing, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{Project: networkProject, Firewall: desired.GetName()})
+		if err != nil {
+			if !gcp.IsNotFound(err) {
+				return nil, err
+			}
+			plan.add(PlanCreate, "firewalls", desired.GetName()).Detail = formatAllowed(desired.GetAllowed())
+			continue
+		}
+		diffs := firewallDiffs(existing, desired)
+		if len(diffs) == 0 {
+			plan.add(PlanUnchanged, "firewalls", desired.GetName())
+			continue
+		}
+		plan.add(PlanPatch, "firewalls", desired.GetName()).Diffs = diffs
+	}
+	stale, err := s.staleFirewalls(ctx, network, firewalls)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		plan.add(PlanDelete, "firewalls", name)
+	}
+
+	if network.PlacementPolicy != "" {
+		_, err := s.Compute.GetResourcePolicy(ctx, &computepb.GetResourcePolicyRequest{Project: project, Region: network.Region, ResourcePolicy: network.PlacementPolicy})
+		if err := planPresence(plan, err, "resourcePolicies", network.PlacementPolicy); err != nil {
+			return nil, err
+		}
+	}
+	if network.RouterName != "" {
+		_, err := s.Compute.GetRouter(ctx, &computepb.GetRouterRequest{Project: networkProject, Region: network.Region, Router: network.RouterName})
+		if err := planPresence(plan, err, "routers", network.RouterName); err != nil {
+			return nil, err
+		}
+	}
+	if network.DatasetDisk != "" {
+		_, err := s.Compute.GetDisk(ctx, &computepb.GetDiskRequest{Project: project, Zone: network.Zone, Disk: network.DatasetDisk})
+		if gcp.IsNotFound(err) && network.DatasetSnapshot == "" {
+			return nil, missingDatasetDisk(network)
+		}
+		if err := planPresence(plan, err, "disks", network.DatasetDisk); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			plan.Changes[len(plan.Changes)-1].Detail = "snapshot " + gcp.ShortName(network.DatasetSnapshot)
+		}
+	}
+
+	for _, node := range nodes {
+		inst, err := s.getInstance(ctx, node.Name)
+		if err != nil {
+			return nil, err
+		}
+		if inst == nil {
+			plan.add(PlanCreate, "instances", node.Name).Detail = s.instanceDetail(s.nodeOptions(node, opts))
+			continue
+		}
+		if err := node.claims(inst); err != nil {
+			return nil, err
+		}
+		action := PlanUnchanged
+		if inst.GetStatus() != "RUNNING" {
+			action = PlanStart
+		}
+		tags := s.clusterTags(node.Cluster, node.Master())
+		var diffs []FieldDiff
+		if !containsAll(inst.GetTags().GetItems(), tags) {
+			diffs = append(diffs, FieldDiff{Field: "tags", Old: joinSorted(inst.GetTags().GetItems()), New: joinSorted(tags)})
+			if action == PlanUnchanged {
+				action = PlanPatch
+			}
+		}
+		change := plan.add(action, "instances", node.Name)
+		change.Detail = inst.GetStatus()
+		change.Diffs = diffs
+	}
+	return plan, nil
+}
+
+// PlanStop reports which instances Stop would stop or delete and, with
+// opts.Delete, which cluster network resources would be removed.
+func (s *Service) PlanStop(ctx context.Context, clusterName string, opts StopOptions) (*Plan, error) {
+	if !validate.IsResourceName(clusterName) {
+		return nil, fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	network, err := s.resolveClusterNetwork(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Cluster: clusterName, Zone: network.Zone}
+	for _, inst := range instances {
+		action := PlanStop
+		switch {
+		case opts.Delete:
+			action = PlanDelete
+		case inst.GetStatus() == "TERMINATED":
+			action = PlanUnchanged
+		}
+		change := plan.add(action, "instances", inst.GetName())
+		change.Detail = inst.GetStatus()
+		if opts.Delete {
+			if opts.KeepDisks && !opts.DeleteDisks {
+				change.Detail += ", keep disks"
+			} else {
+				change.Detail += ", delete disks"
+			}
+		}
+	}
+	if !opts.Delete || len(instances) == 0 {
+		return plan, nil
+	}
+
+	project := network.Project
+	networkProject := network.NetworkProject
+	firewalls, err := s.clusterFirewallNames(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range firewalls {
+		_, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{Project: networkProject, Firewall: rule})
+		if err := planRemoval(plan, err, "firewalls", rule); err != nil {
+			return nil, err
+		}
+	}
+	if network.RouterName != "" {
+		_, err := s.Compute.GetRouter(ctx, &computepb.GetRouterRequest{Project: networkProject, Region: network.Region, Router: network.RouterName})
+		if err := planRemoval(plan, err, "routers", network.RouterName); err != nil {
+			return nil, err
+		}
+	}
+	if !network.Existing {
+		_, err = s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: networkProject, Region: network.Region, Subnetwork: network.SubnetName})
+		if err := planRemoval(plan, err, "subnetworks", network.SubnetName); err != nil {
+			return nil, err
+		}
+		_, err = s.Compute.GetNetwork(ctx, &computepb.GetNetworkRequest{Project: networkProject, Network: network.NetworkName})
+		if err := planRemoval(plan, err, "networks", network.NetworkName); err != nil {
+			return nil, err
+		}
+	}
+	if network.PlacementPolicy != "" {
+		_, err := s.Compute.GetResourcePolicy(ctx, &computepb.GetResourcePolicyRequest{Project: project, Region: network.Region, ResourcePolicy: network.PlacementPolicy})
+		if err := planRemoval(plan, err, "resourcePolicies", network.PlacementPolicy); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planPresence records a create when a get returned NotFound and leaves the
+// resource unchanged when it exists.
+func planPresence(plan *Plan, err error, kind, name string) error {
+	if err == nil {
+		plan.add(PlanUnchanged, kind, name)
+		return nil
+	}
+	if !gcp.IsNotFound(err) {
+		return err
+	}
+	plan.add(PlanCreate, kind, name)
+	return nil
+}
+
+// planRemoval records a delete for resources that exist; missing ones are
+// skipped since Stop ignores them.
+func planRemoval(plan *Plan, err error, kind, name string) error {
+	if err == nil {
+		plan.add(PlanDelete, kind, name)
+		return nil
+	}
+	if gcp.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) instanceDetail(opts StartOptions) string {
+	machineType := strings.TrimSpace(opts.MachineType)
+	if machineType == "" {
+		machineType = s.Config.Instance.MachineType
+	}
+	diskSizeGB := opts.DiskSizeGB
+	if diskSizeGB <= 0 {
+		diskSizeGB = s.Config.Disk.SizeGB
+	}
+	return fmt.Sprintf("%s, %d GB boot disk", machineType, diskSizeGB)
+}
+
+// firewallDiffs compares the fields ensureFirewall would patch.
+func firewallDiffs(existing, desired *computepb.Firewall) []FieldDiff {
+	diffs := []FieldDiff{}
+	compare := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			diffs = append(diffs, FieldDiff{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	compare("direction", existing.GetDirection(), desired.GetDirection())
+	compare("sourceRanges", joinSorted(existing.GetSourceRanges()), joinSorted(desired.GetSourceRanges()))
+	compare("sourceTags", joinSorted(existing.GetSourceTags()), joinSorted(desired.GetSourceTags()))
+	compare("targetTags", joinSorted(existing.GetTargetTags()), joinSorted(desired.GetTargetTags()))
+	compare("allowed", formatAllowed(existing.GetAllowed()), formatAllowed(desired.GetAllowed()))
+	return diffs
+}
+
+// formatAllowed renders allow rules as sorted "proto:ports" entries, e.g.
+// "icmp,tcp:22,tcp:8888".
+func formatAllowed(rules []*computepb.Allowed) string {
+	items := []string{}
+	for _, rule := range rules {
+		protocol := rule.GetIPProtocol()
+		if len(rule.GetPorts()) == 0 {
+			items = append(items, protocol)
+			continue
+		}
+		for _, port := range rule.GetPorts() {
+			items = append(items, fmt.Sprintf("%s:%s", protocol, port))
+		}
+	}
+	return joinSorted(items)
+}
+
+func joinSorted(items []string) string {
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+)
+
+func planActions(plan *Plan) map[string]string {
+	actions := map[string]string{}
+	for _, change := range plan.Changes {
+		actions[change.Kind+"/"+change.Name] = change.Action
+	}
+	return actions
+}
+
+func TestPlanStartIsReadOnly(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+
+	plan, err := service.PlanStart(context.Background(), "fresh", StartOptions{NumInstances: 2})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if got := plan.Count(PlanCreate); got != 7 {
+		t.Fatalf("expected network, subnet, 3 firewalls and 2 instances to be created, got %d: %v", got, planActions(plan))
+	}
+	if plan.CloudInitBytes == 0 {
+		t.Fatalf("expected rendered cloud-init size")
+	}
+	if len(compute.Resources()) != 0 || len(compute.Calls()) != 0 {
+		t.Fatalf("plan must not change resources: %v %v", compute.Resources(), compute.Calls())
+	}
+}
+
+func TestPlanStartReportsDrift(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+
+	if _, err := service.Start(ctx, "drift", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	compute.get(service.Config.Project.Zone, "drift-1").Status = proto.String("TERMINATED")
+	network, err := service.resolveClusterNetwork("drift")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	compute.firewalls[network.SSHRule].SourceRanges = []string{"198.51.100.0/24"}
+
+	plan, err := service.PlanStart(ctx, "drift", StartOptions{NumInstances: 3})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	actions := planActions(plan)
+	want := map[string]string{
+		"networks/" + network.NetworkName:   PlanUnchanged,
+		"subnetworks/" + network.SubnetName: PlanUnchanged,
+		"firewalls/" + network.InternalRule: PlanUnchanged,
+		"firewalls/" + network.SSHRule:      PlanPatch,
+		"firewalls/" + network.PortsRule:    PlanUnchanged,
+		"instances/drift-0":                 PlanUnchanged,
+		"instances/drift-1":                 PlanStart,
+		"instances/drift-2":                 PlanCreate,
+	}
+	for key, action := range want {
+		if actions[key] != action {
+			t.Fatalf("%s: got %q, want %q (plan %v)", key, actions[key], action, actions)
+		}
+	}
+	for _, change := range plan.Changes {
+		if change.Name != network.SSHRule {
+			continue
+		}
+		if len(change.Diffs) != 1 || change.Diffs[0].Field != "sourceRanges" || change.Diffs[0].New != "0.0.0.0/0" {
+			t.Fatalf("unexpected firewall diff: %+v", change.Diffs)
+		}
+	}
+}
+
+func TestPlanStartChecksDatasetDisk(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+
+	service.Config.Cluster.SharedDatasetDisk = "imagenet"
+	if _, err := service.PlanStart(ctx, "data", StartOptions{NumInstances: 1}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing dataset disk error, got %v", err)
+	}
+
+	service.Config.Cluster.SharedDatasetDisk = "snapshots/imagenet-v2"
+	plan, err := service.PlanStart(ctx, "data", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if action := planActions(plan)["disks/gpunow-dataset-imagenet-v2"]; action != PlanCreate {
+		t.Fatalf("expected the dataset disk to be created from its snapshot, got %q (plan %v)", action, planActions(plan))
+	}
+	if _, err := service.Start(ctx, "data", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	plan, err = service.PlanStart(ctx, "data", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if action := planActions(plan)["disks/gpunow-dataset-imagenet-v2"]; action != PlanUnchanged {
+		t.Fatalf("expected the dataset disk to be unchanged, got %q", action)
+	}
+}
+
+func TestPlanStopDelete(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+
+	if _, err := service.Start(ctx, "gone", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	plan, err := service.PlanStop(ctx, "gone", StopOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if got := plan.Count(PlanDelete); got != 7 {
+		t.Fatalf("expected 2 instances and 5 network resources to be deleted, got %d: %v", got, planActions(plan))
+	}
+}
+
+func TestFormatAllowed(t *testing.T) {
+	rules := []*computepb.Allowed{
+		{IPProtocol: proto.String("tcp"), Ports: []string{"8888", "22"}},
+		{IPProtocol: proto.String("icmp")},
+	}
+	if got := formatAllowed(rules); got != "icmp,tcp:22,tcp:8888" {
+		t.Fatalf("formatAllowed = %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/cloudinit"
+)
+
+// Pool is a named group of cluster nodes and its size. Machine type, disk
+// size and setup script come from the matching cluster.pools entry.
+type Pool struct {
+	Name  string
+	Count int
+}
+
+// layoutNodes returns every node the cluster should have: indexes 0 to
+// count-1 of each pool in order, or <cluster>-0 to <cluster>-(n-1) when
+// opts.Pools is empty. The first node is the master.
+func (s *Service) layoutNodes(clusterName string, opts StartOptions) ([]clusterNode, error) {
+	if len(opts.Pools) == 0 {
+		return s.clusterNodes(clusterName, indexRange(0, opts.NumInstances)), nil
+	}
+	nodes := []clusterNode{}
+	for i, pool := range opts.Pools {
+		if _, ok := s.Config.Pool(pool.Name); !ok {
+			return nil, fmt.Errorf("pool %s is not defined in cluster.pools", pool.Name)
+		}
+		if pool.Count < 0 {
+			return nil, fmt.Errorf("pool %s count must be >= 0", pool.Name)
+		}
+		if i == 0 && pool.Count == 0 {
+			return nil, fmt.Errorf("pool %s holds the master and needs at least one node", pool.Name)
+		}
+		for index := 0; index < pool.Count; index++ {
+			role := "worker"
+			if len(nodes) == 0 {
+				role = "master"
+			}
+			nodes = append(nodes, clusterNode{
+				Cluster: clusterName,
+				Name:    s.poolInstanceName(clusterName, pool.Name, index),
+				Index:   index,
+				Pool:    pool.Name,
+				Role:    role,
+			})
+		}
+	}
+	return nodes, nil
+}
+
+func (s *Service) poolInstanceName(clusterName, pool string, index int) string {
+	return fmt.Sprintf("%s-%s-%d", clusterName, pool, index)
+}
+
+// nodeOptions returns opts with the machine type and disk size of the node's
+// pool applied.
+func (s *Service) nodeOptions(node clusterNode, opts StartOptions) StartOptions {
+	if node.Pool == "" {
+		return opts
+	}
+	spec, ok := s.Config.Pool(node.Pool)
+	if !ok {
+		return opts
+	}
+	if machineType := strings.TrimSpace(spec.MachineType); machineType != "" {
+		opts.MachineType = machineType
+	}
+	// A cluster-wide `disk resize` records a size at least as large as every
+	// pool's, and must not be undone for nodes created later.
+	opts.DiskSizeGB = max(opts.DiskSizeGB, spec.DiskSizeGB)
+	return opts
+}
+
+// renderCloudInits renders the cloud-init user-data once per pool in nodes,
+// keyed by pool name ("" for clusters without pools). A port set changed by
+// `gpunow ports` (opts.Ports) is allowed in ufw, as on the running nodes.
+func (s *Service) renderCloudInits(nodes []clusterNode, opts StartOptions) (map[string]string, error) {
+	allows := []string{}
+	for _, spec := range opts.Ports {
+		allows = append(allows, ufwRules(spec)...)
+	}
+	rendered := map[string]string{}
+	for _, node := range nodes {
+		if _, ok := rendered[node.Pool]; ok {
+			continue
+		}
+		cloudInit, err := cloudinit.Render(s.Config.Paths.CloudInitFile, s.Config.PoolSetupScript(node.Pool), s.Config.Paths.ZshrcFile, s.datasetMounts(), allows)
+		if err != nil {
+			return nil, err
+		}
+		rendered[node.Pool] = cloudInit
+	}
+	return rendered, nil
+}
+
+func instancesByName(instances []*computepb.Instance) map[string]*computepb.Instance {
+	byName := make(map[string]*computepb.Instance, len(instances))
+	for _, inst := range instances {
+		byName[inst.GetName()] = inst
+	}
+	return byName
+}
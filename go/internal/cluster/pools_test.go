@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"gpunow/internal/config"
+)
+
+func usePools(service *Service) {
+	service.Config.Cluster.Pools = []config.PoolConfig{
+		{Name: "head", Count: 1, MachineType: "n2-standard-8"},
+		{Name: "gpu", Count: 2, MachineType: "g2-standard-48", DiskSizeGB: 500},
+	}
+}
+
+func TestStartCreatesNodesPerPool(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	usePools(service)
+	zone := service.Config.Project.Zone
+
+	_, err := service.Start(context.Background(), "mixed", StartOptions{
+		NumInstances: 3,
+		Pools:        []Pool{{Name: "head", Count: 1}, {Name: "gpu", Count: 2}},
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	head := compute.get(zone, "mixed-head-0")
+	if head == nil || path.Base(head.GetMachineType()) != "n2-standard-8" {
+		t.Fatalf("unexpected head node: %v", head)
+	}
+	if labels := head.GetLabels(); labels["cluster_pool"] != "head" || labels["cluster_role"] != "master" || labels["cluster_index"] != "0" {
+		t.Fatalf("unexpected head labels: %v", labels)
+	}
+	gpu := compute.get(zone, "mixed-gpu-1")
+	if gpu == nil || path.Base(gpu.GetMachineType()) != "g2-standard-48" {
+		t.Fatalf("unexpected gpu node: %v", gpu)
+	}
+	if labels := gpu.GetLabels(); labels["cluster_pool"] != "gpu" || labels["cluster_role"] != "worker" || labels["cluster_index"] != "1" {
+		t.Fatalf("unexpected gpu labels: %v", labels)
+	}
+	if size := gpu.GetDisks()[0].GetInitializeParams().GetDiskSizeGb(); size != 500 {
+		t.Fatalf("expected gpu pool disk size 500, got %d", size)
+	}
+}
+
+func TestScaleResizesOnePool(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	usePools(service)
+	zone := service.Config.Project.Zone
+
+	opts := StartOptions{NumInstances: 3, Pools: []Pool{{Name: "head", Count: 1}, {Name: "gpu", Count: 2}}}
+	if _, err := service.Start(context.Background(), "mixed", opts); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	compute.calls = nil
+
+	opts = StartOptions{NumInstances: 2, Pools: []Pool{{Name: "head", Count: 1}, {Name: "gpu", Count: 1}}}
+	if err := service.Scale(context.Background(), "mixed", opts); err != nil {
+		t.Fatalf("scale: %v", err)
+	}
+	if want := []string{"delete " + zone + "/mixed-gpu-1"}; !reflect.DeepEqual(compute.Calls(), want) {
+		t.Fatalf("unexpected compute calls: %v, want %v", compute.Calls(), want)
+	}
+	if compute.get(zone, "mixed-head-0") == nil || compute.get(zone, "mixed-gpu-0") == nil {
+		t.Fatalf("expected head-0 and gpu-0 to remain")
+	}
+}
+
+func TestLayoutNodesRejectsUnknownPoolAndEmptyMasterPool(t *testing.T) {
+	service := newTestService(t, newFakeCompute())
+	usePools(service)
+
+	if _, err := service.layoutNodes("mixed", StartOptions{Pools: []Pool{{Name: "tpu", Count: 1}}}); err == nil {
+		t.Fatalf("expected error for undefined pool")
+	}
+	if _, err := service.layoutNodes("mixed", StartOptions{Pools: []Pool{{Name: "head", Count: 0}, {Name: "gpu", Count: 2}}}); err == nil {
+		t.Fatalf("expected error for empty master pool")
+	}
+}
+
+func TestStartRefusesPoolNodeOfAnotherCluster(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	usePools(service)
+	zone := service.Config.Project.Zone
+
+	opts := StartOptions{NumInstances: 3, Pools: []Pool{{Name: "head", Count: 1}, {Name: "gpu", Count: 2}}}
+	if _, err := service.Start(context.Background(), "a", opts); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	tags := compute.get(zone, "a-gpu-0").GetTags().GetItems()
+	compute.calls = nil
+
+	// Node 0 of cluster "a-gpu" has the name of pool node a-gpu-0 of cluster "a".
+	service.Config.Cluster.Pools = nil
+	_, err := service.Start(context.Background(), "a-gpu", StartOptions{NumInstances: 2, MinReady: 1, DeleteFailed: true})
+	if err == nil {
+		t.Fatalf("expected start to refuse an instance of cluster a")
+	}
+	inst := compute.get(zone, "a-gpu-0")
+	if inst == nil {
+		t.Fatalf("a-gpu-0 of cluster a must not be deleted")
+	}
+	if labels := inst.GetLabels(); labels["cluster"] != "a" || labels["cluster_pool"] != "gpu" {
+		t.Fatalf("unexpected a-gpu-0 labels: %v", labels)
+	}
+	if !reflect.DeepEqual(inst.GetTags().GetItems(), tags) {
+		t.Fatalf("a-gpu-0 tags changed: %v, want %v", inst.GetTags().GetItems(), tags)
+	}
+}
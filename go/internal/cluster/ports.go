@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/parse"
+	"gpunow/internal/ssh"
+	"gpunow/internal/validate"
+)
+
+// PortsOptions selects what ApplyPorts changes on a running cluster.
+type PortsOptions struct {
+	// Start carries the cluster's new port set (Start.Ports), its source
+	// range overrides and the SSH user and identity used to reach nodes.
+	Start StartOptions
+	// Open and Close are the entries added to and removed from the port set;
+	// they are applied to ufw on every running node.
+	Open  []parse.PortSpec
+	Close []parse.PortSpec
+	// OnFirewallsUpdated is called once the firewall rules match
+	// Start.Ports, before ufw is changed on the nodes, so the new port set
+	// can be recorded even when a node is unreachable.
+	OnFirewallsUpdated func()
+}
+
+// CheckManagedPorts refuses port entries covering SSH (22/tcp) or the
+// readiness port: ufw commands for them would lock nodes out of SSH and
+// readiness probes.
+func CheckManagedPorts(specs []parse.PortSpec) error {
+	for _, spec := range specs {
+		for _, port := range []int{22, readinessPort} {
+			if spec.Contains(port, "tcp") {
+				return fmt.Errorf("port %s covers %d/tcp, which gpunow keeps open on every node", spec, port)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyPorts updates the cluster's ports firewall rules to opts.Start.Ports
+// and opens opts.Open and closes opts.Close in the ufw of every running node
+// over SSH. Private workers are reached through the master. Nodes created
+// later get the port set through cloud-init (see renderCloudInits).
+func (s *Service) ApplyPorts(ctx context.Context, clusterName string, opts PortsOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if err := CheckManagedPorts(append(append([]parse.PortSpec{}, opts.Open...), opts.Close...)); err != nil {
+		return err
+	}
+	commands := ufwCommands(opts.Open, opts.Close)
+	if len(commands) > 0 && opts.Start.SSHUser == "" {
+		return fmt.Errorf("ssh.default_user is required to update ufw on cluster nodes")
+	}
+	// The subnet ranges are looked up so the full desired rule set, with the
+	// internal "-v6" sibling of dual-stack clusters, is known.
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts.Start)
+	if err != nil {
+		return err
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	masterIP := ""
+	running := []*computepb.Instance{}
+	for _, inst := range instances {
+		if len(commands) == 0 {
+			break
+		}
+		if inst.GetStatus() != "RUNNING" {
+			s.UI.Warnf("Skipping ufw on %s (%s); rerun the ports command once it is running", inst.GetName(), inst.GetStatus())
+			continue
+		}
+		if isMaster(inst) {
+			masterIP, _ = instanceIPs(inst)
+		}
+		running = append(running, inst)
+	}
+
+	taskNames := []string{fmt.Sprintf("firewall %s", network.PortsRule)}
+	for _, inst := range running {
+		taskNames = append(taskNames, fmt.Sprintf("ufw %s", inst.GetName()))
+	}
+	progress := s.UI.TaskList("Updating ports", taskNames)
+	defer progress.Stop()
+
+	firewalls := s.clusterFirewalls(clusterName, network, opts.Start)
+	if err := s.ensurePortFirewalls(ctx, network, firewalls); err != nil {
+		return err
+	}
+	progress.MarkDone(0, fmt.Sprintf("Ready firewalls/%s", network.PortsRule))
+	if opts.OnFirewallsUpdated != nil {
+		opts.OnFirewallsUpdated()
+	}
+
+	if err := s.ensureSSHKeys(ctx, running, opts.Start.SSHUser, opts.Start.SSHPublicKey); err != nil {
+		return err
+	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	for i, inst := range running {
+		index := i + 1
+		name := inst.GetName()
+		group.Go(func() error {
+			target, jumpHost, err := guestSSHTarget(inst, masterIP, opts.Start.SSHUser, opts.Start.SSHIdentityFile)
+			if err != nil {
+				return err
+			}
+			if _, err := s.runGuestSSH(groupCtx, guestSSHArgs(target, jumpHost, commands)); err != nil {
+				return fmt.Errorf("update ufw on %s: %w", name, err)
+			}
+			progress.MarkDone(index, fmt.Sprintf("Updated ufw on %s", name))
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// ufwCommands returns the ufw commands that open the opened entries and
+// close the closed ones, e.g. "sudo ufw allow 8000:8100/tcp".
+func ufwCommands(opened, closed []parse.PortSpec) []string {
+	commands := []string{}
+	for _, spec := range closed {
+		for _, rule := range ufwRules(spec) {
+			commands = append(commands, "sudo ufw delete allow "+rule)
+		}
+	}
+	for _, spec := range opened {
+		for _, rule := range ufwRules(spec) {
+			commands = append(commands, "sudo ufw allow "+rule)
+		}
+	}
+	return commands
+}
+
+func ufwRules(spec parse.PortSpec) []string {
+	ports := strings.ReplaceAll(spec.Range(), "-", ":")
+	if len(spec.SourceRanges) == 0 {
+		return []string{fmt.Sprintf("%s/%s", ports, spec.Protocol)}
+	}
+	rules := make([]string, 0, len(spec.SourceRanges))
+	for _, cidr := range spec.SourceRanges {
+		rules = append(rules, fmt.Sprintf("from %s to any port %s proto %s", cidr, ports, spec.Protocol))
+	}
+	return rules
+}
+
+// guestSSHTarget returns the ssh target for inst and the host to jump
+// through, which is masterIP when inst has no external IP.
+func guestSSHTarget(inst *computepb.Instance, masterIP, user, identityFile string) (ReadinessJump, string, error) {
+	externalIP, internalIP := instanceIPs(inst)
+	target := ReadinessJump{User: user, Host: externalIP, IdentityFile: identityFile}
+	if externalIP != "" {
+		return target, "", nil
+	}
+	if masterIP == "" {
+		return target, "", fmt.Errorf("%s has no external IP and the cluster master is not running", inst.GetName())
+	}
+	target.Host = internalIP
+	return target, masterIP, nil
+}
+
+// guestSSHArgs builds a non-interactive ssh command that runs commands on
+// target, jumping through jumpHost when set.
+func guestSSHArgs(target ReadinessJump, jumpHost string, commands []string) []string {
+	opts := ssh.SSHOptions{
+		User:         target.User,
+		Host:         target.Host,
+		IdentityFile: target.IdentityFile,
+		Options:      []string{"BatchMode=yes", "StrictHostKeyChecking=accept-new", "ConnectTimeout=5"},
+		Command:      []string{strings.Join(commands, " && ")},
+	}
+	if jumpHost != "" {
+		opts.ProxyJump = ssh.FormatRemoteHost(target.User, jumpHost)
+	}
+	return ssh.BuildSSHArgs(opts)
+}
+
+// runGuestSSH runs ssh with args and returns its stdout.
+func (s *Service) runGuestSSH(ctx context.Context, args []string) (string, error) {
+	if s.runSSH != nil {
+		return s.runSSH(ctx, args)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return stdout.String(), fmt.Errorf("%w: %s", err, detail)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
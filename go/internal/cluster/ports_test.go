@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gpunow/internal/parse"
+)
+
+func TestApplyPortsUpdatesFirewallAndUFW(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Cluster.PrivateWorkers = true
+	var mu sync.Mutex
+	commands := []string{}
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, strings.Join(args, " "))
+		return "", nil
+	}
+
+	start := StartOptions{NumInstances: 2, SSHUser: "mo"}
+	if _, err := service.Start(context.Background(), "ports", start); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	opened, err := parse.PortsCSV("6006,60000-61000/udp@10.8.0.0/16")
+	if err != nil {
+		t.Fatalf("parse ports: %v", err)
+	}
+	start.Ports = append(append([]parse.PortSpec{}, service.Config.Network.Ports...), opened...)
+	if err := service.ApplyPorts(context.Background(), "ports", PortsOptions{Start: start, Open: opened}); err != nil {
+		t.Fatalf("apply ports: %v", err)
+	}
+
+	if got := formatAllowed(compute.firewalls["gpunow-ports-ports"].GetAllowed()); got != "tcp:22,tcp:34223,tcp:6006" {
+		t.Fatalf("ports rule allowed = %s", got)
+	}
+	if got := formatAllowed(compute.firewalls["gpunow-ports-ports-1"].GetAllowed()); got != "udp:60000-61000" {
+		t.Fatalf("source rule allowed = %s", got)
+	}
+	want := []string{
+		"-o BatchMode=yes -o StrictHostKeyChecking=accept-new -o ConnectTimeout=5 -J mo@203.0.113.10 mo@10.0.0.2 sudo ufw allow 6006/tcp && sudo ufw allow from 10.8.0.0/16 to any port 60000:61000 proto udp",
+		"-o BatchMode=yes -o StrictHostKeyChecking=accept-new -o ConnectTimeout=5 mo@203.0.113.10 sudo ufw allow 6006/tcp && sudo ufw allow from 10.8.0.0/16 to any port 60000:61000 proto udp",
+	}
+	sort.Strings(commands)
+	if !reflect.DeepEqual(commands, want) {
+		t.Fatalf("ssh commands = %q, want %q", commands, want)
+	}
+}
+
+func TestUFWCommandsCloseBeforeOpen(t *testing.T) {
+	opened, _ := parse.PortsCSV("8000-8100/tcp")
+	closed, _ := parse.PortsCSV("6006")
+	want := []string{"sudo ufw delete allow 6006/tcp", "sudo ufw allow 8000:8100/tcp"}
+	if got := ufwCommands(opened, closed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestApplyPortsKeepsDualStackInternalRule(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.Config.Network.StackType = "IPV4_IPV6"
+
+	start := StartOptions{NumInstances: 1}
+	if _, err := service.Start(context.Background(), "dual", start); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	opened, _ := parse.PortsCSV("6006")
+	start.Ports = append(append([]parse.PortSpec{}, service.Config.Network.Ports...), opened...)
+	if err := service.ApplyPorts(context.Background(), "dual", PortsOptions{Start: start}); err != nil {
+		t.Fatalf("apply ports: %v", err)
+	}
+	if got := compute.firewalls["gpunow-dual-internal-v6"].GetSourceRanges(); !reflect.DeepEqual(got, []string{fakeIPv6Prefix}) {
+		t.Fatalf("internal-v6 source ranges = %v, calls %v", got, compute.Calls())
+	}
+	if got := formatAllowed(compute.firewalls["gpunow-dual-ports-v6"].GetAllowed()); !strings.Contains(got, "tcp:6006") {
+		t.Fatalf("ports-v6 allowed = %s", got)
+	}
+}
+
+func TestCheckManagedPortsRefusesSSHAndReadiness(t *testing.T) {
+	for _, value := range []string{"22", "20-30/tcp", "34223", "34000-35000"} {
+		specs, _ := parse.PortsCSV(value)
+		if err := CheckManagedPorts(specs); err == nil {
+			t.Fatalf("expected %s to be refused", value)
+		}
+	}
+	specs, _ := parse.PortsCSV("22/udp,6006")
+	if err := CheckManagedPorts(specs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestApplyPortsRecordsBeforeUFWAndLaterNodesGetRules(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	service.runSSH = func(ctx context.Context, args []string) (string, error) {
+		return "", errors.New("connection refused")
+	}
+
+	start := StartOptions{NumInstances: 1, SSHUser: "mo"}
+	if _, err := service.Start(context.Background(), "later", start); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	opened, _ := parse.PortsCSV("6006")
+	start.Ports = append(append([]parse.PortSpec{}, service.Config.Network.Ports...), opened...)
+	recorded := false
+	err := service.ApplyPorts(context.Background(), "later", PortsOptions{
+		Start:              start,
+		Open:               opened,
+		OnFirewallsUpdated: func() { recorded = true },
+	})
+	if err == nil || !recorded {
+		t.Fatalf("expected the ufw failure after the port set was recorded, got %v (recorded %v)", err, recorded)
+	}
+
+	start.NumInstances = 2
+	if err := service.Scale(context.Background(), "later", start); err != nil {
+		t.Fatalf("scale: %v", err)
+	}
+	userData := ""
+	for _, item := range compute.get(service.Config.Project.Zone, "later-1").GetMetadata().GetItems() {
+		if item.GetKey() == "user-data" {
+			userData = item.GetValue()
+		}
+	}
+	if !strings.Contains(userData, "ufw allow 6006/tcp\n") {
+		t.Fatalf("expected the new node to allow 6006 in ufw, user-data:\n%s", userData)
+	}
+}
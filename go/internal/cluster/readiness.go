@@ -1,13 +1,21 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"gpunow/internal/ssh"
+	"gpunow/internal/ui"
 )
 
 const (
@@ -15,7 +23,17 @@ const (
 	readinessPort           = 34223
 )
 
-func (s *Service) WaitForReady(ctx context.Context, instanceName, host string, timeout time.Duration) error {
+// ReadinessJump is the SSH hop used to probe the sentinel of a node without
+// a public IP: the probe runs curl on Host, the cluster master.
+type ReadinessJump struct {
+	User         string
+	Host         string
+	IdentityFile string
+}
+
+// WaitForReady polls the sentinel of one instance at host. With a non-nil
+// jump, host is an internal IP probed from the master over SSH.
+func (s *Service) WaitForReady(ctx context.Context, instanceName, host string, jump *ReadinessJump, timeout time.Duration) error {
 	if host == "" {
 		return fmt.Errorf("instance %s has no external IP", instanceName)
 	}
@@ -25,59 +43,247 @@ func (s *Service) WaitForReady(ctx context.Context, instanceName, host string, t
 	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	state, err := probeReadinessHTTP(waitCtx, host)
+	probe := probeReadinessHTTP
+	if jump != nil {
+		probe = probeReadinessSSH(*jump)
+	}
+	state, err := probe(waitCtx, readinessURL(host), nil)
+	if err != nil {
+		return fmt.Errorf("wait for readiness on %s: %w", instanceName, err)
+	}
+	if state != "ready" {
+		return fmt.Errorf("instance %s reported readiness state %q", instanceName, state)
+	}
+	return nil
+}
+
+// clusterReadiness polls readiness sentinels for many nodes concurrently.
+// Every node shares one deadline, so a cluster operation waits at most the
+// readiness timeout in total regardless of how many nodes it starts. The
+// deadline starts with the first Wait, once a node has been created, so
+// slow deletes and inserts do not eat into it.
+type clusterReadiness struct {
+	timeout  time.Duration
+	once     sync.Once
+	deadline time.Time
+	progress *ui.TaskList
+	probe    readinessProbe
+	// viaMaster probes nodes without an external IP through the master; nil
+	// unless cluster.private_workers is set.
+	viaMaster func(ctx context.Context) (readinessProbe, error)
+}
+
+// readinessProbe polls a sentinel URL until it reports a terminal state.
+type readinessProbe func(ctx context.Context, probeURL string, onState func(string)) (string, error)
+
+// newClusterReadiness returns the readiness waiter for one cluster operation.
+// masterName is the node private workers are probed through.
+func (s *Service) newClusterReadiness(masterName string, opts StartOptions, progress *ui.TaskList) *clusterReadiness {
+	timeout := opts.ReadinessTimeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	readiness := &clusterReadiness{
+		timeout:  timeout,
+		progress: progress,
+		probe:    s.probe,
+	}
+	if readiness.probe == nil {
+		readiness.probe = probeReadinessHTTP
+	}
+	if s.Config.Cluster.PrivateWorkers {
+		readiness.viaMaster = s.masterProbe(masterName, opts)
+	}
+	return readiness
+}
+
+// masterProbe returns a function resolving the master's external IP once and
+// returning a probe that fetches sentinels from it over SSH. It waits for the
+// master to get its IP, since workers are created alongside it.
+func (s *Service) masterProbe(masterName string, opts StartOptions) func(ctx context.Context) (readinessProbe, error) {
+	var mu sync.Mutex
+	var probe readinessProbe
+	return func(ctx context.Context) (readinessProbe, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if probe != nil {
+			return probe, nil
+		}
+		if strings.TrimSpace(opts.SSHUser) == "" {
+			return nil, fmt.Errorf("cluster.private_workers probes workers through the master over SSH; set ssh.default_user")
+		}
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			inst, err := s.getInstance(ctx, masterName)
+			if err != nil {
+				return nil, err
+			}
+			if host, _ := instanceIPs(inst); host != "" {
+				probe = s.probe
+				if probe == nil {
+					probe = probeReadinessSSH(ReadinessJump{User: opts.SSHUser, Host: host, IdentityFile: opts.SSHIdentityFile})
+				}
+				return probe, nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w: master %s has no external IP", ctx.Err(), masterName)
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// Wait blocks until the node reports ready, reporting the observed sentinel
+// state and elapsed time into the node's progress row. Nodes without an
+// external IP are probed at internalIP through the master.
+func (r *clusterReadiness) Wait(ctx context.Context, instanceName, externalIP, internalIP string, progressIndex int) error {
+	host, probe := externalIP, r.probe
+	if host == "" && r.viaMaster != nil {
+		host = internalIP
+	}
+	if host == "" {
+		return fmt.Errorf("instance %s has no external IP", instanceName)
+	}
+	r.once.Do(func() { r.deadline = time.Now().Add(r.timeout) })
+	waitCtx, cancel := context.WithDeadline(ctx, r.deadline)
+	defer cancel()
+
+	started := time.Now()
+	report := func(state string) {
+		r.progress.SetStatus(progressIndex, fmt.Sprintf("%s %s", state, formatElapsed(time.Since(started))))
+	}
+	report("waiting")
+	if externalIP == "" {
+		viaMaster, err := r.viaMaster(waitCtx)
+		if err != nil {
+			r.progress.MarkWarning(progressIndex, fmt.Sprintf("Readiness failed for %s after %s", instanceName, formatElapsed(time.Since(started))))
+			return fmt.Errorf("wait for readiness on %s: %w", instanceName, err)
+		}
+		probe = viaMaster
+	}
+	state, err := probe(waitCtx, readinessURL(host), report)
 	if err != nil {
+		r.progress.MarkWarning(progressIndex, fmt.Sprintf("Readiness failed for %s after %s", instanceName, formatElapsed(time.Since(started))))
 		return fmt.Errorf("wait for readiness on %s: %w", instanceName, err)
 	}
 	if state != "ready" {
+		r.progress.MarkWarning(progressIndex, fmt.Sprintf("Readiness %s for %s after %s", state, instanceName, formatElapsed(time.Since(started))))
 		return fmt.Errorf("instance %s reported readiness state %q", instanceName, state)
 	}
+	r.progress.SetStatus(progressIndex, "")
 	return nil
 }
 
-func probeReadinessHTTP(ctx context.Context, host string) (string, error) {
-	probeURL := fmt.Sprintf("http://%s:%d/", host, readinessPort)
+func readinessURL(host string) string {
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(host, strconv.Itoa(readinessPort)))
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// probeReadinessHTTP polls probeURL until the sentinel reports ready or error,
+// or ctx expires. onState, when set, is called after every probe with the
+// observed state ("waiting" while the sentinel is unreachable).
+func probeReadinessHTTP(ctx context.Context, probeURL string, onState func(string)) (string, error) {
 	_, err := url.Parse(probeURL)
 	if err != nil {
 		return "", err
 	}
-
 	client := &http.Client{
 		Timeout: 3 * time.Second,
 	}
+	return pollReadiness(ctx, onState, func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, 32))
+		_ = resp.Body.Close()
+		if readErr != nil {
+			return "", readErr
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected readiness HTTP status %d", resp.StatusCode)
+		}
+		return string(body), nil
+	})
+}
+
+// probeReadinessSSH returns a probe that fetches probeURL with curl on
+// jump.Host, for nodes that are only reachable from inside the cluster.
+func probeReadinessSSH(jump ReadinessJump) readinessProbe {
+	return func(ctx context.Context, probeURL string, onState func(string)) (string, error) {
+		return pollReadiness(ctx, onState, func(ctx context.Context) (string, error) {
+			fetchCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+			defer cancel()
+			var stderr bytes.Buffer
+			cmd := exec.CommandContext(fetchCtx, "ssh", readinessSSHArgs(jump, probeURL)...)
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if err != nil {
+				if detail := strings.TrimSpace(stderr.String()); detail != "" {
+					return "", fmt.Errorf("%w: %s", err, detail)
+				}
+				return "", err
+			}
+			return string(out), nil
+		})
+	}
+}
+
+// readinessSSHArgs builds a non-interactive ssh command that prints the
+// sentinel response from jump.Host.
+func readinessSSHArgs(jump ReadinessJump, probeURL string) []string {
+	return ssh.BuildSSHArgs(ssh.SSHOptions{
+		User:         jump.User,
+		Host:         jump.Host,
+		IdentityFile: jump.IdentityFile,
+		Options:      []string{"BatchMode=yes", "StrictHostKeyChecking=accept-new", "ConnectTimeout=5"},
+		Command:      []string{"curl", "-fsS", "--max-time", "3", probeURL},
+	})
+}
+
+// pollReadiness calls fetch every two seconds until the sentinel body it
+// returns is ready or error, or ctx expires. onState, when set, is called
+// after every fetch with the observed state ("waiting" while the sentinel is
+// unreachable).
+func pollReadiness(ctx context.Context, onState func(string), fetch func(ctx context.Context) (string, error)) (string, error) {
+	if onState == nil {
+		onState = func(string) {}
+	}
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	var lastErr error
 	for {
-		req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
-		if reqErr != nil {
-			return "", reqErr
-		}
-		resp, doErr := client.Do(req)
-		if doErr == nil {
-			body, readErr := io.ReadAll(io.LimitReader(resp.Body, 32))
-			_ = resp.Body.Close()
-			if readErr == nil && resp.StatusCode == http.StatusOK {
-				state := strings.TrimSpace(strings.ToLower(string(body)))
-				switch state {
-				case "ready":
-					return "ready", nil
-				case "running":
-					lastErr = fmt.Errorf("readiness still running")
-				case "error":
-					return "error", nil
-				default:
-					lastErr = fmt.Errorf("unexpected readiness response %q", state)
-				}
-			} else if readErr != nil {
-				lastErr = readErr
-			} else {
-				lastErr = fmt.Errorf("unexpected readiness HTTP status %d", resp.StatusCode)
+		observed := "waiting"
+		body, err := fetch(ctx)
+		if err == nil {
+			state := strings.TrimSpace(strings.ToLower(body))
+			switch state {
+			case "ready":
+				onState(state)
+				return "ready", nil
+			case "running":
+				observed = state
+				lastErr = fmt.Errorf("readiness still running")
+			case "error":
+				onState(state)
+				return "error", nil
+			default:
+				lastErr = fmt.Errorf("unexpected readiness response %q", state)
 			}
 		} else {
-			lastErr = doErr
+			lastErr = err
 		}
+		onState(observed)
 
 		select {
 		case <-ctx.Done():
@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/gcp"
+)
+
+func TestProbeReadinessHTTPReportsStates(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			_, _ = w.Write([]byte("running\n"))
+			return
+		}
+		_, _ = w.Write([]byte("ready\n"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var states []string
+	state, err := probeReadinessHTTP(ctx, server.URL, func(s string) { states = append(states, s) })
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state != "ready" {
+		t.Fatalf("expected ready, got %q", state)
+	}
+	if len(states) != 2 || states[0] != "running" || states[1] != "ready" {
+		t.Fatalf("unexpected reported states: %v", states)
+	}
+}
+
+func TestProbeReadinessHTTPDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("running"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := probeReadinessHTTP(ctx, server.URL, nil); err == nil {
+		t.Fatalf("expected deadline error")
+	}
+}
+
+func TestReadinessSSHArgs(t *testing.T) {
+	args := readinessSSHArgs(ReadinessJump{User: "mo", Host: "203.0.113.10", IdentityFile: "/home/mo/.ssh/id"}, readinessURL("10.0.0.3"))
+	want := "-i /home/mo/.ssh/id -o BatchMode=yes -o StrictHostKeyChecking=accept-new -o ConnectTimeout=5 mo@203.0.113.10 curl -fsS --max-time 3 http://10.0.0.3:34223/"
+	if got := strings.Join(args, " "); got != want {
+		t.Fatalf("args = %s, want %s", got, want)
+	}
+}
+
+// slowInserts takes delay to create each instance.
+type slowInserts struct {
+	*fakeCompute
+	delay time.Duration
+}
+
+func (f slowInserts) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (gcp.Operation, error) {
+	time.Sleep(f.delay)
+	return f.fakeCompute.InsertInstance(ctx, req)
+}
+
+func TestReadinessDeadlineStartsAfterCreation(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, slowInserts{fakeCompute: compute, delay: 300 * time.Millisecond})
+	service.probe = func(ctx context.Context, probeURL string, onState func(string)) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "ready", nil
+	}
+	opts := StartOptions{NumInstances: 2, ReadinessTimeout: 200 * time.Millisecond}
+	if _, err := service.Start(context.Background(), "slow", opts); err != nil {
+		t.Fatalf("expected slow inserts not to count against readiness, got %v", err)
+	}
+}
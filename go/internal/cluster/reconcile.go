@@ -0,0 +1,447 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/instance"
+	"gpunow/internal/labels"
+	"gpunow/internal/validate"
+)
+
+type ReconcileOptions struct {
+	// Start carries the node count and per-cluster overrides (max run hours,
+	// termination action) the cluster was created with.
+	Start StartOptions
+	// Apply fixes drift in place; without it Reconcile only reports.
+	Apply bool
+}
+
+// Drift is one field of a live cluster resource that differs from what the
+// profile and cluster config expect.
+type Drift struct {
+	Kind  string
+	Name  string
+	Field string
+	Live  string
+	Want  string
+	// Note explains why the drift cannot be fixed in place; empty when it
+	// can.
+	Note  string
+	Fixed bool
+}
+
+type ReconcileResult struct {
+	Drifts []Drift
+}
+
+// Unfixed returns drifts that remain after Reconcile, including fixable ones
+// when Apply was not set.
+func (r *ReconcileResult) Unfixed() []Drift {
+	out := []Drift{}
+	for _, drift := range r.Drifts {
+		if !drift.Fixed {
+			out = append(out, drift)
+		}
+	}
+	return out
+}
+
+// reconcileFix is one API call that fixes the drifts at the given indexes.
+type reconcileFix struct {
+	label  string
+	drifts []int
+	apply  func(ctx context.Context) error
+}
+
+type reconciler struct {
+	result *ReconcileResult
+	fixes  []reconcileFix
+}
+
+func (r *reconciler) report(kind, name, field, live, want, note string) int {
+	r.result.Drifts = append(r.result.Drifts, Drift{Kind: kind, Name: name, Field: field, Live: live, Want: want, Note: note})
+	return len(r.result.Drifts) - 1
+}
+
+func (r *reconciler) fix(label string, drifts []int, apply func(ctx context.Context) error) {
+	if len(drifts) == 0 {
+		return
+	}
+	r.fixes = append(r.fixes, reconcileFix{label: label, drifts: drifts, apply: apply})
+}
+
+// Reconcile compares the live cluster network, firewalls, placement policy
+// and instance tags, labels, metadata and scheduling against the profile and
+// reports each drift. With opts.Apply it fixes what can be changed without
+// restarting nodes; scheduling drift on running nodes is only reported.
+func (s *Service) Reconcile(ctx context.Context, clusterName string, opts ReconcileOptions) (*ReconcileResult, error) {
+	if !validate.IsResourceName(clusterName) {
+		return nil, fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.Start.NumInstances <= 0 {
+		return nil, fmt.Errorf("num-instances must be >= 1")
+	}
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := s.layoutNodes(clusterName, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	cloudInits, err := s.renderCloudInits(layout, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	r := &reconciler{result: &ReconcileResult{}}
+	if err := s.reconcileNetwork(ctx, r, clusterName, network, opts.Start); err != nil {
+		return nil, err
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	existing := instancesByName(instances)
+	for _, node := range layout {
+		inst := existing[node.Name]
+		if inst == nil {
+			r.report("instances", node.Name, "status", "missing", "present", "run `gpunow start` or `gpunow watch` to recreate")
+			continue
+		}
+		s.reconcileInstance(r, node, inst, cloudInits[node.Pool], opts.Start)
+	}
+
+	if !opts.Apply || len(r.fixes) == 0 {
+		return r.result, nil
+	}
+	taskNames := make([]string, 0, len(r.fixes))
+	for _, fix := range r.fixes {
+		taskNames = append(taskNames, fix.label)
+	}
+	progress := s.UI.TaskList("Reconciling", taskNames)
+	var errs []error
+	for i, fix := range r.fixes {
+		if err := fix.apply(ctx); err != nil {
+			progress.MarkWarning(i, fmt.Sprintf("Failed %s: %v", fix.label, err))
+			errs = append(errs, fmt.Errorf("%s: %w", fix.label, err))
+			continue
+		}
+		for _, index := range fix.drifts {
+			r.result.Drifts[index].Fixed = true
+		}
+		progress.MarkDone(i, fmt.Sprintf("Fixed %s", fix.label))
+	}
+	progress.Stop()
+	return r.result, errors.Join(errs...)
+}
+
+func (s *Service) reconcileNetwork(ctx context.Context, r *reconciler, clusterName string, network clusterNetwork, opts StartOptions) error {
+	project := network.Project
+	networkProject := network.NetworkProject
+
+	_, err := s.Compute.GetNetwork(ctx, &computepb.GetNetworkRequest{Project: networkProject, Network: network.NetworkName})
+	if err != nil {
+		if !gcp.IsNotFound(err) {
+			return err
+		}
+		if network.Existing {
+			r.report("networks", network.NetworkName, "status", "missing", "present", "existing networks are not created by gpunow")
+		} else {
+			drift := r.report("networks", network.NetworkName, "status", "missing", "present", "")
+			r.fix(fmt.Sprintf("network %s", network.NetworkName), []int{drift}, func(ctx context.Context) error {
+				return s.ensureNetwork(ctx, networkProject, network.NetworkName)
+			})
+		}
+	}
+
+	subnet, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: networkProject, Region: network.Region, Subnetwork: network.SubnetName})
+	switch {
+	case err == nil:
+		if !network.Existing && subnet.GetIpCidrRange() != network.SubnetCIDR {
+			r.report("subnetworks", network.SubnetName, "ipCidrRange", subnet.GetIpCidrRange(), network.SubnetCIDR, "subnet ranges cannot be changed in place")
+		}
+		if network.DualStack && subnet.GetStackType() != "IPV4_IPV6" {
+			if network.Existing {
+				r.report("subnetworks", network.SubnetName, "stackType", subnet.GetStackType(), "IPV4_IPV6", "existing subnetworks are not changed by gpunow")
+			} else {
+				drift := r.report("subnetworks", network.SubnetName, "stackType", subnet.GetStackType(), "IPV4_IPV6", "")
+				r.fix(fmt.Sprintf("subnetwork %s", network.SubnetName), []int{drift}, func(ctx context.Context) error {
+					return s.enableSubnetIPv6(ctx, network, subnet)
+				})
+			}
+		}
+	case gcp.IsNotFound(err) && network.Existing:
+		r.report("subnetworks", network.SubnetName, "status", "missing", "present", "existing subnetworks are not created by gpunow")
+	case gcp.IsNotFound(err):
+		drift := r.report("subnetworks", network.SubnetName, "status", "missing", "present", "")
+		r.fix(fmt.Sprintf("subnetwork %s", network.SubnetName), []int{drift}, func(ctx context.Context) error {
+			return s.ensureSubnetwork(ctx, network)
+		})
+	default:
+		return err
+	}
+
+	firewalls := s.clusterFirewalls(clusterName, network, opts)
+	for _, desired := range firewalls {
+		desired := desired
+		name := desired.GetName()
+		existing, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{Project: networkProject, Firewall: name})
+		drifts := []int{}
+		switch {
+		case err == nil:
+			for _, diff := range firewallDiffs(existing, desired) {
+				drifts = append(drifts, r.report("firewalls", name, diff.Field, diff.Old, diff.New, ""))
+			}
+		case gcp.IsNotFound(err):
+			drifts = append(drifts, r.report("firewalls", name, "status", "missing", "present", ""))
+		default:
+			return err
+		}
+		r.fix(fmt.Sprintf("firewall %s", name), drifts, func(ctx context.Context) error {
+			return s.ensureFirewall(ctx, networkProject, name, desired)
+		})
+	}
+	stale, err := s.staleFirewalls(ctx, network, firewalls)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		name := name
+		drift := r.report("firewalls", name, "status", "present", "absent", "")
+		r.fix(fmt.Sprintf("firewall %s", name), []int{drift}, func(ctx context.Context) error {
+			return s.deleteFirewall(ctx, networkProject, name)
+		})
+	}
+
+	if network.PlacementPolicy != "" {
+		_, err := s.Compute.GetResourcePolicy(ctx, &computepb.GetResourcePolicyRequest{Project: project, Region: network.Region, ResourcePolicy: network.PlacementPolicy})
+		if err != nil {
+			if !gcp.IsNotFound(err) {
+				return err
+			}
+			drift := r.report("resourcePolicies", network.PlacementPolicy, "status", "missing", "present", "")
+			r.fix(fmt.Sprintf("placement policy %s", network.PlacementPolicy), []int{drift}, func(ctx context.Context) error {
+				return s.ensurePlacementPolicy(ctx, network)
+			})
+		}
+	}
+
+	if network.RouterName != "" {
+		_, err := s.Compute.GetRouter(ctx, &computepb.GetRouterRequest{Project: networkProject, Region: network.Region, Router: network.RouterName})
+		if err != nil {
+			if !gcp.IsNotFound(err) {
+				return err
+			}
+			drift := r.report("routers", network.RouterName, "status", "missing", "present", "")
+			r.fix(fmt.Sprintf("router %s", network.RouterName), []int{drift}, func(ctx context.Context) error {
+				return s.ensureRouter(ctx, network)
+			})
+		}
+	}
+	return nil
+}
+
+func (s *Service) reconcileInstance(r *reconciler, node clusterNode, inst *computepb.Instance, cloudInit string, opts StartOptions) {
+	name := node.Name
+
+	tags := s.clusterTags(node.Cluster, node.Master())
+	if live, want := joinSorted(inst.GetTags().GetItems()), joinSorted(tags); live != want {
+		drift := r.report("instances", name, "tags", live, want, "")
+		r.fix(fmt.Sprintf("tags for %s", name), []int{drift}, func(ctx context.Context) error {
+			return s.setInstanceTags(ctx, inst, tags)
+		})
+	}
+
+	wantLabels := labels.EnsureManaged(withIdentity(node, opts.Labels))
+	labelDrifts := []int{}
+	for _, key := range sortedMapKeys(wantLabels) {
+		if live := inst.GetLabels()[key]; live != wantLabels[key] {
+			labelDrifts = append(labelDrifts, r.report("instances", name, "labels."+key, live, wantLabels[key], ""))
+		}
+	}
+	r.fix(fmt.Sprintf("labels for %s", name), labelDrifts, func(ctx context.Context) error {
+		return s.setInstanceLabels(ctx, inst, wantLabels)
+	})
+
+	wantMetadata := s.Builder.MetadataFor(instance.Options{Metadata: withIdentity(node, opts.Metadata)})
+	delete(wantMetadata, "ssh-keys")
+	wantMetadata["user-data"] = cloudInit
+	liveMetadata := map[string]string{}
+	for _, item := range inst.GetMetadata().GetItems() {
+		liveMetadata[item.GetKey()] = item.GetValue()
+	}
+	metadataDrifts := []int{}
+	for _, key := range sortedMapKeys(wantMetadata) {
+		if live := liveMetadata[key]; live != wantMetadata[key] {
+			metadataDrifts = append(metadataDrifts, r.report("instances", name, "metadata."+key, summarizeValue(live), summarizeValue(wantMetadata[key]), ""))
+		}
+	}
+	r.fix(fmt.Sprintf("metadata for %s", name), metadataDrifts, func(ctx context.Context) error {
+		return s.setInstanceMetadata(ctx, inst, wantMetadata)
+	})
+
+	want := s.Builder.SchedulingFor(instance.Options{
+		MaxRunHours:       opts.MaxRunHours,
+		TerminationAction: strings.ToUpper(strings.TrimSpace(opts.TerminationAction)),
+	})
+	note := ""
+	if inst.GetStatus() != "TERMINATED" {
+		note = "scheduling can only be changed while the node is stopped"
+	}
+	schedulingDrifts := []int{}
+	for _, diff := range schedulingDiffs(inst.GetScheduling(), want) {
+		schedulingDrifts = append(schedulingDrifts, r.report("instances", name, "scheduling."+diff.Field, diff.Old, diff.New, note))
+	}
+	if note == "" {
+		r.fix(fmt.Sprintf("scheduling for %s", name), schedulingDrifts, func(ctx context.Context) error {
+			return s.setInstanceScheduling(ctx, name, want)
+		})
+	}
+}
+
+// schedulingDiffs compares the scheduling fields the builder sets.
+func schedulingDiffs(live, want *computepb.Scheduling) []FieldDiff {
+	diffs := []FieldDiff{}
+	compare := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			diffs = append(diffs, FieldDiff{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	compare("provisioningModel", live.GetProvisioningModel(), want.GetProvisioningModel())
+	compare("onHostMaintenance", live.GetOnHostMaintenance(), want.GetOnHostMaintenance())
+	compare("instanceTerminationAction", live.GetInstanceTerminationAction(), want.GetInstanceTerminationAction())
+	compare("automaticRestart", strconv.FormatBool(live.GetAutomaticRestart()), strconv.FormatBool(want.GetAutomaticRestart()))
+	compare("maxRunDuration", formatDuration(live.GetMaxRunDuration()), formatDuration(want.GetMaxRunDuration()))
+	return diffs
+}
+
+func formatDuration(duration *computepb.Duration) string {
+	if duration == nil || duration.GetSeconds() == 0 {
+		return ""
+	}
+	seconds := duration.GetSeconds()
+	if seconds%3600 == 0 {
+		return fmt.Sprintf("%dh", seconds/3600)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// summarizeValue keeps long or multi-line metadata values (such as
+// user-data) readable in drift reports.
+func summarizeValue(value string) string {
+	if len(value) > 64 || strings.Contains(value, "\n") {
+		return fmt.Sprintf("<%d bytes>", len(value))
+	}
+	return value
+}
+
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reservedLabelKeys and reservedMetadataKeys are owned by gpunow and cannot
+// be set by users.
+var (
+	reservedLabelKeys    = []string{labels.ManagedKey, "cluster", "cluster_index", "cluster_role", "cluster_pool"}
+	reservedMetadataKeys = []string{"cluster", "cluster_index", "cluster_role", "cluster_pool", "user-data", "ssh-keys"}
+)
+
+func checkUserKeys(kind string, values map[string]string, reserved []string) error {
+	for key := range values {
+		if slices.Contains(reserved, key) {
+			return fmt.Errorf("%s %s is managed by gpunow", kind, key)
+		}
+	}
+	return nil
+}
+
+func (s *Service) setInstanceLabels(ctx context.Context, inst *computepb.Instance, want map[string]string) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	merged := map[string]string{}
+	for key, value := range inst.GetLabels() {
+		merged[key] = value
+	}
+	for key, value := range want {
+		merged[key] = value
+	}
+	call := s.api("compute.instances.setLabels", gcp.ZoneResource(project, zone, "instances", inst.GetName()), fmt.Sprintf("Updating labels for %s", inst.GetName()))
+	op, err := s.Compute.SetInstanceLabels(ctx, &computepb.SetLabelsInstanceRequest{
+		Project:  project,
+		Zone:     zone,
+		Instance: inst.GetName(),
+		InstancesSetLabelsRequestResource: &computepb.InstancesSetLabelsRequest{
+			Labels:           merged,
+			LabelFingerprint: proto.String(inst.GetLabelFingerprint()),
+		},
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+// setInstanceMetadata sets the wanted keys and keeps every other live item,
+// including ssh-keys.
+func (s *Service) setInstanceMetadata(ctx context.Context, inst *computepb.Instance, want map[string]string) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	items := []*computepb.Items{}
+	for _, item := range inst.GetMetadata().GetItems() {
+		if _, ok := want[item.GetKey()]; ok {
+			continue
+		}
+		items = append(items, item)
+	}
+	for _, key := range sortedMapKeys(want) {
+		items = append(items, &computepb.Items{Key: proto.String(key), Value: proto.String(want[key])})
+	}
+	call := s.api("compute.instances.setMetadata", gcp.ZoneResource(project, zone, "instances", inst.GetName()), fmt.Sprintf("Updating metadata for %s", inst.GetName()))
+	op, err := s.Compute.SetInstanceMetadata(ctx, &computepb.SetMetadataInstanceRequest{
+		Project:  project,
+		Zone:     zone,
+		Instance: inst.GetName(),
+		MetadataResource: &computepb.Metadata{
+			Items:       items,
+			Fingerprint: proto.String(inst.GetMetadata().GetFingerprint()),
+		},
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+func (s *Service) setInstanceScheduling(ctx context.Context, name string, scheduling *computepb.Scheduling) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	call := s.api("compute.instances.setScheduling", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Updating scheduling for %s", name))
+	op, err := s.Compute.SetInstanceScheduling(ctx, &computepb.SetSchedulingInstanceRequest{
+		Project:            project,
+		Zone:               zone,
+		Instance:           name,
+		SchedulingResource: scheduling,
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
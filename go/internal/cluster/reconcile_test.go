@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReconcileCleanCluster(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+	if _, err := service.Start(ctx, "clean", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	result, err := service.Reconcile(ctx, "clean", ReconcileOptions{Start: StartOptions{NumInstances: 2}})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(result.Drifts) != 0 {
+		t.Fatalf("expected no drift, got %+v", result.Drifts)
+	}
+}
+
+func TestReconcileApplyFixesDriftWithoutRestart(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	ctx := context.Background()
+	opts := StartOptions{NumInstances: 2}
+	if _, err := service.Start(ctx, "edited", opts); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	network, err := service.resolveClusterNetwork("edited")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	zone := service.Config.Project.Zone
+	compute.firewalls[network.PortsRule].SourceRanges = []string{"10.0.0.0/8"}
+	master := compute.get(zone, "edited-0")
+	master.Tags = &computepb.Tags{Items: []string{"manual"}}
+	worker := compute.get(zone, "edited-1")
+	worker.Labels["cluster_role"] = "master"
+	worker.Scheduling.MaxRunDuration = &computepb.Duration{Seconds: proto.Int64(3600)}
+	for _, item := range worker.Metadata.Items {
+		if item.GetKey() == "cluster_index" {
+			item.Value = proto.String("7")
+		}
+	}
+
+	result, err := service.Reconcile(ctx, "edited", ReconcileOptions{Start: opts, Apply: true})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	fields := map[string]bool{}
+	for _, drift := range result.Drifts {
+		fields[drift.Name+" "+drift.Field] = drift.Fixed
+	}
+	want := map[string]bool{
+		network.PortsRule + " sourceRanges":  true,
+		"edited-0 tags":                      true,
+		"edited-1 labels.cluster_role":       true,
+		"edited-1 metadata.cluster_index":    true,
+		"edited-1 scheduling.maxRunDuration": false,
+	}
+	for key, fixed := range want {
+		got, ok := fields[key]
+		if !ok || got != fixed {
+			t.Fatalf("drift %q: reported=%v fixed=%v, want fixed=%v (all: %v)", key, ok, got, fixed, fields)
+		}
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("unexpected drifts: %v", fields)
+	}
+	for _, call := range compute.Calls() {
+		if strings.HasPrefix(call, "start ") || strings.HasPrefix(call, "stop ") || strings.HasPrefix(call, "setScheduling ") {
+			t.Fatalf("reconcile must not restart running nodes: %v", compute.Calls())
+		}
+	}
+
+	result, err = service.Reconcile(ctx, "edited", ReconcileOptions{Start: opts})
+	if err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+	if len(result.Drifts) != 1 || result.Drifts[0].Note == "" {
+		t.Fatalf("expected only the scheduling drift to remain, got %+v", result.Drifts)
+	}
+}
@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/ui"
+	"gpunow/internal/validate"
+)
+
+type ReimageOptions struct {
+	// Start carries the node layout and the settings nodes are recreated
+	// with, exactly as Start would.
+	Start StartOptions
+	// Nodes limits the reimage to these instances; empty reimages every node.
+	Nodes []string
+	// BatchSize is the number of nodes recreated at a time; zero recreates
+	// every node at once.
+	BatchSize int
+}
+
+// Reimage deletes and recreates running nodes together with their boot
+// disks, so they come back with the current disk.image and cloud-init.
+// Nodes go in batches of opts.BatchSize, workers before the master, and a
+// batch must be ready before the next one starts. The first failure stops
+// the reimage; nodes of later batches are left untouched.
+func (s *Service) Reimage(ctx context.Context, clusterName string, opts ReimageOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.BatchSize < 0 {
+		return fmt.Errorf("batch size must be >= 1")
+	}
+	if opts.Start.NumInstances <= 0 {
+		return fmt.Errorf("num-instances must be >= 1")
+	}
+	layout, err := s.layoutNodes(clusterName, opts.Start)
+	if err != nil {
+		return err
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(opts.Nodes) > 0 {
+		if instances, err = selectInstances(clusterName, instances, opts.Nodes); err != nil {
+			return err
+		}
+	}
+	existing := instancesByName(instances)
+	nodes := []clusterNode{}
+	for _, node := range layout {
+		inst := existing[node.Name]
+		if inst == nil {
+			continue
+		}
+		// A recreated node comes back running; stopped and suspended nodes
+		// stay as they are.
+		if inst.GetStatus() != "RUNNING" {
+			s.UI.Warnf("Skipping %s (%s); start it to reimage it", node.Name, inst.GetStatus())
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		s.UI.Infof("No nodes of cluster %s to reimage", clusterName)
+		return nil
+	}
+	// The master keeps serving, and jumping to private workers, until last.
+	sort.SliceStable(nodes, func(i, j int) bool { return !nodes[i].Master() && nodes[j].Master() })
+	batchSize := opts.BatchSize
+	if batchSize == 0 || batchSize > len(nodes) {
+		batchSize = len(nodes)
+	}
+
+	split := s.UI.StartLiveSplit()
+	if split != nil {
+		defer split.Stop()
+	}
+	network, err := s.resolveClusterNetwork(clusterName)
+	if err != nil {
+		return err
+	}
+	cloudInits, err := s.renderCloudInits(nodes, opts.Start)
+	if err != nil {
+		return err
+	}
+	batches := (len(nodes) + batchSize - 1) / batchSize
+	for batch := 0; batch < batches; batch++ {
+		first := batch * batchSize
+		batchNodes := nodes[first:min(first+batchSize, len(nodes))]
+		taskNames := []string{}
+		for _, node := range batchNodes {
+			taskNames = append(taskNames, fmt.Sprintf("delete %s", node.Name), fmt.Sprintf("instance %s", node.Name))
+		}
+		label := "Reimaging"
+		if batches > 1 {
+			label = fmt.Sprintf("Reimaging (batch %d/%d)", batch+1, batches)
+		}
+		progress := s.UI.TaskList(label, taskNames)
+		// Each batch gets its own readiness deadline, which starts once the
+		// batch's first node is recreated.
+		readiness := s.newClusterReadiness(layout[0].Name, opts.Start, progress)
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i, node := range batchNodes {
+			group.Go(func() error {
+				return s.reimageNode(groupCtx, existing[node.Name], node, network, cloudInits[node.Pool], opts.Start, progress, 2*i, readiness)
+			})
+		}
+		err := group.Wait()
+		progress.Stop()
+		if err != nil {
+			return fmt.Errorf("reimage stopped with %d of %d nodes done: %w", first, len(nodes), err)
+		}
+	}
+	return nil
+}
+
+// reimageNode deletes inst along with its boot disk and creates the node
+// again. The delete uses row progressIndex and the new instance the row
+// after it.
+func (s *Service) reimageNode(ctx context.Context, inst *computepb.Instance, node clusterNode, network clusterNetwork, cloudInit string, opts StartOptions, progress *ui.TaskList, progressIndex int, readiness *clusterReadiness) error {
+	if err := s.deleteNode(ctx, inst, true, opts.OnStateChange, progress, progressIndex); err != nil {
+		return err
+	}
+	return s.startNode(ctx, node, network, cloudInit, opts, progress, progressIndex+1, readiness)
+}
@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReimageRecreatesNodesInBatchesMasterLast(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "fresh", StartOptions{NumInstances: 3}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	image := "projects/example/global/images/gpu-image-v2"
+	service.Config.Disk.Image = image
+	var mu sync.Mutex
+	images := map[string]string{}
+	opts := ReimageOptions{
+		Start: StartOptions{NumInstances: 3, OnImage: func(name, image string) {
+			mu.Lock()
+			defer mu.Unlock()
+			images[name] = image
+		}},
+		BatchSize: 2,
+	}
+	compute.calls = nil
+	if err := service.Reimage(context.Background(), "fresh", opts); err != nil {
+		t.Fatalf("reimage: %v", err)
+	}
+	// Workers go in the first batch; the master is recreated only after both
+	// are ready.
+	last := compute.calls[len(compute.calls)-2:]
+	if want := []string{"delete " + zone + "/fresh-0", "insert " + zone + "/fresh-0"}; !reflect.DeepEqual(last, want) {
+		t.Fatalf("expected the master to be reimaged last, got %v", compute.calls)
+	}
+	for _, name := range []string{"fresh-0", "fresh-1", "fresh-2"} {
+		inst := compute.get(zone, name)
+		if got := inst.GetDisks()[0].GetInitializeParams().GetSourceImage(); got != image {
+			t.Fatalf("%s boot disk image = %q", name, got)
+		}
+		if images[name] != image {
+			t.Fatalf("expected %s image to be reported, got %v", name, images)
+		}
+	}
+
+	compute.calls = nil
+	opts.Nodes = []string{"fresh-2"}
+	if err := service.Reimage(context.Background(), "fresh", opts); err != nil {
+		t.Fatalf("reimage fresh-2: %v", err)
+	}
+	if got := compute.Calls(); !reflect.DeepEqual(got, []string{"delete " + zone + "/fresh-2", "insert " + zone + "/fresh-2"}) {
+		t.Fatalf("calls = %v", got)
+	}
+}
+
+func TestReimageStopsAtFirstFailedBatchAndSkipsStoppedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "roll", StartOptions{NumInstances: 4}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := service.Stop(context.Background(), "roll", StopOptions{Nodes: []string{"roll-3"}}); err != nil {
+		t.Fatalf("stop roll-3: %v", err)
+	}
+
+	var probes atomic.Int32
+	service.probe = func(ctx context.Context, probeURL string, onState func(string)) (string, error) {
+		if probes.Add(1) == 1 {
+			return "error", nil
+		}
+		return "ready", nil
+	}
+	compute.calls = nil
+	err := service.Reimage(context.Background(), "roll", ReimageOptions{Start: StartOptions{NumInstances: 4}, BatchSize: 1})
+	if err == nil || !strings.Contains(err.Error(), "0 of 3 nodes done") {
+		t.Fatalf("expected the first batch to stop the reimage, got %v", err)
+	}
+	if want := []string{"delete " + zone + "/roll-1", "insert " + zone + "/roll-1"}; !reflect.DeepEqual(compute.Calls(), want) {
+		t.Fatalf("expected only the first batch to run, got %v", compute.Calls())
+	}
+	if got := compute.Status(zone, "roll-3"); got != "TERMINATED" {
+		t.Fatalf("expected the stopped node to stay stopped, got %s", got)
+	}
+}
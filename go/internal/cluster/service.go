@@ -3,23 +3,23 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/iterator"
 	"google.golang.org/protobuf/proto"
 
-	"gpunow/internal/cloudinit"
 	"gpunow/internal/config"
 	"gpunow/internal/gcp"
 	"gpunow/internal/instance"
 	"gpunow/internal/lifecycle"
+	"gpunow/internal/parse"
 	"gpunow/internal/ssh"
 	"gpunow/internal/ui"
 	"gpunow/internal/validate"
@@ -31,10 +31,19 @@ type Service struct {
 	Builder *instance.Builder
 	UI      *ui.UI
 	Logger  *zap.Logger
+
+	// probe overrides the readiness sentinel probe; nil uses HTTP.
+	probe readinessProbe
+	// runSSH overrides running ssh with the given arguments and returns its
+	// output; nil runs the ssh binary.
+	runSSH func(ctx context.Context, args []string) (string, error)
 }
 
 type StartOptions struct {
-	NumInstances      int
+	NumInstances int
+	// Pools lays the cluster out in named node pools; NumInstances must then
+	// be the sum of their counts. Empty names nodes <cluster>-<index>.
+	Pools             []Pool
 	SSHUser           string
 	SSHPublicKey      string
 	MachineType       string
@@ -43,7 +52,83 @@ type StartOptions struct {
 	DiskSizeGB        int
 	KeepDisks         bool
 	ReadinessTimeout  time.Duration
-	OnStateChange     func(name, state, externalIP, internalIP string)
+	// SSHIdentityFile is used to probe private workers through the master;
+	// empty uses the ssh defaults.
+	SSHIdentityFile string
+	// SSHSourceRanges and PortSourceRanges override network.ssh_source_ranges
+	// and network.port_source_ranges for this cluster when set.
+	SSHSourceRanges  []string
+	PortSourceRanges []string
+	// Ports replaces network.ports for this cluster when set.
+	Ports []parse.PortSpec
+	// SubnetCIDR pins the per-cluster subnet range. Empty keeps the range of
+	// the live subnet, or allocates a free block of cluster.subnet_cidr_base.
+	SubnetCIDR string
+	// ReservedSubnetCIDRs are ranges held by other clusters (e.g. recorded in
+	// state) that allocation avoids besides the live gpunow subnets.
+	ReservedSubnetCIDRs []string
+	// MinReady enables partial-success mode: node failures no longer cancel
+	// the rest of the cluster, and Start succeeds when at least MinReady
+	// nodes reach READY.
+	MinReady int
+	// DeleteFailed deletes nodes that failed to reach READY (requires MinReady).
+	DeleteFailed bool
+	// Zones lists candidate zones in order; empty uses project.zone followed
+	// by project.fallback_zones.
+	Zones []string
+	// Labels and Metadata are added to every node; the node identity keys
+	// always win.
+	Labels        map[string]string
+	Metadata      map[string]string
+	OnStateChange func(name, state, externalIP, internalIP string)
+	// OnImage is called with the source image of every boot disk a node is
+	// created with; reused disks are not reported.
+	OnImage func(name, image string)
+}
+
+// StartResult reports the outcome of every node Start attempted.
+type StartResult struct {
+	// Zone is the zone the cluster was started in.
+	Zone string
+	// SubnetCIDR is the range of the cluster subnet; empty on an existing
+	// network.
+	SubnetCIDR string
+	Nodes      []NodeResult
+}
+
+type NodeResult struct {
+	Name    string
+	Index   int
+	State   string
+	Elapsed time.Duration
+	Err     error
+	Deleted bool
+}
+
+func (r *StartResult) ReadyCount() int {
+	if r == nil {
+		return 0
+	}
+	count := 0
+	for _, node := range r.Nodes {
+		if node.State == lifecycle.InstanceStateReady {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *StartResult) Failed() []NodeResult {
+	if r == nil {
+		return nil
+	}
+	failed := []NodeResult{}
+	for _, node := range r.Nodes {
+		if node.Err != nil {
+			failed = append(failed, node)
+		}
+	}
+	return failed
 }
 
 type StopOptions struct {
@@ -51,10 +136,38 @@ type StopOptions struct {
 	KeepDisks     bool
 	DeleteDisks   bool
 	OnStateChange func(name, state, externalIP, internalIP string)
+	// Force stops the cluster even when a hooks.pre_stop command fails.
+	Force bool
+	// SSHUser, SSHPublicKey and SSHIdentityFile reach nodes to run
+	// hooks.pre_stop.
+	SSHUser         string
+	SSHPublicKey    string
+	SSHIdentityFile string
+	// Nodes limits the stop to these instances. Deleting them leaves the
+	// cluster network, firewalls and placement policy in place.
+	Nodes []string
 }
 
 type UpdateOptions struct {
-	MaxRunHours int
+	// MaxRunHours and TerminationAction set the scheduling of stopped
+	// nodes; when only one is given the other falls back to the profile.
+	MaxRunHours       int
+	TerminationAction string
+	// MachineType resizes stopped nodes.
+	MachineType string
+	// Labels and Metadata are merged into the live labels and metadata.
+	Labels   map[string]string
+	Metadata map[string]string
+	// Nodes limits the update to these instances.
+	Nodes []string
+}
+
+func (o UpdateOptions) needsStop() bool {
+	return o.MaxRunHours > 0 || strings.TrimSpace(o.TerminationAction) != "" || strings.TrimSpace(o.MachineType) != ""
+}
+
+func (o UpdateOptions) empty() bool {
+	return !o.needsStop() && len(o.Labels) == 0 && len(o.Metadata) == 0
 }
 
 func NewService(compute gcp.Compute, cfg *config.Config, uiPrinter *ui.UI, logger *zap.Logger) *Service {
@@ -67,261 +180,448 @@ func NewService(compute gcp.Compute, cfg *config.Config, uiPrinter *ui.UI, logge
 	}
 }
 
-func (s *Service) Start(ctx context.Context, clusterName string, opts StartOptions) error {
+// Start creates or starts every node of the cluster and waits for them to
+// become ready. By default the first node failure cancels the others; with
+// opts.MinReady set each node's outcome is collected independently. When a
+// fresh cluster hits a zone stockout, the whole cluster is retried in the
+// next candidate zone. The returned result is populated whenever nodes were
+// attempted, including when an error is returned.
+func (s *Service) Start(ctx context.Context, clusterName string, opts StartOptions) (*StartResult, error) {
 	if !validate.IsResourceName(clusterName) {
-		return fmt.Errorf("invalid cluster name: %s", clusterName)
+		return nil, fmt.Errorf("invalid cluster name: %s", clusterName)
 	}
 	if opts.NumInstances <= 0 {
-		return fmt.Errorf("num-instances must be >= 1")
+		return nil, fmt.Errorf("num-instances must be >= 1")
+	}
+	if opts.MinReady < 0 || opts.MinReady > opts.NumInstances {
+		return nil, fmt.Errorf("min-ready must be between 1 and num-instances (%d)", opts.NumInstances)
+	}
+	if opts.DeleteFailed && opts.MinReady == 0 {
+		return nil, fmt.Errorf("--delete-failed requires --min-ready")
+	}
+	if s.Config.Cluster.PrivateWorkers && opts.NumInstances > 1 && strings.TrimSpace(opts.SSHUser) == "" {
+		return nil, fmt.Errorf("cluster.private_workers probes workers through the master over SSH; set ssh.default_user")
 	}
 
+	zones := opts.Zones
+	if len(zones) == 0 {
+		zones = s.Config.Zones()
+	}
+	zones, pinned, err := s.pinClusterZone(ctx, clusterName, zones)
+	if err != nil {
+		return nil, err
+	}
+	var result *StartResult
+	for i, zone := range zones {
+		zoneService := s.inZone(zone)
+		result, err = zoneService.startInZone(ctx, clusterName, opts)
+		if result != nil {
+			result.Zone = zone
+		}
+		if err == nil {
+			return result, nil
+		}
+		// Only a cluster that had nothing in any candidate zone moves on;
+		// existing nodes (and their disks) pin the cluster to their zone.
+		if !startExhausted(result, err) || pinned || i == len(zones)-1 {
+			return result, err
+		}
+		next := zones[i+1]
+		s.UI.Warnf("Zone %s is out of capacity for cluster %s; retrying in %s", zone, clusterName, next)
+		if cleanupErr := zoneService.abandonZone(ctx, clusterName, next, opts); cleanupErr != nil {
+			return result, fmt.Errorf("%w (cleanup in %s failed: %v)", err, zone, cleanupErr)
+		}
+	}
+	return result, err
+}
+
+// startInZone runs one start attempt in the service's configured zone.
+func (s *Service) startInZone(ctx context.Context, clusterName string, opts StartOptions) (*StartResult, error) {
 	split := s.UI.StartLiveSplit()
 	if split != nil {
 		defer split.Stop()
 	}
-	project := s.Config.Project.ID
-	zone := s.Config.Project.Zone
-	region, err := gcp.RegionFromZone(zone)
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	networkName := s.clusterNetworkName(clusterName)
-	subnetName := s.clusterSubnetName(networkName)
-	networkURL := gcp.GlobalResource(project, "networks", networkName)
-	subnetURL := gcp.RegionResource(project, region, "subnetworks", subnetName)
-
-	subnetCIDR, err := DeriveSubnetCIDR(s.Config.Cluster.SubnetCIDRBase, s.Config.Cluster.SubnetPrefix, clusterName)
+	nodes, err := s.layoutNodes(clusterName, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	internalRule := fmt.Sprintf("%s-internal", networkName)
-	sshRule := fmt.Sprintf("%s-ssh", networkName)
-	portsRule := fmt.Sprintf("%s-ports", networkName)
-
-	cloudInit, err := cloudinit.Render(s.Config.Paths.CloudInitFile, s.Config.Paths.SetupScript, s.Config.Paths.ZshrcFile)
+	cloudInits, err := s.renderCloudInits(nodes, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	instanceNames := make([]string, 0, opts.NumInstances)
-	for i := 0; i < opts.NumInstances; i++ {
-		instanceNames = append(instanceNames, s.instanceName(clusterName, i))
-	}
-	resourceTasks := []string{
-		fmt.Sprintf("network %s", networkName),
-		fmt.Sprintf("subnetwork %s", subnetName),
-		fmt.Sprintf("firewall %s", internalRule),
-		fmt.Sprintf("firewall %s", sshRule),
-		fmt.Sprintf("firewall %s", portsRule),
-	}
+	resourceTasks := network.taskNames()
 	taskNames := append([]string{}, resourceTasks...)
-	for _, name := range instanceNames {
-		taskNames = append(taskNames, fmt.Sprintf("instance %s", name))
+	for _, node := range nodes {
+		taskNames = append(taskNames, fmt.Sprintf("instance %s", node.Name))
 	}
 	progress := s.UI.TaskList("Creating", taskNames)
 	resourceTaskCount := len(resourceTasks)
 
-	if err := s.ensureNetwork(ctx, project, networkName); err != nil {
+	if err := s.ensureClusterNetwork(ctx, clusterName, network, opts, progress); err != nil {
 		progress.Stop()
-		return err
+		return nil, err
 	}
-	progress.MarkDone(0, fmt.Sprintf("Ready networks/%s", networkName))
 
-	if err := s.ensureSubnetwork(ctx, project, region, subnetName, subnetCIDR, networkURL); err != nil {
+	partial := opts.MinReady > 0
+	result := &StartResult{SubnetCIDR: network.SubnetCIDR, Nodes: make([]NodeResult, len(nodes))}
+	group, groupCtx := errgroup.WithContext(ctx)
+	readiness := s.newClusterReadiness(nodes[0].Name, opts, progress)
+	for i, node := range nodes {
+		i, node := i, node
+		progressIndex := resourceTaskCount + i
+		group.Go(func() error {
+			started := time.Now()
+			err := s.startNode(groupCtx, node, network, cloudInits[node.Pool], opts, progress, progressIndex, readiness)
+			nodeResult := NodeResult{
+				Name:    node.Name,
+				Index:   node.Index,
+				State:   lifecycle.InstanceStateReady,
+				Elapsed: time.Since(started),
+				Err:     err,
+			}
+			if err != nil {
+				nodeResult.State = lifecycle.InstanceStateFailed
+			}
+			result.Nodes[i] = nodeResult
+			if err != nil && partial {
+				progress.MarkWarning(progressIndex, fmt.Sprintf("Failed %s: %v", node.Name, err))
+				return nil
+			}
+			return err
+		})
+	}
+
+	if err := group.Wait(); err != nil {
 		progress.Stop()
-		return err
+		return result, err
 	}
-	progress.MarkDone(1, fmt.Sprintf("Ready subnetworks/%s", subnetName))
+	progress.Stop()
 
-	network := gcp.GlobalResource(project, "networks", networkName)
-	clusterTag := s.clusterTag(clusterName)
-	internalFirewall := &computepb.Firewall{
-		Name:         proto.String(internalRule),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{subnetCIDR},
-		Allowed: []*computepb.Allowed{
-			{IPProtocol: proto.String("tcp"), Ports: []string{"0-65535"}},
-			{IPProtocol: proto.String("udp"), Ports: []string{"0-65535"}},
-			{IPProtocol: proto.String("icmp")},
-		},
+	if opts.DeleteFailed {
+		s.deleteFailedNodes(ctx, nodes, result, opts)
 	}
-	sshFirewall := &computepb.Firewall{
-		Name:         proto.String(sshRule),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{"0.0.0.0/0"},
-		Allowed: []*computepb.Allowed{{
-			IPProtocol: proto.String("tcp"),
-			Ports:      []string{"22"},
-		}},
+	if partial {
+		if ready := result.ReadyCount(); ready < opts.MinReady {
+			return result, fmt.Errorf("only %d of %d nodes reached READY (min-ready %d)", ready, len(nodes), opts.MinReady)
+		}
 	}
-	portsFirewall := &computepb.Firewall{
-		Name:         proto.String(portsRule),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{"0.0.0.0/0"},
-		Allowed: []*computepb.Allowed{{
-			IPProtocol: proto.String("tcp"),
-			Ports:      portsToStringsWithReadiness(s.Config.Network.Ports),
-		}},
+	return result, nil
+}
+
+// deleteFailedNodes deletes every node in result that failed to become ready,
+// marking each deleted entry. Instances of another cluster that share a
+// node's name are left alone. Delete errors are logged and reported as
+// warnings; they never fail the start.
+func (s *Service) deleteFailedNodes(ctx context.Context, nodes []clusterNode, result *StartResult, opts StartOptions) {
+	failed := []int{}
+	taskNames := []string{}
+	for i, node := range result.Nodes {
+		if node.Err != nil {
+			failed = append(failed, i)
+			taskNames = append(taskNames, fmt.Sprintf("instance %s", node.Name))
+		}
 	}
-	if err := s.ensureFirewall(ctx, project, internalRule, internalFirewall); err != nil {
-		progress.Stop()
+	if len(failed) == 0 {
+		return
+	}
+	progress := s.UI.TaskList("Deleting failed", taskNames)
+	group, groupCtx := errgroup.WithContext(ctx)
+	for progressIndex, resultIndex := range failed {
+		progressIndex, resultIndex := progressIndex, resultIndex
+		name := result.Nodes[resultIndex].Name
+		group.Go(func() error {
+			inst, err := s.getInstance(groupCtx, name)
+			if err == nil && inst == nil {
+				progress.MarkDone(progressIndex, fmt.Sprintf("Already deleted %s", name))
+				result.Nodes[resultIndex].Deleted = true
+				return nil
+			}
+			if err == nil {
+				err = nodes[resultIndex].claims(inst)
+			}
+			if err == nil {
+				err = s.deleteNode(groupCtx, inst, !opts.KeepDisks, opts.OnStateChange, progress, progressIndex)
+			}
+			if err != nil {
+				s.Logger.Warn("failed to delete failed node", zap.String("instance", name), zap.Error(err))
+				progress.MarkWarning(progressIndex, fmt.Sprintf("Failed to delete %s: %v", name, err))
+				return nil
+			}
+			result.Nodes[resultIndex].Deleted = true
+			return nil
+		})
+	}
+	_ = group.Wait()
+	progress.Stop()
+}
+
+// Scale grows or shrinks a cluster to the layout in opts. Missing nodes are
+// created with the same settings Start uses; nodes outside the layout are
+// deleted, highest index first. The master is always part of the layout and
+// is never removed.
+func (s *Service) Scale(ctx context.Context, clusterName string, opts StartOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.NumInstances <= 0 {
+		return fmt.Errorf("num-instances must be >= 1")
+	}
+	layout, err := s.layoutNodes(clusterName, opts)
+	if err != nil {
 		return err
 	}
-	progress.MarkDone(2, fmt.Sprintf("Ready firewalls/%s", internalRule))
-	if err := s.ensureFirewall(ctx, project, sshRule, sshFirewall); err != nil {
-		progress.Stop()
+
+	split := s.UI.StartLiveSplit()
+	if split != nil {
+		defer split.Stop()
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
 		return err
 	}
-	progress.MarkDone(3, fmt.Sprintf("Ready firewalls/%s", sshRule))
-	if err := s.ensureFirewall(ctx, project, portsRule, portsFirewall); err != nil {
-		progress.Stop()
+	existing := instancesByName(instances)
+	wanted := map[string]bool{}
+	nodes := []clusterNode{}
+	for _, node := range layout {
+		wanted[node.Name] = true
+		if existing[node.Name] == nil {
+			nodes = append(nodes, node)
+		}
+	}
+	deleteInstances := []*computepb.Instance{}
+	for _, inst := range instances {
+		if !wanted[inst.GetName()] && instanceIndex(clusterName, inst) >= 0 {
+			deleteInstances = append(deleteInstances, inst)
+		}
+	}
+	sort.Slice(deleteInstances, func(i, j int) bool {
+		left, right := deleteInstances[i], deleteInstances[j]
+		if left.GetLabels()["cluster_pool"] != right.GetLabels()["cluster_pool"] {
+			return left.GetLabels()["cluster_pool"] < right.GetLabels()["cluster_pool"]
+		}
+		return instanceIndex(clusterName, left) > instanceIndex(clusterName, right)
+	})
+	if len(nodes) == 0 && len(deleteInstances) == 0 {
+		if split != nil {
+			split.Stop()
+		}
+		s.UI.Infof("Cluster %s already has %d instances", clusterName, len(layout))
+		return nil
+	}
+
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts)
+	if err != nil {
 		return err
 	}
-	progress.MarkDone(4, fmt.Sprintf("Ready firewalls/%s", portsRule))
+	taskNames := []string{}
+	if len(nodes) > 0 {
+		taskNames = append(taskNames, network.taskNames()...)
+	}
+	resourceTaskCount := len(taskNames)
+	for _, node := range nodes {
+		taskNames = append(taskNames, fmt.Sprintf("instance %s", node.Name))
+	}
+	deleteBase := len(taskNames)
+	for _, inst := range deleteInstances {
+		taskNames = append(taskNames, fmt.Sprintf("instance %s", inst.GetName()))
+	}
+	progress := s.UI.TaskList("Scaling", taskNames)
 
 	group, groupCtx := errgroup.WithContext(ctx)
-	var readinessMu sync.Mutex
-	waitReady := func(instanceName, externalIP string) error {
-		readinessMu.Lock()
-		defer readinessMu.Unlock()
-		return s.WaitForReady(groupCtx, instanceName, externalIP, opts.ReadinessTimeout)
-	}
-	for i := 0; i < opts.NumInstances; i++ {
-		name := s.instanceName(clusterName, i)
-		clusterIndex := i
-		progressIndex := resourceTaskCount + i
-		role := "worker"
-		publicIP := true
-		if i == 0 {
-			role = "master"
+	if len(nodes) > 0 {
+		cloudInits, err := s.renderCloudInits(nodes, opts)
+		if err != nil {
+			progress.Stop()
+			return err
+		}
+		if err := s.ensureClusterNetwork(ctx, clusterName, network, opts, progress); err != nil {
+			progress.Stop()
+			return err
 		}
+		readiness := s.newClusterReadiness(layout[0].Name, opts, progress)
+		for i, node := range nodes {
+			node := node
+			progressIndex := resourceTaskCount + i
+			group.Go(func() error {
+				return s.startNode(groupCtx, node, network, cloudInits[node.Pool], opts, progress, progressIndex, readiness)
+			})
+		}
+	}
+	for i, inst := range deleteInstances {
+		inst := inst
+		progressIndex := deleteBase + i
 		group.Go(func() error {
-			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateStarting, "", "")
-			labels := map[string]string{
-				"cluster":       clusterName,
-				"cluster_index": strconv.Itoa(clusterIndex),
-				"cluster_role":  role,
-			}
-			metadata := map[string]string{
-				"cluster":       clusterName,
-				"cluster_index": strconv.Itoa(clusterIndex),
-				"cluster_role":  role,
-			}
-			if opts.SSHUser != "" && opts.SSHPublicKey != "" {
-				metadata["ssh-keys"] = fmt.Sprintf("%s:%s", opts.SSHUser, opts.SSHPublicKey)
-			}
-			tags := s.clusterTags(clusterName, role == "master")
+			return s.deleteNode(groupCtx, inst, !opts.KeepDisks, opts.OnStateChange, progress, progressIndex)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		progress.Stop()
+		return err
+	}
+	progress.Stop()
+	return nil
+}
 
-			instanceObj, err := s.getInstance(groupCtx, name)
-			if err != nil {
-				return err
-			}
-			if instanceObj != nil {
-				if err := s.ensureInstanceTags(groupCtx, instanceObj, tags); err != nil {
-					return err
-				}
-				if opts.SSHUser != "" && opts.SSHPublicKey != "" {
-					if err := s.ensureInstanceSSHKey(groupCtx, name, opts.SSHUser, opts.SSHPublicKey); err != nil {
-						return err
-					}
-				}
-				if instanceObj.GetStatus() == "RUNNING" {
-					externalIP, internalIP := instanceIPs(instanceObj)
-					s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
-					if err := waitReady(name, externalIP); err != nil {
-						return err
-					}
-					s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
-					progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
-					return nil
-				}
-				call := s.api("compute.instances.start", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Starting %s", name))
-				op, err := s.Compute.StartInstance(groupCtx, &computepb.StartInstanceRequest{
-					Project:  project,
-					Zone:     zone,
-					Instance: name,
-				})
-				if err != nil {
-					call.Stop()
-					return err
-				}
-				if err := s.waitWithProgress(groupCtx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
-					return err
-				}
-				refreshed, err := s.getInstance(groupCtx, name)
-				if err != nil {
-					return err
-				}
-				externalIP, internalIP := instanceIPs(refreshed)
-				s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
-				if err := waitReady(name, externalIP); err != nil {
-					return err
-				}
-				s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
-				progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
-				return nil
-			}
+// startNode brings a single cluster node to READY: it starts the instance when
+// it already exists, or creates it otherwise with its pool's settings, then
+// waits for readiness.
+func (s *Service) startNode(ctx context.Context, node clusterNode, network clusterNetwork, cloudInit string, opts StartOptions, progress *ui.TaskList, progressIndex int, readiness *clusterReadiness) error {
+	opts = s.nodeOptions(node, opts)
+	project := network.Project
+	zone := network.Zone
+	name := node.Name
 
-			instanceReq, err := s.Builder.Build(groupCtx, s.Compute, instance.Options{
-				Name:              name,
-				Network:           networkURL,
-				Subnetwork:        subnetURL,
-				PublicIP:          publicIP,
-				Tags:              tags,
-				CloudInit:         cloudInit,
-				Labels:            labels,
-				Metadata:          metadata,
-				MachineType:       strings.TrimSpace(opts.MachineType),
-				MaxRunHours:       opts.MaxRunHours,
-				TerminationAction: strings.ToUpper(strings.TrimSpace(opts.TerminationAction)),
-				DiskSizeGB:        opts.DiskSizeGB,
-				DiskAutoDelete:    diskAutoDeleteOverride(opts.KeepDisks),
-			})
-			if err != nil {
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateStarting, "", "")
+	labels := withIdentity(node, opts.Labels)
+	metadata := withIdentity(node, opts.Metadata)
+	if opts.SSHUser != "" && opts.SSHPublicKey != "" {
+		metadata["ssh-keys"] = fmt.Sprintf("%s:%s", opts.SSHUser, opts.SSHPublicKey)
+	}
+	tags := s.clusterTags(node.Cluster, node.Master())
+
+	instanceObj, err := s.getInstance(ctx, name)
+	if err != nil {
+		return err
+	}
+	if instanceObj != nil {
+		if err := node.claims(instanceObj); err != nil {
+			return err
+		}
+		if err := s.ensureInstanceTags(ctx, instanceObj, tags); err != nil {
+			return err
+		}
+		if opts.SSHUser != "" && opts.SSHPublicKey != "" {
+			if err := s.ensureInstanceSSHKey(ctx, name, opts.SSHUser, opts.SSHPublicKey); err != nil {
 				return err
 			}
-			call := s.api("compute.instances.insert", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Creating %s", name))
-			op, err := s.Compute.InsertInstance(groupCtx, instanceReq)
-			if err != nil {
-				call.Stop()
+		}
+		if instanceObj.GetStatus() == "RUNNING" {
+			externalIP, internalIP := instanceIPs(instanceObj)
+			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
+			if err := readiness.Wait(ctx, name, externalIP, internalIP, progressIndex); err != nil {
 				return err
 			}
-			if err := s.waitWithProgress(groupCtx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+			progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
+			return nil
+		}
+		// A suspended node cannot be started; resuming it keeps its memory.
+		if instanceObj.GetStatus() == "SUSPENDED" {
+			if err := s.resumeInstance(ctx, name, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
 				return err
 			}
-			if opts.SSHUser != "" && opts.SSHPublicKey != "" {
-				if err := s.ensureInstanceSSHKey(groupCtx, name, opts.SSHUser, opts.SSHPublicKey); err != nil {
-					return err
-				}
-			}
-			refreshed, err := s.getInstance(groupCtx, name)
+		} else {
+			call := s.api("compute.instances.start", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Starting %s", name))
+			op, err := s.Compute.StartInstance(ctx, &computepb.StartInstanceRequest{
+				Project:  project,
+				Zone:     zone,
+				Instance: name,
+			})
 			if err != nil {
+				call.Stop()
 				return err
 			}
-			externalIP, internalIP := instanceIPs(refreshed)
-			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
-			if err := waitReady(name, externalIP); err != nil {
+			if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
 				return err
 			}
-			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
-			progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
-			return nil
-		})
+		}
+		refreshed, err := s.getInstance(ctx, name)
+		if err != nil {
+			return err
+		}
+		externalIP, internalIP := instanceIPs(refreshed)
+		s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
+		if err := readiness.Wait(ctx, name, externalIP, internalIP, progressIndex); err != nil {
+			return err
+		}
+		s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+		progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
+		return nil
 	}
 
-	if err := group.Wait(); err != nil {
-		progress.Stop()
+	instanceReq, err := s.Builder.Build(ctx, s.Compute, instance.Options{
+		Name:              name,
+		Network:           network.NetworkURL,
+		Subnetwork:        network.SubnetURL,
+		PublicIP:          node.Master() || !s.Config.Cluster.PrivateWorkers,
+		Tags:              tags,
+		CloudInit:         cloudInit,
+		Labels:            labels,
+		Metadata:          metadata,
+		MachineType:       strings.TrimSpace(opts.MachineType),
+		MaxRunHours:       opts.MaxRunHours,
+		TerminationAction: strings.ToUpper(strings.TrimSpace(opts.TerminationAction)),
+		DiskSizeGB:        opts.DiskSizeGB,
+		DiskAutoDelete:    diskAutoDeleteOverride(opts.KeepDisks),
+		ResourcePolicies:  network.resourcePolicies(),
+		DatasetDisk:       network.DatasetURL,
+	})
+	if err != nil {
 		return err
 	}
-	progress.Stop()
+	call := s.api("compute.instances.insert", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Creating %s", name))
+	op, err := s.Compute.InsertInstance(ctx, instanceReq)
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+		return err
+	}
+	if params := instanceReq.GetInstanceResource().GetDisks()[0].GetInitializeParams(); params != nil && opts.OnImage != nil {
+		opts.OnImage(name, params.GetSourceImage())
+	}
+	if opts.SSHUser != "" && opts.SSHPublicKey != "" {
+		if err := s.ensureInstanceSSHKey(ctx, name, opts.SSHUser, opts.SSHPublicKey); err != nil {
+			return err
+		}
+	}
+	refreshed, err := s.getInstance(ctx, name)
+	if err != nil {
+		return err
+	}
+	externalIP, internalIP := instanceIPs(refreshed)
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateProvisioning, externalIP, internalIP)
+	if err := readiness.Wait(ctx, name, externalIP, internalIP, progressIndex); err != nil {
+		return err
+	}
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+	progress.MarkDone(progressIndex, fmt.Sprintf("Ready %s", name))
+	return nil
+}
+
+// deleteNode deletes one cluster instance, first updating the auto-delete
+// flag on its disks so they are kept or removed as requested.
+func (s *Service) deleteNode(ctx context.Context, inst *computepb.Instance, autoDelete bool, onStateChange func(name, state, externalIP, internalIP string), progress *ui.TaskList, progressIndex int) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	name := inst.GetName()
+
+	s.updateInstanceState(onStateChange, name, lifecycle.InstanceStateTerminating, "", "")
+	if err := s.setAutoDelete(ctx, name, inst, autoDelete); err != nil {
+		return err
+	}
+	call := s.api("compute.instances.delete", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Deleting %s", name))
+	op, err := s.Compute.DeleteInstance(ctx, &computepb.DeleteInstanceRequest{
+		Project:  project,
+		Zone:     zone,
+		Instance: name,
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+		return err
+	}
+	s.updateInstanceState(onStateChange, name, lifecycle.InstanceStateTerminated, "", "")
+	progress.MarkDone(progressIndex, fmt.Sprintf("Deleted %s", name))
 	return nil
 }
 
@@ -349,6 +649,21 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 		return nil
 	}
 
+	masterIP := clusterMasterIP(instances)
+	if len(opts.Nodes) > 0 {
+		if instances, err = selectInstances(clusterName, instances, opts.Nodes); err != nil {
+			return err
+		}
+		if opts.Delete && slices.ContainsFunc(instances, isMaster) {
+			return fmt.Errorf("the master of cluster %s can only be deleted with the whole cluster", clusterName)
+		}
+	}
+	deleteCluster := opts.Delete && len(opts.Nodes) == 0
+
+	if err := s.runPreStopHooks(ctx, instances, masterIP, opts); err != nil {
+		return err
+	}
+
 	project := s.Config.Project.ID
 	zone := s.Config.Project.Zone
 	region, err := gcp.RegionFromZone(zone)
@@ -357,18 +672,42 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 	}
 
 	label := "Stopping instance"
-	networkName := s.clusterNetworkName(clusterName)
-	subnetName := s.clusterSubnetName(networkName)
+	network, err := s.resolveClusterNetwork(clusterName)
+	if err != nil {
+		return err
+	}
+	networkProject := network.NetworkProject
+	networkName := network.NetworkName
+	subnetName := network.SubnetName
+	placementPolicy := network.PlacementPolicy
+	router := network.RouterName
 	extraTasks := []string{}
+	firewalls := []string{}
 	if opts.Delete {
 		label = "Deleting"
-		extraTasks = append(extraTasks,
-			fmt.Sprintf("firewalls/%s-internal", networkName),
-			fmt.Sprintf("firewalls/%s-ssh", networkName),
-			fmt.Sprintf("firewalls/%s-ports", networkName),
-			fmt.Sprintf("subnetworks/%s", subnetName),
-			fmt.Sprintf("networks/%s", networkName),
-		)
+	}
+	if deleteCluster {
+		firewalls, err = s.clusterFirewallNames(ctx, network)
+		if err != nil {
+			return err
+		}
+		for _, rule := range firewalls {
+			extraTasks = append(extraTasks, fmt.Sprintf("firewalls/%s", rule))
+		}
+		if router != "" {
+			extraTasks = append(extraTasks, fmt.Sprintf("routers/%s", router))
+		}
+		// An existing network belongs to someone else; only the cluster's
+		// own rules are removed from it.
+		if !network.Existing {
+			extraTasks = append(extraTasks,
+				fmt.Sprintf("subnetworks/%s", subnetName),
+				fmt.Sprintf("networks/%s", networkName),
+			)
+		}
+		if placementPolicy != "" {
+			extraTasks = append(extraTasks, fmt.Sprintf("resourcePolicies/%s", placementPolicy))
+		}
 	}
 	taskNames := instanceNames(instances)
 	if opts.Delete {
@@ -385,30 +724,11 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 		name := inst.GetName()
 		group.Go(func() error {
 			if opts.Delete {
-				s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateTerminating, "", "")
 				autoDelete := !opts.KeepDisks
 				if opts.DeleteDisks {
 					autoDelete = true
 				}
-				if err := s.setAutoDelete(groupCtx, name, inst, autoDelete); err != nil {
-					return err
-				}
-				call := s.api("compute.instances.delete", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Deleting %s", name))
-				op, err := s.Compute.DeleteInstance(groupCtx, &computepb.DeleteInstanceRequest{
-					Project:  project,
-					Zone:     zone,
-					Instance: name,
-				})
-				if err != nil {
-					call.Stop()
-					return err
-				}
-				if err := s.waitWithProgress(groupCtx, call, op, func(p int32) { progress.Update(index, p) }); err != nil {
-					return err
-				}
-				s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateTerminated, "", "")
-				progress.MarkDone(index, fmt.Sprintf("Deleted %s", name))
-				return nil
+				return s.deleteNode(groupCtx, inst, autoDelete, opts.OnStateChange, progress, index)
 			}
 
 			if inst.GetStatus() == "TERMINATED" {
@@ -419,9 +739,10 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 			s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateTerminating, "", "")
 			call := s.api("compute.instances.stop", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Stopping %s", name))
 			op, err := s.Compute.StopInstance(groupCtx, &computepb.StopInstanceRequest{
-				Project:  project,
-				Zone:     zone,
-				Instance: name,
+				Project:         project,
+				Zone:            zone,
+				Instance:        name,
+				DiscardLocalSsd: instance.DiscardLocalSSD(inst),
 			})
 			if err != nil {
 				call.Stop()
@@ -440,18 +761,13 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 		return err
 	}
 
-	if opts.Delete {
+	if deleteCluster {
 		base := len(instances)
-		firewalls := []string{
-			fmt.Sprintf("%s-internal", networkName),
-			fmt.Sprintf("%s-ssh", networkName),
-			fmt.Sprintf("%s-ports", networkName),
-		}
 		for i, rule := range firewalls {
 			idx := base + i
-			call := s.api("compute.firewalls.delete", gcp.GlobalResource(project, "firewalls", rule), fmt.Sprintf("Deleting firewall %s", rule))
+			call := s.api("compute.firewalls.delete", gcp.GlobalResource(networkProject, "firewalls", rule), fmt.Sprintf("Deleting firewall %s", rule))
 			op, err := s.Compute.DeleteFirewall(ctx, &computepb.DeleteFirewallRequest{
-				Project:  project,
+				Project:  networkProject,
 				Firewall: rule,
 			})
 			if err != nil {
@@ -469,42 +785,26 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 		}
 
 		subnetIdx := base + len(firewalls)
-		call := s.api("compute.subnetworks.delete", gcp.RegionResource(project, region, "subnetworks", subnetName), fmt.Sprintf("Deleting subnetwork %s", subnetName))
-		op, err := s.Compute.DeleteSubnetwork(ctx, &computepb.DeleteSubnetworkRequest{
-			Project:    project,
-			Region:     region,
-			Subnetwork: subnetName,
-		})
-		if err != nil {
-			call.Stop()
-			if !gcp.IsNotFound(err) {
+		if router != "" {
+			if err := s.deleteRouter(ctx, networkProject, region, router); err != nil {
 				return err
 			}
-			progress.MarkDone(subnetIdx, fmt.Sprintf("Deleted subnetworks/%s", subnetName))
-		} else {
-			if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(subnetIdx, p) }); err != nil {
+			progress.MarkDone(subnetIdx, fmt.Sprintf("Deleted routers/%s", router))
+			subnetIdx++
+		}
+		placementIdx := subnetIdx
+		if !network.Existing {
+			if err := s.deleteClusterSubnetAndNetwork(ctx, progress, network, subnetIdx); err != nil {
 				return err
 			}
-			progress.MarkDone(subnetIdx, fmt.Sprintf("Deleted subnetworks/%s", subnetName))
+			placementIdx += 2
 		}
 
-		networkIdx := subnetIdx + 1
-		call = s.api("compute.networks.delete", gcp.GlobalResource(project, "networks", networkName), fmt.Sprintf("Deleting network %s", networkName))
-		op, err = s.Compute.DeleteNetwork(ctx, &computepb.DeleteNetworkRequest{
-			Project: project,
-			Network: networkName,
-		})
-		if err != nil {
-			call.Stop()
-			if !gcp.IsNotFound(err) {
-				return err
-			}
-			progress.MarkDone(networkIdx, fmt.Sprintf("Deleted networks/%s", networkName))
-		} else {
-			if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(networkIdx, p) }); err != nil {
+		if placementPolicy != "" {
+			if err := s.deletePlacementPolicy(ctx, project, region, placementPolicy); err != nil {
 				return err
 			}
-			progress.MarkDone(networkIdx, fmt.Sprintf("Deleted networks/%s", networkName))
+			progress.MarkDone(placementIdx, fmt.Sprintf("Deleted resourcePolicies/%s", placementPolicy))
 		}
 	}
 
@@ -512,6 +812,53 @@ func (s *Service) Stop(ctx context.Context, clusterName string, opts StopOptions
 	return nil
 }
 
+// deleteClusterSubnetAndNetwork removes a per-cluster subnetwork and network,
+// marking progress rows subnetIdx and subnetIdx+1.
+func (s *Service) deleteClusterSubnetAndNetwork(ctx context.Context, progress *ui.TaskList, network clusterNetwork, subnetIdx int) error {
+	networkProject := network.NetworkProject
+	region := network.Region
+	subnetName := network.SubnetName
+	networkName := network.NetworkName
+	call := s.api("compute.subnetworks.delete", gcp.RegionResource(networkProject, region, "subnetworks", subnetName), fmt.Sprintf("Deleting subnetwork %s", subnetName))
+	op, err := s.Compute.DeleteSubnetwork(ctx, &computepb.DeleteSubnetworkRequest{
+		Project:    networkProject,
+		Region:     region,
+		Subnetwork: subnetName,
+	})
+	if err != nil {
+		call.Stop()
+		if !gcp.IsNotFound(err) {
+			return err
+		}
+		progress.MarkDone(subnetIdx, fmt.Sprintf("Deleted subnetworks/%s", subnetName))
+	} else {
+		if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(subnetIdx, p) }); err != nil {
+			return err
+		}
+		progress.MarkDone(subnetIdx, fmt.Sprintf("Deleted subnetworks/%s", subnetName))
+	}
+
+	networkIdx := subnetIdx + 1
+	call = s.api("compute.networks.delete", gcp.GlobalResource(networkProject, "networks", networkName), fmt.Sprintf("Deleting network %s", networkName))
+	op, err = s.Compute.DeleteNetwork(ctx, &computepb.DeleteNetworkRequest{
+		Project: networkProject,
+		Network: networkName,
+	})
+	if err != nil {
+		call.Stop()
+		if !gcp.IsNotFound(err) {
+			return err
+		}
+		progress.MarkDone(networkIdx, fmt.Sprintf("Deleted networks/%s", networkName))
+	} else {
+		if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(networkIdx, p) }); err != nil {
+			return err
+		}
+		progress.MarkDone(networkIdx, fmt.Sprintf("Deleted networks/%s", networkName))
+	}
+	return nil
+}
+
 func (s *Service) Show(ctx context.Context, clusterName string) error {
 	if !validate.IsResourceName(clusterName) {
 		return fmt.Errorf("invalid cluster name: %s", clusterName)
@@ -549,12 +896,25 @@ func (s *Service) Show(ctx context.Context, clusterName string) error {
 	return nil
 }
 
+// Update changes settings of the cluster's nodes in place. Machine type and
+// scheduling (max run hours, termination action) can only change while a
+// node is stopped, so every selected node must be TERMINATED when one of
+// them is requested; labels and metadata are merged into nodes in any state.
 func (s *Service) Update(ctx context.Context, clusterName string, opts UpdateOptions) error {
 	if !validate.IsResourceName(clusterName) {
 		return fmt.Errorf("invalid cluster name: %s", clusterName)
 	}
-	if opts.MaxRunHours <= 0 {
-		return fmt.Errorf("max-hours must be >= 1")
+	if opts.MaxRunHours < 0 {
+		return fmt.Errorf("max-hours must not be negative")
+	}
+	if opts.empty() {
+		return fmt.Errorf("nothing to update")
+	}
+	if err := checkUserKeys("label", opts.Labels, reservedLabelKeys); err != nil {
+		return err
+	}
+	if err := checkUserKeys("metadata", opts.Metadata, reservedMetadataKeys); err != nil {
+		return err
 	}
 
 	split := s.UI.StartLiveSplit()
@@ -572,47 +932,106 @@ func (s *Service) Update(ctx context.Context, clusterName string, opts UpdateOpt
 		s.UI.Infof("No instances found for cluster %s", clusterName)
 		return nil
 	}
+	if len(opts.Nodes) > 0 {
+		if instances, err = selectInstances(clusterName, instances, opts.Nodes); err != nil {
+			return err
+		}
+	}
+	if opts.needsStop() {
+		running := []string{}
+		for _, inst := range instances {
+			if inst.GetStatus() != "TERMINATED" {
+				running = append(running, fmt.Sprintf("%s (%s)", inst.GetName(), inst.GetStatus()))
+			}
+		}
+		if len(running) > 0 {
+			return fmt.Errorf("machine type, max run hours and termination action can only change on stopped nodes; stop %s first", strings.Join(running, ", "))
+		}
+	}
 
-	project := s.Config.Project.ID
-	zone := s.Config.Project.Zone
-	scheduling := s.Builder.Scheduling(opts.MaxRunHours)
-	label := "Updating instance"
-	progress := s.UI.TaskList(label, instanceNames(instances))
+	progress := s.UI.TaskList("Updating instance", instanceNames(instances))
+	defer progress.Stop()
 	group, groupCtx := errgroup.WithContext(ctx)
 	for idx, inst := range instances {
-		index := idx
-		name := inst.GetName()
 		group.Go(func() error {
-			if inst.GetStatus() != "TERMINATED" {
-				progress.MarkWarning(index, fmt.Sprintf("%s must be TERMINATED to update max run duration", name))
-				return nil
-			}
-			call := s.api("compute.instances.setScheduling", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Updating scheduling for %s", name))
-			op, err := s.Compute.SetInstanceScheduling(groupCtx, &computepb.SetSchedulingInstanceRequest{
-				Project:            project,
-				Zone:               zone,
-				Instance:           name,
-				SchedulingResource: scheduling,
-			})
-			if err != nil {
-				call.Stop()
-				return err
-			}
-			if err := s.waitWithProgress(groupCtx, call, op, func(p int32) { progress.Update(index, p) }); err != nil {
-				return err
+			if err := s.updateNode(groupCtx, inst, opts); err != nil {
+				return fmt.Errorf("update %s: %w", inst.GetName(), err)
 			}
-			progress.MarkDone(index, fmt.Sprintf("Updated %s", name))
+			progress.MarkDone(idx, fmt.Sprintf("Updated %s", inst.GetName()))
 			return nil
 		})
 	}
-	if err := group.Wait(); err != nil {
-		progress.Stop()
-		return err
+	return group.Wait()
+}
+
+func (s *Service) updateNode(ctx context.Context, inst *computepb.Instance, opts UpdateOptions) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	name := inst.GetName()
+	if machineType := strings.TrimSpace(opts.MachineType); machineType != "" {
+		call := s.api("compute.instances.setMachineType", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Setting machine type of %s to %s", name, machineType))
+		op, err := s.Compute.SetInstanceMachineType(ctx, &computepb.SetMachineTypeInstanceRequest{
+			Project:  project,
+			Zone:     zone,
+			Instance: name,
+			InstancesSetMachineTypeRequestResource: &computepb.InstancesSetMachineTypeRequest{
+				MachineType: proto.String(gcp.ZoneResource(project, zone, "machineTypes", machineType)),
+			},
+		})
+		if err != nil {
+			call.Stop()
+			return err
+		}
+		if err := s.wait(ctx, call, op); err != nil {
+			return err
+		}
+	}
+	if opts.MaxRunHours > 0 || opts.TerminationAction != "" {
+		scheduling := s.Builder.SchedulingFor(instance.Options{
+			MaxRunHours:       opts.MaxRunHours,
+			TerminationAction: strings.ToUpper(strings.TrimSpace(opts.TerminationAction)),
+		})
+		if err := s.setInstanceScheduling(ctx, name, scheduling); err != nil {
+			return err
+		}
+	}
+	if len(opts.Labels) > 0 {
+		if err := s.withFreshFingerprint(ctx, inst, func(inst *computepb.Instance) error {
+			return s.setInstanceLabels(ctx, inst, opts.Labels)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(opts.Metadata) > 0 {
+		if err := s.withFreshFingerprint(ctx, inst, func(inst *computepb.Instance) error {
+			return s.setInstanceMetadata(ctx, inst, opts.Metadata)
+		}); err != nil {
+			return err
+		}
 	}
-	progress.Stop()
 	return nil
 }
 
+// withFreshFingerprint runs apply with inst and, while GCE rejects the
+// label or metadata fingerprint as stale because the instance changed in
+// the meantime, again with a freshly read copy.
+func (s *Service) withFreshFingerprint(ctx context.Context, inst *computepb.Instance, apply func(*computepb.Instance) error) error {
+	const attempts = 3
+	for attempt := 1; ; attempt++ {
+		err := apply(inst)
+		if err == nil || !gcp.IsPreconditionFailed(err) || attempt == attempts {
+			return err
+		}
+		name := inst.GetName()
+		if inst, err = s.getInstance(ctx, name); err != nil {
+			return err
+		}
+		if inst == nil {
+			return fmt.Errorf("instance %s not found", name)
+		}
+	}
+}
+
 func (s *Service) listClusterInstances(ctx context.Context, clusterName string) ([]*computepb.Instance, error) {
 	project := s.Config.Project.ID
 	zone := s.Config.Project.Zone
@@ -690,29 +1109,64 @@ func (s *Service) ensureNetwork(ctx context.Context, project, networkName string
 	return s.wait(ctx, call, op)
 }
 
-func (s *Service) ensureSubnetwork(ctx context.Context, project, region, subnetName, cidr, networkURL string) error {
+func (s *Service) ensureSubnetwork(ctx context.Context, network clusterNetwork) error {
+	project := network.NetworkProject
+	region := network.Region
+	subnetName := network.SubnetName
 	getCall := s.api("compute.subnetworks.get", gcp.RegionResource(project, region, "subnetworks", subnetName), "")
-	_, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{
+	existing, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{
 		Project:    project,
 		Region:     region,
 		Subnetwork: subnetName,
 	})
 	getCall.Stop()
 	if err == nil {
+		if existing.GetIpCidrRange() != network.SubnetCIDR {
+			return fmt.Errorf("subnetwork %s uses %s, not %s; subnet ranges cannot be changed in place", subnetName, existing.GetIpCidrRange(), network.SubnetCIDR)
+		}
+		if network.DualStack && existing.GetStackType() != "IPV4_IPV6" {
+			return s.enableSubnetIPv6(ctx, network, existing)
+		}
 		return nil
 	}
 	if !gcp.IsNotFound(err) {
 		return err
 	}
 
+	subnet := &computepb.Subnetwork{
+		Name:        proto.String(subnetName),
+		IpCidrRange: proto.String(network.SubnetCIDR),
+		Network:     proto.String(network.NetworkURL),
+	}
+	if network.DualStack {
+		subnet.StackType = proto.String("IPV4_IPV6")
+		subnet.Ipv6AccessType = proto.String("EXTERNAL")
+	}
 	call := s.api("compute.subnetworks.insert", gcp.RegionResource(project, region, "subnetworks", subnetName), fmt.Sprintf("Creating subnetwork %s", subnetName))
 	op, err := s.Compute.InsertSubnetwork(ctx, &computepb.InsertSubnetworkRequest{
-		Project: project,
-		Region:  region,
+		Project:            project,
+		Region:             region,
+		SubnetworkResource: subnet,
+	})
+	if err != nil {
+		call.Stop()
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
+// enableSubnetIPv6 turns an IPv4-only cluster subnet dual-stack in place.
+func (s *Service) enableSubnetIPv6(ctx context.Context, network clusterNetwork, existing *computepb.Subnetwork) error {
+	project := network.NetworkProject
+	call := s.api("compute.subnetworks.patch", gcp.RegionResource(project, network.Region, "subnetworks", network.SubnetName), fmt.Sprintf("Enabling IPv6 on subnetwork %s", network.SubnetName))
+	op, err := s.Compute.PatchSubnetwork(ctx, &computepb.PatchSubnetworkRequest{
+		Project:    project,
+		Region:     network.Region,
+		Subnetwork: network.SubnetName,
 		SubnetworkResource: &computepb.Subnetwork{
-			Name:        proto.String(subnetName),
-			IpCidrRange: proto.String(cidr),
-			Network:     proto.String(networkURL),
+			StackType:      proto.String("IPV4_IPV6"),
+			Ipv6AccessType: proto.String("EXTERNAL"),
+			Fingerprint:    proto.String(existing.GetFingerprint()),
 		},
 	})
 	if err != nil {
@@ -722,66 +1176,386 @@ func (s *Service) ensureSubnetwork(ctx context.Context, project, region, subnetN
 	return s.wait(ctx, call, op)
 }
 
-func (s *Service) ensureFirewalls(ctx context.Context, project, networkName, cidr, clusterName string) error {
-	internalRule := fmt.Sprintf("%s-internal", networkName)
-	sshRule := fmt.Sprintf("%s-ssh", networkName)
-	portsRule := fmt.Sprintf("%s-ports", networkName)
+// subnetIPv6Range returns the IPv6 range Google assigned to a dual-stack
+// subnet, or "" for IPv4-only subnets.
+func subnetIPv6Range(subnet *computepb.Subnetwork) string {
+	if prefix := subnet.GetExternalIpv6Prefix(); prefix != "" {
+		return prefix
+	}
+	return subnet.GetIpv6CidrRange()
+}
+
+// lookupSubnetIPv6Range reads the IPv6 range of the cluster subnet; a missing
+// subnet has none yet.
+func (s *Service) lookupSubnetIPv6Range(ctx context.Context, network clusterNetwork) (string, error) {
+	subnet, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: network.NetworkProject, Region: network.Region, Subnetwork: network.SubnetName})
+	if gcp.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return subnetIPv6Range(subnet), nil
+}
+
+type clusterNetwork struct {
+	Project      string
+	Zone         string
+	Region       string
+	NetworkName  string
+	SubnetName   string
+	NetworkURL   string
+	SubnetURL    string
+	SubnetCIDR   string
+	InternalRule string
+	SSHRule      string
+	PortsRule    string
+	// PlacementPolicy and PlacementURL are empty unless cluster.placement
+	// is set.
+	PlacementPolicy string
+	PlacementURL    string
+	// RouterName and NATName are empty unless cluster.private_workers is
+	// set.
+	RouterName string
+	NATName    string
+	// DatasetDisk and DatasetURL are empty unless
+	// cluster.shared_dataset_disk is set; DatasetSnapshot is set when the
+	// disk is created from a snapshot.
+	DatasetDisk     string
+	DatasetURL      string
+	DatasetSnapshot string
+	// Existing is set for cluster.network_mode = "existing": the network
+	// and subnet are shared and never created or deleted, and SubnetCIDR is
+	// empty.
+	Existing bool
+	// NetworkProject hosts the network, subnet, firewall rules and router;
+	// it differs from Project for Shared VPC subnets.
+	NetworkProject string
+	// DualStack is set for network.stack_type = "IPV4_IPV6". SubnetIPv6CIDR
+	// is the range Google assigned to the subnet, empty until it exists.
+	DualStack      bool
+	SubnetIPv6CIDR string
+}
+
+func (s *Service) resolveClusterNetwork(clusterName string) (clusterNetwork, error) {
+	project := s.Config.Project.ID
+	networkProject := s.Config.ClusterNetworkProject()
+	zone := s.Config.Project.Zone
+	region, err := gcp.RegionFromZone(zone)
+	if err != nil {
+		return clusterNetwork{}, err
+	}
+	// Rules and the policy are named after the cluster in both modes, so
+	// they stay cluster-scoped on a shared network.
+	clusterPrefix := s.clusterNetworkName(clusterName)
+	network := clusterNetwork{
+		Project:        project,
+		NetworkProject: networkProject,
+		DualStack:      s.Config.Network.DualStack(),
+		Zone:           zone,
+		Region:         region,
+		InternalRule:   fmt.Sprintf("%s-internal", clusterPrefix),
+		SSHRule:        fmt.Sprintf("%s-ssh", clusterPrefix),
+		PortsRule:      fmt.Sprintf("%s-ports", clusterPrefix),
+	}
+	if s.Config.Cluster.NetworkMode == "existing" {
+		network.Existing = true
+		network.NetworkName = s.Config.Cluster.Network
+		network.SubnetName = s.Config.Cluster.Subnetwork
+	} else {
+		network.NetworkName = clusterPrefix
+		network.SubnetName = s.clusterSubnetName(clusterPrefix)
+		network.SubnetCIDR, err = DeriveSubnetCIDR(s.Config.Cluster.SubnetCIDRBase, s.Config.Cluster.SubnetPrefix, clusterName)
+		if err != nil {
+			return clusterNetwork{}, err
+		}
+	}
+	network.NetworkURL = gcp.GlobalResource(networkProject, "networks", network.NetworkName)
+	network.SubnetURL = gcp.RegionResource(networkProject, region, "subnetworks", network.SubnetName)
+	if policy := s.placementPolicyName(clusterPrefix); policy != "" {
+		network.PlacementPolicy = policy
+		network.PlacementURL = gcp.RegionResource(project, region, "resourcePolicies", policy)
+	}
+	if router := s.routerName(clusterPrefix); router != "" {
+		network.RouterName = router
+		network.NATName = fmt.Sprintf("%s-nat", clusterPrefix)
+	}
+	if disk, snapshot := s.datasetDisk(); disk != "" {
+		network.DatasetDisk = disk
+		network.DatasetSnapshot = snapshot
+		network.DatasetURL = gcp.ZoneResource(project, zone, "disks", disk)
+	}
+	return network, nil
+}
+
+func (n clusterNetwork) taskNames() []string {
+	names := []string{
+		fmt.Sprintf("network %s", n.NetworkName),
+		fmt.Sprintf("subnetwork %s", n.SubnetName),
+		fmt.Sprintf("firewall %s", n.InternalRule),
+		fmt.Sprintf("firewall %s", n.SSHRule),
+		fmt.Sprintf("firewall %s", n.PortsRule),
+	}
+	if n.PlacementPolicy != "" {
+		names = append(names, fmt.Sprintf("placement policy %s", n.PlacementPolicy))
+	}
+	if n.RouterName != "" {
+		names = append(names, fmt.Sprintf("router %s", n.RouterName))
+	}
+	if n.DatasetDisk != "" {
+		names = append(names, fmt.Sprintf("dataset disk %s", n.DatasetDisk))
+	}
+	return names
+}
+
+// ensureClusterNetwork creates or updates the cluster VPC, subnet, firewalls,
+// placement policy, NAT router and dataset disk, marking the progress rows
+// of network.taskNames(). An existing network and subnet are only checked.
+func (s *Service) ensureClusterNetwork(ctx context.Context, clusterName string, network clusterNetwork, opts StartOptions, progress *ui.TaskList) error {
+	tasks := network.taskNames()
+	row := func(task string) int {
+		return slices.Index(tasks, task)
+	}
+	if network.Existing {
+		if err := s.checkExistingNetwork(ctx, network); err != nil {
+			return err
+		}
+		progress.MarkDone(row("network "+network.NetworkName), fmt.Sprintf("Using networks/%s", network.NetworkName))
+		progress.MarkDone(row("subnetwork "+network.SubnetName), fmt.Sprintf("Using subnetworks/%s", network.SubnetName))
+	} else {
+		if err := s.ensureNetwork(ctx, network.NetworkProject, network.NetworkName); err != nil {
+			return err
+		}
+		progress.MarkDone(row("network "+network.NetworkName), fmt.Sprintf("Ready networks/%s", network.NetworkName))
+
+		if err := s.ensureSubnetwork(ctx, network); err != nil {
+			return err
+		}
+		progress.MarkDone(row("subnetwork "+network.SubnetName), fmt.Sprintf("Ready subnetworks/%s", network.SubnetName))
+	}
+	if network.DualStack && network.SubnetIPv6CIDR == "" {
+		ipv6Range, err := s.lookupSubnetIPv6Range(ctx, network)
+		if err != nil {
+			return err
+		}
+		network.SubnetIPv6CIDR = ipv6Range
+	}
+
+	// The internal and ssh rules have a row each, which also covers their
+	// "-v6" siblings; the ports rule row covers the per-source port rules.
+	firewalls := s.clusterFirewalls(clusterName, network, opts)
+	for _, rule := range firewalls {
+		if isPortFirewall(network, rule.GetName()) {
+			continue
+		}
+		if err := s.ensureFirewall(ctx, network.NetworkProject, rule.GetName(), rule); err != nil {
+			return err
+		}
+	}
+	progress.MarkDone(row("firewall "+network.InternalRule), fmt.Sprintf("Ready firewalls/%s", network.InternalRule))
+	progress.MarkDone(row("firewall "+network.SSHRule), fmt.Sprintf("Ready firewalls/%s", network.SSHRule))
+	if err := s.ensurePortFirewalls(ctx, network, firewalls); err != nil {
+		return err
+	}
+	progress.MarkDone(row("firewall "+network.PortsRule), fmt.Sprintf("Ready firewalls/%s", network.PortsRule))
+
+	if network.PlacementPolicy != "" {
+		if err := s.ensurePlacementPolicy(ctx, network); err != nil {
+			return err
+		}
+		progress.MarkDone(row("placement policy "+network.PlacementPolicy), fmt.Sprintf("Ready resourcePolicies/%s", network.PlacementPolicy))
+	}
+
+	if network.RouterName != "" {
+		if err := s.ensureRouter(ctx, network); err != nil {
+			return err
+		}
+		progress.MarkDone(row("router "+network.RouterName), fmt.Sprintf("Ready routers/%s", network.RouterName))
+	}
+	if network.DatasetDisk != "" {
+		if err := s.ensureDatasetDisk(ctx, network); err != nil {
+			return err
+		}
+		progress.MarkDone(row("dataset disk "+network.DatasetDisk), fmt.Sprintf("Ready disks/%s", network.DatasetDisk))
+	}
+	return nil
+}
+
+// sourceRanges returns the CIDRs allowed to reach SSH and the exposed ports,
+// from opts when overridden for the cluster and from the profile otherwise.
+func (s *Service) sourceRanges(opts StartOptions) (sshRanges, portRanges []string) {
+	sshRanges = s.Config.Network.SSHSourceRanges
+	if len(opts.SSHSourceRanges) > 0 {
+		sshRanges = opts.SSHSourceRanges
+	}
+	portRanges = s.Config.Network.PortSourceRanges
+	if len(opts.PortSourceRanges) > 0 {
+		portRanges = opts.PortSourceRanges
+	}
+	return sshRanges, portRanges
+}
 
-	network := gcp.GlobalResource(project, "networks", networkName)
+// clusterFirewalls returns the internal, ssh and ports rules of a cluster,
+// then the per-source port rules, then the "-v6" siblings carrying IPv6
+// sources (a rule cannot mix address families).
+func (s *Service) clusterFirewalls(clusterName string, network clusterNetwork, opts StartOptions) []*computepb.Firewall {
 	clusterTag := s.clusterTag(clusterName)
+	sshRanges, portRanges := s.sourceRanges(opts)
 
 	internalFirewall := &computepb.Firewall{
-		Name:         proto.String(internalRule),
-		Network:      proto.String(network),
+		Name:         proto.String(network.InternalRule),
+		Network:      proto.String(network.NetworkURL),
 		Direction:    proto.String("INGRESS"),
 		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{cidr},
+		SourceRanges: []string{network.SubnetCIDR},
 		Allowed: []*computepb.Allowed{
 			{IPProtocol: proto.String("tcp"), Ports: []string{"0-65535"}},
 			{IPProtocol: proto.String("udp"), Ports: []string{"0-65535"}},
 			{IPProtocol: proto.String("icmp")},
 		},
 	}
+	ipv6Rules := []*computepb.Firewall{}
+	if network.Existing {
+		// A shared subnet holds other workloads; only cluster nodes may
+		// reach each other.
+		internalFirewall.SourceRanges = nil
+		internalFirewall.SourceTags = []string{clusterTag}
+	} else if network.SubnetIPv6CIDR != "" {
+		internalIPv6 := proto.Clone(internalFirewall).(*computepb.Firewall)
+		internalIPv6.Name = proto.String(ipv6RuleName(network.InternalRule))
+		internalIPv6.SourceRanges = []string{network.SubnetIPv6CIDR}
+		internalIPv6.Allowed[2].IPProtocol = proto.String("58")
+		ipv6Rules = append(ipv6Rules, internalIPv6)
+	}
 
 	sshFirewall := &computepb.Firewall{
-		Name:         proto.String(sshRule),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{"0.0.0.0/0"},
+		Name:       proto.String(network.SSHRule),
+		Network:    proto.String(network.NetworkURL),
+		Direction:  proto.String("INGRESS"),
+		TargetTags: []string{clusterTag},
 		Allowed: []*computepb.Allowed{{
 			IPProtocol: proto.String("tcp"),
 			Ports:      []string{"22"},
 		}},
 	}
+	if sibling := splitSourceRanges(sshFirewall, sshRanges, network.DualStack); sibling != nil {
+		ipv6Rules = append(ipv6Rules, sibling)
+	}
 
-	portsFirewall := &computepb.Firewall{
-		Name:         proto.String(portsRule),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		TargetTags:   []string{clusterTag},
-		SourceRanges: []string{"0.0.0.0/0"},
-		Allowed: []*computepb.Allowed{{
-			IPProtocol: proto.String("tcp"),
-			Ports:      portsToStringsWithReadiness(s.Config.Network.Ports),
-		}},
+	firewalls := []*computepb.Firewall{internalFirewall, sshFirewall}
+	ports := s.Config.Network.Ports
+	if len(opts.Ports) > 0 {
+		ports = opts.Ports
+	}
+	for i, group := range parse.GroupPortSpecs(ports) {
+		name := network.PortsRule
+		ranges := portRanges
+		if i == 0 {
+			group = withReadinessPort(group)
+		} else {
+			name = portSourceRuleName(network.PortsRule, i)
+			ranges = group.SourceRanges
+		}
+		rule := &computepb.Firewall{
+			Name:       proto.String(name),
+			Network:    proto.String(network.NetworkURL),
+			Direction:  proto.String("INGRESS"),
+			TargetTags: []string{clusterTag},
+			Allowed:    portGroupAllowed(group),
+		}
+		if sibling := splitSourceRanges(rule, ranges, network.DualStack); sibling != nil {
+			ipv6Rules = append(ipv6Rules, sibling)
+		}
+		firewalls = append(firewalls, rule)
 	}
+	return append(firewalls, ipv6Rules...)
+}
 
-	if err := s.ensureFirewall(ctx, project, internalRule, internalFirewall); err != nil {
-		return err
+// splitSourceRanges sets rule's source ranges to the IPv4 entries of ranges
+// and returns a "-v6" copy for the IPv6 entries, or nil when there are none.
+// On dual-stack clusters an open 0.0.0.0/0 is mirrored as ::/0. A list with
+// only IPv6 entries stays on rule itself.
+func splitSourceRanges(rule *computepb.Firewall, ranges []string, dualStack bool) *computepb.Firewall {
+	ipv4, ipv6 := []string{}, []string{}
+	for _, cidr := range ranges {
+		if strings.Contains(cidr, ":") {
+			ipv6 = append(ipv6, cidr)
+		} else {
+			ipv4 = append(ipv4, cidr)
+		}
 	}
-	if err := s.ensureFirewall(ctx, project, sshRule, sshFirewall); err != nil {
-		return err
+	if dualStack && slices.Contains(ipv4, "0.0.0.0/0") && !slices.Contains(ipv6, "::/0") {
+		ipv6 = append(ipv6, "::/0")
+	}
+	if len(ipv4) == 0 {
+		rule.SourceRanges = ipv6
+		return nil
+	}
+	rule.SourceRanges = ipv4
+	if len(ipv6) == 0 {
+		return nil
+	}
+	sibling := proto.Clone(rule).(*computepb.Firewall)
+	sibling.Name = proto.String(ipv6RuleName(rule.GetName()))
+	sibling.SourceRanges = ipv6
+	return sibling
+}
+
+func ipv6RuleName(name string) string {
+	return name + "-v6"
+}
+
+// ensurePortFirewalls ensures the ports rule, the per-source port rules and
+// their "-v6" siblings among firewalls, the cluster's full desired rule set,
+// and deletes per-source and "-v6" rules it no longer contains.
+func (s *Service) ensurePortFirewalls(ctx context.Context, network clusterNetwork, firewalls []*computepb.Firewall) error {
+	for _, rule := range firewalls {
+		if !isPortFirewall(network, rule.GetName()) {
+			continue
+		}
+		if err := s.ensureFirewall(ctx, network.NetworkProject, rule.GetName(), rule); err != nil {
+			return err
+		}
 	}
-	if err := s.ensureFirewall(ctx, project, portsRule, portsFirewall); err != nil {
+	stale, err := s.staleFirewalls(ctx, network, firewalls)
+	if err != nil {
 		return err
 	}
+	for _, name := range stale {
+		if err := s.deleteFirewall(ctx, network.NetworkProject, name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// portSourceRuleName names the extra rule for the i-th group of ports with
+// their own source ranges, e.g. "gpunow-demo-ports-1".
+func portSourceRuleName(portsRule string, i int) string {
+	return fmt.Sprintf("%s-%d", portsRule, i)
+}
+
+// isPortSourceRule reports whether name is one of portsRule's per-source
+// rules.
+func isPortSourceRule(portsRule, name string) bool {
+	suffix, ok := strings.CutPrefix(name, portsRule+"-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// isPortFirewall reports whether name is the ports rule, one of its
+// per-source rules or the "-v6" sibling of either.
+func isPortFirewall(network clusterNetwork, name string) bool {
+	base, _ := strings.CutSuffix(name, "-v6")
+	return base == network.PortsRule || isPortSourceRule(network.PortsRule, base)
+}
+
 func (s *Service) ensureFirewall(ctx context.Context, project, name string, rule *computepb.Firewall) error {
 	getCall := s.api("compute.firewalls.get", gcp.GlobalResource(project, "firewalls", name), "")
-	_, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{
+	existing, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{
 		Project:  project,
 		Firewall: name,
 	})
@@ -801,6 +1575,9 @@ func (s *Service) ensureFirewall(ctx context.Context, project, name string, rule
 		}
 		return err
 	}
+	if len(firewallDiffs(existing, rule)) == 0 {
+		return nil
+	}
 
 	call := s.api("compute.firewalls.patch", gcp.GlobalResource(project, "firewalls", name), fmt.Sprintf("Updating firewall %s", name))
 	op, err := s.Compute.PatchFirewall(ctx, &computepb.PatchFirewallRequest{
@@ -815,33 +1592,113 @@ func (s *Service) ensureFirewall(ctx context.Context, project, name string, rule
 	return s.wait(ctx, call, op)
 }
 
-func (s *Service) deleteFirewalls(ctx context.Context, project, networkName string) error {
-	rules := []string{
-		fmt.Sprintf("%s-internal", networkName),
-		fmt.Sprintf("%s-ssh", networkName),
-		fmt.Sprintf("%s-ports", networkName),
+// clusterFirewallNames returns the internal, ssh and ports rule names of a
+// cluster followed by the per-source port rules that exist.
+func (s *Service) clusterFirewallNames(ctx context.Context, network clusterNetwork) ([]string, error) {
+	rules := []string{network.InternalRule, network.SSHRule, network.PortsRule}
+	extraRules, err := s.listExtraFirewalls(ctx, network)
+	if err != nil {
+		return nil, err
 	}
+	return append(rules, extraRules...), nil
+}
 
+// listExtraFirewalls lists the cluster's per-source port rules
+// ("<ports rule>-<n>") and "-v6" rules, sorted by name.
+func (s *Service) listExtraFirewalls(ctx context.Context, network clusterNetwork) ([]string, error) {
+	project := network.NetworkProject
+	call := s.api("compute.firewalls.list", fmt.Sprintf("projects/%s/global/firewalls", project), "")
+	rules, err := gcp.Collect(s.Compute.ListFirewalls(ctx, &computepb.ListFirewallsRequest{Project: project}))
+	call.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("list firewalls: %w", err)
+	}
+	names := []string{}
 	for _, rule := range rules {
-		call := s.api("compute.firewalls.delete", gcp.GlobalResource(project, "firewalls", rule), fmt.Sprintf("Deleting firewall %s", rule))
-		op, err := s.Compute.DeleteFirewall(ctx, &computepb.DeleteFirewallRequest{
-			Project:  project,
-			Firewall: rule,
-		})
-		if err != nil {
-			call.Stop()
-			if gcp.IsNotFound(err) {
-				continue
-			}
-			return err
+		// A shared network project holds rules of other networks too.
+		if !strings.HasSuffix(rule.GetNetwork(), network.NetworkURL) {
+			continue
 		}
-		if err := s.wait(ctx, call, op); err != nil {
-			return err
+		if isExtraFirewall(network, rule.GetName()) {
+			names = append(names, rule.GetName())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func isExtraFirewall(network clusterNetwork, name string) bool {
+	base, ipv6 := strings.CutSuffix(name, "-v6")
+	if ipv6 && (base == network.InternalRule || base == network.SSHRule || base == network.PortsRule) {
+		return true
+	}
+	return isPortSourceRule(network.PortsRule, base)
+}
+
+// staleFirewalls returns the per-source port and "-v6" rules of the cluster
+// that desired no longer contains.
+func (s *Service) staleFirewalls(ctx context.Context, network clusterNetwork, desired []*computepb.Firewall) ([]string, error) {
+	existing, err := s.listExtraFirewalls(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, rule := range desired {
+		wanted[rule.GetName()] = true
+	}
+	stale := []string{}
+	for _, name := range existing {
+		if !wanted[name] {
+			stale = append(stale, name)
 		}
 	}
+	return stale, nil
+}
+
+// checkExistingNetwork fails unless the shared network and its subnet in the
+// cluster region exist.
+func (s *Service) checkExistingNetwork(ctx context.Context, network clusterNetwork) error {
+	project := network.NetworkProject
+	getCall := s.api("compute.networks.get", gcp.GlobalResource(project, "networks", network.NetworkName), "")
+	_, err := s.Compute.GetNetwork(ctx, &computepb.GetNetworkRequest{Project: project, Network: network.NetworkName})
+	getCall.Stop()
+	if gcp.IsNotFound(err) {
+		return fmt.Errorf("cluster.network %s not found in project %s", network.NetworkName, project)
+	}
+	if err != nil {
+		return err
+	}
+	getCall = s.api("compute.subnetworks.get", gcp.RegionResource(project, network.Region, "subnetworks", network.SubnetName), "")
+	subnet, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: project, Region: network.Region, Subnetwork: network.SubnetName})
+	getCall.Stop()
+	if gcp.IsNotFound(err) {
+		return fmt.Errorf("cluster.subnetwork %s not found in %s of project %s", network.SubnetName, network.Region, project)
+	}
+	if err != nil {
+		return err
+	}
+	if network.DualStack && subnet.GetStackType() != "IPV4_IPV6" {
+		return fmt.Errorf("cluster.subnetwork %s is IPv4-only; network.stack_type = \"IPV4_IPV6\" needs a dual-stack subnet", network.SubnetName)
+	}
 	return nil
 }
 
+func (s *Service) deleteFirewall(ctx context.Context, project, name string) error {
+	call := s.api("compute.firewalls.delete", gcp.GlobalResource(project, "firewalls", name), fmt.Sprintf("Deleting firewall %s", name))
+	op, err := s.Compute.DeleteFirewall(ctx, &computepb.DeleteFirewallRequest{
+		Project:  project,
+		Firewall: name,
+	})
+	if err != nil {
+		call.Stop()
+		if gcp.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return s.wait(ctx, call, op)
+}
+
 func (s *Service) deleteSubnetwork(ctx context.Context, project, region, subnetName string) error {
 	call := s.api("compute.subnetworks.delete", gcp.RegionResource(project, region, "subnetworks", subnetName), fmt.Sprintf("Deleting subnetwork %s", subnetName))
 	op, err := s.Compute.DeleteSubnetwork(ctx, &computepb.DeleteSubnetworkRequest{
@@ -880,6 +1737,9 @@ func (s *Service) setAutoDelete(ctx context.Context, name string, instanceObj *c
 	zone := s.Config.Project.Zone
 
 	for _, disk := range instanceObj.GetDisks() {
+		if s.Builder.KeepsAutoDelete(disk) {
+			continue
+		}
 		req := &computepb.SetDiskAutoDeleteInstanceRequest{
 			Project:    project,
 			Zone:       zone,
@@ -901,13 +1761,17 @@ func (s *Service) setAutoDelete(ctx context.Context, name string, instanceObj *c
 }
 
 func (s *Service) ensureInstanceTags(ctx context.Context, instanceObj *computepb.Instance, tags []string) error {
-	project := s.Config.Project.ID
-	zone := s.Config.Project.Zone
-
 	existing := instanceObj.GetTags().GetItems()
 	if containsAll(existing, tags) {
 		return nil
 	}
+	return s.setInstanceTags(ctx, instanceObj, tags)
+}
+
+// setInstanceTags replaces the network tags on an instance.
+func (s *Service) setInstanceTags(ctx context.Context, instanceObj *computepb.Instance, tags []string) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
 
 	req := &computepb.SetTagsInstanceRequest{
 		Project:  project,
@@ -931,7 +1795,7 @@ func (s *Service) api(action, resource, label string) *ui.APICall {
 	return s.UI.APICall(action, resource, label)
 }
 
-func (s *Service) wait(ctx context.Context, call *ui.APICall, op *compute.Operation) error {
+func (s *Service) wait(ctx context.Context, call *ui.APICall, op gcp.Operation) error {
 	err := op.Wait(ctx)
 	if call != nil {
 		call.Stop()
@@ -939,7 +1803,7 @@ func (s *Service) wait(ctx context.Context, call *ui.APICall, op *compute.Operat
 	return err
 }
 
-func (s *Service) waitWithProgress(ctx context.Context, call *ui.APICall, op *compute.Operation, update func(int32)) error {
+func (s *Service) waitWithProgress(ctx context.Context, call *ui.APICall, op gcp.Operation, update func(int32)) error {
 	ticker := time.NewTicker(800 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -970,6 +1834,21 @@ func (s *Service) waitWithProgress(ctx context.Context, call *ui.APICall, op *co
 	}
 }
 
+// ensureSSHKeys adds the SSH key to every instance before commands run over
+// SSH, so the master accepts jumps to private workers. An empty key is a
+// no-op.
+func (s *Service) ensureSSHKeys(ctx context.Context, instances []*computepb.Instance, user, publicKey string) error {
+	if publicKey == "" {
+		return nil
+	}
+	for _, inst := range instances {
+		if err := s.ensureInstanceSSHKey(ctx, inst.GetName(), user, publicKey); err != nil {
+			return fmt.Errorf("add ssh key to %s: %w", inst.GetName(), err)
+		}
+	}
+	return nil
+}
+
 func (s *Service) ensureInstanceSSHKey(ctx context.Context, name, user, publicKey string) error {
 	return ssh.EnsureInstanceSSHKey(ctx, s.Compute, s.Config, name, user, publicKey)
 }
@@ -1003,6 +1882,109 @@ func (s *Service) instanceName(clusterName string, index int) string {
 	return fmt.Sprintf("%s-%d", clusterName, index)
 }
 
+type clusterNode struct {
+	Cluster string
+	Name    string
+	// Index is the node's position within its pool, or within the cluster
+	// when it has no pools.
+	Index int
+	Pool  string
+	Role  string
+}
+
+func (n clusterNode) Master() bool {
+	return n.Role == "master"
+}
+
+// identity returns the cluster keys set as both labels and metadata on the
+// node's instance.
+func (n clusterNode) identity() map[string]string {
+	identity := map[string]string{
+		"cluster":       n.Cluster,
+		"cluster_index": strconv.Itoa(n.Index),
+		"cluster_role":  n.Role,
+	}
+	if n.Pool != "" {
+		identity["cluster_pool"] = n.Pool
+	}
+	return identity
+}
+
+// claims returns an error when inst carries the identity of another cluster
+// or pool. Pool nodes are named <cluster>-<pool>-<index>, so a lookup by name
+// can find a node of a different cluster called <cluster>-<pool>. Instances
+// without a cluster label predate identity labels and are accepted.
+func (n clusterNode) claims(inst *computepb.Instance) error {
+	labels := inst.GetLabels()
+	owner := labels["cluster"]
+	if owner == "" {
+		return nil
+	}
+	if owner != n.Cluster {
+		return fmt.Errorf("instance %s belongs to cluster %s, not %s", inst.GetName(), owner, n.Cluster)
+	}
+	if pool := labels["cluster_pool"]; pool != n.Pool {
+		return fmt.Errorf("instance %s belongs to pool %q of cluster %s, not %q", inst.GetName(), pool, n.Cluster, n.Pool)
+	}
+	return nil
+}
+
+// withIdentity returns extra with the node identity keys added on top.
+func withIdentity(node clusterNode, extra map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	for key, value := range node.identity() {
+		merged[key] = value
+	}
+	return merged
+}
+
+func (s *Service) clusterNodes(clusterName string, indexes []int) []clusterNode {
+	nodes := make([]clusterNode, 0, len(indexes))
+	for _, index := range indexes {
+		role := "worker"
+		if index == 0 {
+			role = "master"
+		}
+		nodes = append(nodes, clusterNode{
+			Cluster: clusterName,
+			Name:    s.instanceName(clusterName, index),
+			Index:   index,
+			Role:    role,
+		})
+	}
+	return nodes
+}
+
+func indexRange(start, end int) []int {
+	out := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+// instanceIndex returns the cluster index of an instance from its
+// cluster_index label, falling back to the <cluster>-<index> name.
+func instanceIndex(clusterName string, inst *computepb.Instance) int {
+	if raw, ok := inst.GetLabels()["cluster_index"]; ok {
+		if index, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && index >= 0 {
+			return index
+		}
+	}
+	raw := strings.TrimPrefix(inst.GetName(), clusterName+"-")
+	if raw == inst.GetName() {
+		return -1
+	}
+	index, err := strconv.Atoi(raw)
+	if err != nil || index < 0 {
+		return -1
+	}
+	return index
+}
+
 func (s *Service) updateInstanceState(callback func(name, state, externalIP, internalIP string), name, state, externalIP, internalIP string) {
 	if callback == nil {
 		return
@@ -1020,6 +2002,9 @@ func instanceIPs(inst *computepb.Instance) (string, string) {
 	if len(iface.GetAccessConfigs()) > 0 {
 		external = iface.GetAccessConfigs()[0].GetNatIP()
 	}
+	if external == "" && len(iface.GetIpv6AccessConfigs()) > 0 {
+		external = iface.GetIpv6AccessConfigs()[0].GetExternalIpv6()
+	}
 	return external, internal
 }
 
@@ -1031,27 +2016,28 @@ func diskAutoDeleteOverride(keepDisks bool) *bool {
 	return &value
 }
 
-func portsToStrings(ports []int) []string {
-	out := make([]string, 0, len(ports))
-	for _, port := range ports {
-		out = append(out, strconv.Itoa(port))
+func portGroupAllowed(group parse.PortGroup) []*computepb.Allowed {
+	protocols, ranges := group.Allowed()
+	allowed := make([]*computepb.Allowed, 0, len(protocols))
+	for _, protocol := range protocols {
+		allowed = append(allowed, &computepb.Allowed{
+			IPProtocol: proto.String(protocol),
+			Ports:      ranges[protocol],
+		})
 	}
-	return out
+	return allowed
 }
 
-func portsToStringsWithReadiness(ports []int) []string {
-	withReadiness := append([]int{}, ports...)
-	found := false
-	for _, port := range withReadiness {
-		if port == readinessPort {
-			found = true
-			break
+// withReadinessPort adds the readiness sentinel port to the group unless one
+// of its specs already covers it.
+func withReadinessPort(group parse.PortGroup) parse.PortGroup {
+	for _, spec := range group.Specs {
+		if spec.Contains(readinessPort, "tcp") {
+			return group
 		}
 	}
-	if !found {
-		withReadiness = append(withReadiness, readinessPort)
-	}
-	return portsToStrings(withReadiness)
+	group.Specs = append(append([]parse.PortSpec{}, group.Specs...), parse.PortSpec{From: readinessPort, To: readinessPort, Protocol: "tcp"})
+	return group
 }
 
 func instanceNames(instances []*computepb.Instance) []string {
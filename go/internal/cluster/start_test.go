@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/lifecycle"
+)
+
+// brokenInserts creates the named instances but reports the insert as failed.
+type brokenInserts struct {
+	*fakeCompute
+	names map[string]bool
+}
+
+func (f brokenInserts) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (gcp.Operation, error) {
+	op, err := f.fakeCompute.InsertInstance(ctx, req)
+	if err == nil && f.names[req.GetInstanceResource().GetName()] {
+		return nil, errors.New("boot failed")
+	}
+	return op, err
+}
+
+func TestStartMinReadyKeepsFailedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, brokenInserts{fakeCompute: compute, names: map[string]bool{"quorum-2": true}})
+	zone := service.Config.Project.Zone
+
+	result, err := service.Start(context.Background(), "quorum", StartOptions{NumInstances: 3, MinReady: 2})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if result.ReadyCount() != 2 {
+		t.Fatalf("expected 2 ready nodes, got %d", result.ReadyCount())
+	}
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].Name != "quorum-2" || failed[0].State != lifecycle.InstanceStateFailed || failed[0].Deleted {
+		t.Fatalf("unexpected failed nodes: %+v", failed)
+	}
+	if compute.Status(zone, "quorum-2") == "" {
+		t.Fatalf("failed node must be kept without --delete-failed")
+	}
+}
+
+func TestStartMinReadyNotMet(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, brokenInserts{fakeCompute: compute, names: map[string]bool{"short-1": true, "short-2": true}})
+
+	result, err := service.Start(context.Background(), "short", StartOptions{NumInstances: 3, MinReady: 2})
+	if err == nil {
+		t.Fatalf("expected min-ready error")
+	}
+	if result == nil || result.ReadyCount() != 1 || len(result.Failed()) != 2 {
+		t.Fatalf("expected every node outcome to be collected, got %+v", result)
+	}
+}
+
+func TestStartDeleteFailedRemovesFailedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, brokenInserts{fakeCompute: compute, names: map[string]bool{"prune-2": true}})
+	zone := service.Config.Project.Zone
+
+	result, err := service.Start(context.Background(), "prune", StartOptions{NumInstances: 3, MinReady: 2, DeleteFailed: true})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].Name != "prune-2" || !failed[0].Deleted {
+		t.Fatalf("expected prune-2 to be reported deleted: %+v", failed)
+	}
+	if compute.Status(zone, "prune-2") != "" {
+		t.Fatalf("expected prune-2 to be deleted")
+	}
+	for _, name := range []string{"prune-0", "prune-1"} {
+		if compute.Status(zone, name) != "RUNNING" {
+			t.Fatalf("expected %s to keep running", name)
+		}
+	}
+}
@@ -1,13 +1,28 @@
 package cluster
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"hash/fnv"
 	"net"
+	"path"
+	"slices"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"gpunow/internal/gcp"
 )
 
+// DeriveSubnetCIDR returns the block of baseCIDR a cluster name hashes to.
 func DeriveSubnetCIDR(baseCIDR string, prefix int, clusterName string) (string, error) {
+	return AllocateSubnetCIDR(baseCIDR, prefix, clusterName, nil)
+}
+
+// AllocateSubnetCIDR returns the first block of baseCIDR, starting at the one
+// the cluster name hashes to and wrapping around, that overlaps none of used.
+// The result only depends on its arguments, so retries pick the same block.
+func AllocateSubnetCIDR(baseCIDR string, prefix int, clusterName string, used []string) (string, error) {
 	ip, ipNet, err := net.ParseCIDR(baseCIDR)
 	if err != nil {
 		return "", fmt.Errorf("invalid base CIDR: %w", err)
@@ -23,17 +38,137 @@ func DeriveSubnetCIDR(baseCIDR string, prefix int, clusterName string) (string,
 	if prefix > 30 {
 		return "", fmt.Errorf("subnet prefix must be <= 30")
 	}
+	usedNets := make([]*net.IPNet, 0, len(used))
+	for _, cidr := range used {
+		_, usedNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", fmt.Errorf("invalid subnet CIDR %q: %w", cidr, err)
+		}
+		usedNets = append(usedNets, usedNet)
+	}
 
 	count := 1 << (prefix - basePrefix)
 	h := fnv.New32a()
 	_, _ = h.Write([]byte(clusterName))
-	index := int(h.Sum32() % uint32(count))
+	start := int(h.Sum32() % uint32(count))
 
 	base := binary.BigEndian.Uint32(ip4)
 	blockSize := uint32(1) << (32 - prefix)
-	subnetIP := base + (uint32(index) * blockSize)
+	for i := 0; i < count; i++ {
+		index := (start + i) % count
+		out := make(net.IP, 4)
+		binary.BigEndian.PutUint32(out, base+uint32(index)*blockSize)
+		block := &net.IPNet{IP: out, Mask: net.CIDRMask(prefix, 32)}
+		if !overlapsAny(block, usedNets) {
+			return block.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free /%d block left in %s", prefix, baseCIDR)
+}
+
+// CIDRsOverlap reports whether two CIDRs share any address.
+func CIDRsOverlap(a, b string) (bool, error) {
+	_, aNet, err := net.ParseCIDR(a)
+	if err != nil {
+		return false, err
+	}
+	_, bNet, err := net.ParseCIDR(b)
+	if err != nil {
+		return false, err
+	}
+	return overlapsAny(aNet, []*net.IPNet{bNet}), nil
+}
+
+func overlapsAny(block *net.IPNet, nets []*net.IPNet) bool {
+	for _, other := range nets {
+		if block.Contains(other.IP) || other.Contains(block.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveStartNetwork resolves the cluster network and settles the subnet
+// ranges a start creates or expects.
+func (s *Service) resolveStartNetwork(ctx context.Context, clusterName string, opts StartOptions) (clusterNetwork, error) {
+	network, err := s.resolveClusterNetwork(clusterName)
+	if err != nil {
+		return clusterNetwork{}, err
+	}
+	if network.Existing {
+		return network, nil
+	}
+	cidr, err := s.clusterSubnetCIDR(ctx, clusterName, network, opts)
+	if err != nil {
+		return clusterNetwork{}, err
+	}
+	network.SubnetCIDR = cidr
+	if network.DualStack {
+		if network.SubnetIPv6CIDR, err = s.lookupSubnetIPv6Range(ctx, network); err != nil {
+			return clusterNetwork{}, err
+		}
+	}
+	return network, nil
+}
 
-	out := make(net.IP, 4)
-	binary.BigEndian.PutUint32(out, subnetIP)
-	return fmt.Sprintf("%s/%d", out.String(), prefix), nil
+// clusterSubnetCIDR returns opts.SubnetCIDR when pinned, the range of the
+// live subnet when it exists, and otherwise the first block from the hashed
+// one that overlaps no other gpunow subnet nor opts.ReservedSubnetCIDRs.
+func (s *Service) clusterSubnetCIDR(ctx context.Context, clusterName string, network clusterNetwork, opts StartOptions) (string, error) {
+	if opts.SubnetCIDR == "" {
+		subnet, err := s.Compute.GetSubnetwork(ctx, &computepb.GetSubnetworkRequest{Project: network.NetworkProject, Region: network.Region, Subnetwork: network.SubnetName})
+		if err == nil {
+			return subnet.GetIpCidrRange(), nil
+		}
+		if !gcp.IsNotFound(err) {
+			return "", err
+		}
+	}
+	used, err := s.otherSubnetCIDRs(ctx, clusterName, network, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.SubnetCIDR == "" {
+		return AllocateSubnetCIDR(s.Config.Cluster.SubnetCIDRBase, s.Config.Cluster.SubnetPrefix, clusterName, used)
+	}
+	for _, cidr := range used {
+		if overlap, _ := CIDRsOverlap(opts.SubnetCIDR, cidr); overlap {
+			return "", fmt.Errorf("subnet CIDR %s overlaps %s used by another gpunow cluster", opts.SubnetCIDR, cidr)
+		}
+	}
+	return opts.SubnetCIDR, nil
+}
+
+// otherSubnetCIDRs lists the ranges of gpunow subnets of other clusters in the
+// regions the cluster may start in, plus opts.ReservedSubnetCIDRs.
+func (s *Service) otherSubnetCIDRs(ctx context.Context, clusterName string, network clusterNetwork, opts StartOptions) ([]string, error) {
+	zones := opts.Zones
+	if len(zones) == 0 {
+		zones = s.Config.Zones()
+	}
+	regions := []string{network.Region}
+	for _, zone := range zones {
+		region, err := gcp.RegionFromZone(zone)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	used := append([]string{}, opts.ReservedSubnetCIDRs...)
+	for _, region := range regions {
+		subnets, err := gcp.Collect(s.Compute.ListSubnetworks(ctx, &computepb.ListSubnetworksRequest{Project: network.NetworkProject, Region: region}))
+		if err != nil {
+			return nil, fmt.Errorf("list subnetworks in %s: %w", region, err)
+		}
+		for _, subnet := range subnets {
+			owner := s.networkCluster(path.Base(subnet.GetNetwork()))
+			if owner == "" || owner == clusterName {
+				continue
+			}
+			used = append(used, subnet.GetIpCidrRange())
+		}
+	}
+	return used, nil
 }
@@ -1,8 +1,15 @@
 package cluster
 
 import (
+	"context"
 	"net"
+	"strings"
 	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/gcp"
 )
 
 func TestDeriveSubnetCIDR(t *testing.T) {
@@ -19,3 +26,112 @@ func TestDeriveSubnetCIDR(t *testing.T) {
 		t.Fatalf("derived subnet not within base: %s", cidr)
 	}
 }
+
+func TestAllocateSubnetCIDRSkipsUsedBlocks(t *testing.T) {
+	hashed, err := DeriveSubnetCIDR("10.200.0.0/16", 24, "my-cluster")
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	first, err := AllocateSubnetCIDR("10.200.0.0/16", 24, "my-cluster", []string{hashed})
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	if first == hashed {
+		t.Fatalf("expected a block other than the used %s", hashed)
+	}
+	again, err := AllocateSubnetCIDR("10.200.0.0/16", 24, "my-cluster", []string{hashed})
+	if err != nil || again != first {
+		t.Fatalf("expected allocation to be deterministic, got %s then %s (%v)", first, again, err)
+	}
+	// A wider used range covers several blocks at once.
+	if cidr, err := AllocateSubnetCIDR("10.200.0.0/16", 24, "my-cluster", []string{"10.200.0.0/17"}); err != nil || !strings.HasPrefix(cidr, "10.200.") {
+		t.Fatalf("allocate around /17: %s %v", cidr, err)
+	} else if overlap, _ := CIDRsOverlap(cidr, "10.200.0.0/17"); overlap {
+		t.Fatalf("allocated %s inside the used /17", cidr)
+	}
+	if _, err := AllocateSubnetCIDR("10.200.0.0/16", 24, "my-cluster", []string{"10.200.0.0/16"}); err == nil {
+		t.Fatalf("expected an error once the base is exhausted")
+	}
+}
+
+func TestStartAvoidsOtherClusterSubnets(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	region, err := gcp.RegionFromZone(service.Config.Project.Zone)
+	if err != nil {
+		t.Fatalf("region: %v", err)
+	}
+	hashed, err := DeriveSubnetCIDR(service.Config.Cluster.SubnetCIDRBase, service.Config.Cluster.SubnetPrefix, "beta")
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	if _, err := compute.InsertSubnetwork(context.Background(), &computepb.InsertSubnetworkRequest{
+		Region: region,
+		SubnetworkResource: &computepb.Subnetwork{
+			Name:        proto.String("gpunow-alpha-subnet"),
+			IpCidrRange: proto.String(hashed),
+			Network:     proto.String(gcp.GlobalResource(service.Config.Project.ID, "networks", "gpunow-alpha")),
+		},
+	}); err != nil {
+		t.Fatalf("seed subnet: %v", err)
+	}
+
+	result, err := service.Start(context.Background(), "beta", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if result.SubnetCIDR == "" || result.SubnetCIDR == hashed {
+		t.Fatalf("expected beta to avoid alpha's %s, got %q", hashed, result.SubnetCIDR)
+	}
+	network, err := service.resolveClusterNetwork("beta")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	subnet := compute.subnets[region+"/"+network.SubnetName]
+	if subnet.GetIpCidrRange() != result.SubnetCIDR {
+		t.Fatalf("subnet range = %s, want %s", subnet.GetIpCidrRange(), result.SubnetCIDR)
+	}
+	if got := compute.firewalls[network.InternalRule].GetSourceRanges(); len(got) != 1 || got[0] != result.SubnetCIDR {
+		t.Fatalf("internal rule ranges = %v", got)
+	}
+
+	// The live range wins over the hash once alpha is gone.
+	delete(compute.subnets, region+"/gpunow-alpha-subnet")
+	again, err := service.Start(context.Background(), "beta", StartOptions{NumInstances: 1})
+	if err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if again.SubnetCIDR != result.SubnetCIDR {
+		t.Fatalf("subnet range moved from %s to %s", result.SubnetCIDR, again.SubnetCIDR)
+	}
+
+	_, err = service.Start(context.Background(), "gamma", StartOptions{NumInstances: 1, SubnetCIDR: "10.200.0.0/16", ReservedSubnetCIDRs: []string{"10.200.7.0/24"}})
+	if err == nil || !strings.Contains(err.Error(), "overlaps") {
+		t.Fatalf("expected overlap error for a pinned range, got %v", err)
+	}
+	_, err = service.Start(context.Background(), "beta", StartOptions{NumInstances: 1, SubnetCIDR: "10.201.0.0/24"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be changed in place") {
+		t.Fatalf("expected error when pinning a different range on a live subnet, got %v", err)
+	}
+}
+
+func TestScaleAvoidsReservedSubnets(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	hashed, err := DeriveSubnetCIDR(service.Config.Cluster.SubnetCIDRBase, service.Config.Cluster.SubnetPrefix, "delta")
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+
+	if err := service.Scale(context.Background(), "delta", StartOptions{NumInstances: 1, ReservedSubnetCIDRs: []string{hashed}}); err != nil {
+		t.Fatalf("scale: %v", err)
+	}
+	network, err := service.resolveClusterNetwork("delta")
+	if err != nil {
+		t.Fatalf("resolve network: %v", err)
+	}
+	subnet := compute.subnets[network.Region+"/"+network.SubnetName]
+	if subnet == nil || subnet.GetIpCidrRange() == hashed {
+		t.Fatalf("expected scale to avoid the reserved %s, got %v", hashed, subnet)
+	}
+}
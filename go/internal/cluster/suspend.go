@@ -0,0 +1,178 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/instance"
+	"gpunow/internal/lifecycle"
+	"gpunow/internal/ui"
+	"gpunow/internal/validate"
+)
+
+// resumeReadinessTimeout bounds the readiness wait after a resume. The
+// sentinel keeps running in the restored memory, so it answers as soon as
+// networking is back instead of after first-boot provisioning.
+const resumeReadinessTimeout = 3 * time.Minute
+
+type SuspendOptions struct {
+	OnStateChange func(name, state, externalIP, internalIP string)
+}
+
+// Suspend suspends every running node of the cluster. Suspended nodes keep
+// their memory, processes and disks; nodes in any other state are skipped.
+func (s *Service) Suspend(ctx context.Context, clusterName string, opts SuspendOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		s.UI.Infof("No instances found for cluster %s", clusterName)
+		return nil
+	}
+	progress := s.UI.TaskList("Suspending", instanceNames(instances))
+	defer progress.Stop()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for idx, inst := range instances {
+		group.Go(func() error {
+			return s.suspendNode(groupCtx, inst, opts.OnStateChange, progress, idx)
+		})
+	}
+	return group.Wait()
+}
+
+func (s *Service) suspendNode(ctx context.Context, inst *computepb.Instance, onStateChange func(name, state, externalIP, internalIP string), progress *ui.TaskList, progressIndex int) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	name := inst.GetName()
+
+	switch inst.GetStatus() {
+	case "RUNNING":
+	case "SUSPENDED":
+		s.updateInstanceState(onStateChange, name, lifecycle.InstanceStateSuspended, "", "")
+		progress.MarkDone(progressIndex, fmt.Sprintf("Already suspended %s", name))
+		return nil
+	default:
+		progress.MarkWarning(progressIndex, fmt.Sprintf("Skipped %s (%s)", name, inst.GetStatus()))
+		return nil
+	}
+
+	call := s.api("compute.instances.suspend", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Suspending %s", name))
+	op, err := s.Compute.SuspendInstance(ctx, &computepb.SuspendInstanceRequest{
+		Project:         project,
+		Zone:            zone,
+		Instance:        name,
+		DiscardLocalSsd: instance.DiscardLocalSSD(inst),
+	})
+	if err != nil {
+		call.Stop()
+		return fmt.Errorf("suspend %s: %w", name, err)
+	}
+	// Only recorded once GCE accepted the call: a refused suspend leaves
+	// the node running.
+	s.updateInstanceState(onStateChange, name, lifecycle.InstanceStateSuspending, "", "")
+	if err := s.waitWithProgress(ctx, call, op, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+		return err
+	}
+	s.updateInstanceState(onStateChange, name, lifecycle.InstanceStateSuspended, "", "")
+	progress.MarkDone(progressIndex, fmt.Sprintf("Suspended %s", name))
+	return nil
+}
+
+// Resume resumes every suspended node of the cluster and waits for their
+// sentinels, within opts.ReadinessTimeout (default resumeReadinessTimeout).
+// Nodes that are not suspended are left alone.
+func (s *Service) Resume(ctx context.Context, clusterName string, opts StartOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances found for cluster %s", clusterName)
+	}
+	if opts.ReadinessTimeout <= 0 {
+		opts.ReadinessTimeout = resumeReadinessTimeout
+	}
+	masterName := ""
+	for _, inst := range instances {
+		if isMaster(inst) {
+			masterName = inst.GetName()
+		}
+	}
+	progress := s.UI.TaskList("Resuming", instanceNames(instances))
+	defer progress.Stop()
+	readiness := s.newClusterReadiness(masterName, opts, progress)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for idx, inst := range instances {
+		group.Go(func() error {
+			return s.resumeNode(groupCtx, inst, opts, progress, idx, readiness)
+		})
+	}
+	return group.Wait()
+}
+
+func (s *Service) resumeNode(ctx context.Context, inst *computepb.Instance, opts StartOptions, progress *ui.TaskList, progressIndex int, readiness *clusterReadiness) error {
+	name := inst.GetName()
+	switch inst.GetStatus() {
+	case "SUSPENDED":
+	case "RUNNING":
+		externalIP, internalIP := instanceIPs(inst)
+		s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+		progress.MarkDone(progressIndex, fmt.Sprintf("Already running %s", name))
+		return nil
+	case "SUSPENDING":
+		progress.MarkWarning(progressIndex, fmt.Sprintf("Skipped %s (SUSPENDING); rerun gpunow resume once it is suspended", name))
+		return nil
+	case "TERMINATED":
+		progress.MarkWarning(progressIndex, fmt.Sprintf("Skipped %s (TERMINATED); use gpunow start", name))
+		return nil
+	default:
+		progress.MarkWarning(progressIndex, fmt.Sprintf("Skipped %s (%s)", name, inst.GetStatus()))
+		return nil
+	}
+
+	if err := s.resumeInstance(ctx, name, func(p int32) { progress.Update(progressIndex, p) }); err != nil {
+		return err
+	}
+	refreshed, err := s.getInstance(ctx, name)
+	if err != nil {
+		return err
+	}
+	externalIP, internalIP := instanceIPs(refreshed)
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateStarting, externalIP, internalIP)
+	if err := readiness.Wait(ctx, name, externalIP, internalIP, progressIndex); err != nil {
+		return err
+	}
+	s.updateInstanceState(opts.OnStateChange, name, lifecycle.InstanceStateReady, externalIP, internalIP)
+	progress.MarkDone(progressIndex, fmt.Sprintf("Resumed %s", name))
+	return nil
+}
+
+func (s *Service) resumeInstance(ctx context.Context, name string, onProgress func(int32)) error {
+	project := s.Config.Project.ID
+	zone := s.Config.Project.Zone
+	call := s.api("compute.instances.resume", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Resuming %s", name))
+	op, err := s.Compute.ResumeInstance(ctx, &computepb.ResumeInstanceRequest{
+		Project:  project,
+		Zone:     zone,
+		Instance: name,
+	})
+	if err != nil {
+		call.Stop()
+		return fmt.Errorf("resume %s: %w", name, err)
+	}
+	return s.waitWithProgress(ctx, call, op, onProgress)
+}
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gpunow/internal/lifecycle"
+)
+
+func TestSuspendAndResumeCluster(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "nap", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	var mu sync.Mutex
+	states := map[string][]string{}
+	record := func(name, state, externalIP, internalIP string) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[name] = append(states[name], state)
+	}
+	if err := service.Suspend(context.Background(), "nap", SuspendOptions{OnStateChange: record}); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	for _, name := range []string{"nap-0", "nap-1"} {
+		if got := compute.Status(zone, name); got != "SUSPENDED" {
+			t.Fatalf("%s status = %s", name, got)
+		}
+	}
+	want := []string{lifecycle.InstanceStateSuspending, lifecycle.InstanceStateSuspended}
+	if !reflect.DeepEqual(states["nap-0"], want) {
+		t.Fatalf("suspend states = %v, want %v", states["nap-0"], want)
+	}
+
+	states = map[string][]string{}
+	compute.calls = nil
+	if err := service.Resume(context.Background(), "nap", StartOptions{OnStateChange: record}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if got := compute.Calls(); !reflect.DeepEqual(got, []string{"resume nap-0", "resume nap-1"}) {
+		t.Fatalf("resume calls = %v", got)
+	}
+	// A resumed node goes straight back to READY without first-boot
+	// provisioning.
+	want = []string{lifecycle.InstanceStateStarting, lifecycle.InstanceStateReady}
+	if !reflect.DeepEqual(states["nap-1"], want) {
+		t.Fatalf("resume states = %v, want %v", states["nap-1"], want)
+	}
+}
+
+func TestRefusedSuspendKeepsNodeState(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "gpu", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	compute.unsuspendable = map[string]bool{"gpu-0": true}
+
+	states := []string{}
+	record := func(name, state, externalIP, internalIP string) {
+		states = append(states, state)
+	}
+	if err := service.Suspend(context.Background(), "gpu", SuspendOptions{OnStateChange: record}); err == nil {
+		t.Fatalf("expected the refused suspend to fail")
+	}
+	if len(states) != 0 {
+		t.Fatalf("expected no state change for a refused suspend, got %v", states)
+	}
+	if got := compute.Status(zone, "gpu-0"); got != "RUNNING" {
+		t.Fatalf("status = %s", got)
+	}
+}
+
+func TestStartResumesSuspendedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	if _, err := service.Start(context.Background(), "wake", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := service.Suspend(context.Background(), "wake", SuspendOptions{}); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	compute.calls = nil
+	if _, err := service.Start(context.Background(), "wake", StartOptions{NumInstances: 1}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if got := compute.Calls(); !reflect.DeepEqual(got, []string{"resume wake-0"}) {
+		t.Fatalf("expected start to resume the suspended node, got %v", got)
+	}
+}
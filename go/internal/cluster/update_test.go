@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateMachineTypeRequiresStoppedNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "grow", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	opts := UpdateOptions{MachineType: "g2-standard-48", TerminationAction: "delete"}
+	err := service.Update(context.Background(), "grow", opts)
+	if err == nil || !strings.Contains(err.Error(), "grow-0 (RUNNING)") {
+		t.Fatalf("expected running nodes to be refused, got %v", err)
+	}
+
+	if err := service.Stop(context.Background(), "grow", StopOptions{}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	compute.calls = nil
+	if err := service.Update(context.Background(), "grow", opts); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := []string{"setMachineType grow-0", "setMachineType grow-1", "setScheduling grow-0", "setScheduling grow-1"}
+	if got := compute.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	inst := compute.get(zone, "grow-1")
+	if !strings.HasSuffix(inst.GetMachineType(), "/machineTypes/g2-standard-48") {
+		t.Fatalf("machine type = %s", inst.GetMachineType())
+	}
+	if got := inst.GetScheduling().GetInstanceTerminationAction(); got != "DELETE" {
+		t.Fatalf("termination action = %s", got)
+	}
+}
+
+func TestUpdateLabelsAndMetadataOnRunningNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	if _, err := service.Start(context.Background(), "tag", StartOptions{NumInstances: 2}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// The first label write of each node loses a race with another writer
+	// and is retried with a fresh fingerprint.
+	compute.labelRaces = 2
+	opts := UpdateOptions{
+		Labels:   map[string]string{"team": "vision"},
+		Metadata: map[string]string{"wandb-project": "sweep-7"},
+		Nodes:    []string{"tag-1"},
+	}
+	if err := service.Update(context.Background(), "tag", opts); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	inst := compute.get(zone, "tag-1")
+	if inst.GetLabels()["team"] != "vision" || inst.GetLabels()["cluster_role"] != "worker" {
+		t.Fatalf("labels = %v", inst.GetLabels())
+	}
+	metadata := map[string]string{}
+	for _, item := range inst.GetMetadata().GetItems() {
+		metadata[item.GetKey()] = item.GetValue()
+	}
+	if metadata["wandb-project"] != "sweep-7" || metadata["user-data"] == "" {
+		t.Fatalf("expected metadata to be merged, got keys %v", sortedMapKeys(metadata))
+	}
+	if _, ok := compute.get(zone, "tag-0").GetLabels()["team"]; ok {
+		t.Fatalf("expected only the selected node to change")
+	}
+
+	err := service.Update(context.Background(), "tag", UpdateOptions{Labels: map[string]string{"cluster_role": "master"}})
+	if err == nil || !strings.Contains(err.Error(), "managed by gpunow") {
+		t.Fatalf("expected reserved label error, got %v", err)
+	}
+}
+
+func TestStartAppliesClusterLabelsAndMetadata(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	opts := StartOptions{
+		NumInstances: 1,
+		Labels:       map[string]string{"team": "vision", "cluster": "spoof"},
+		Metadata:     map[string]string{"wandb-project": "sweep-7"},
+	}
+	if _, err := service.Start(context.Background(), "lbl", opts); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	inst := compute.get(service.Config.Project.Zone, "lbl-0")
+	if inst.GetLabels()["team"] != "vision" || inst.GetLabels()["cluster"] != "lbl" {
+		t.Fatalf("labels = %v", inst.GetLabels())
+	}
+	result, err := service.Reconcile(context.Background(), "lbl", ReconcileOptions{Start: opts})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(result.Drifts) != 0 {
+		t.Fatalf("expected no drift, got %+v", result.Drifts)
+	}
+}
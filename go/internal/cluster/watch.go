@@ -0,0 +1,184 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/validate"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+const (
+	RecoveryActionRestart  = "restart"
+	RecoveryActionRecreate = "recreate"
+)
+
+type WatchOptions struct {
+	// Start carries the node count and the settings used to restart or
+	// recreate nodes, exactly as Start would.
+	Start    StartOptions
+	Interval time.Duration
+	// ShouldRecover reports whether a node that is not running is expected to
+	// be up, i.e. it was READY and was not stopped on purpose. Nil recovers
+	// every node that is down.
+	ShouldRecover func(name string) bool
+	// OnRecovery is called once per recovery attempt, after it finishes.
+	OnRecovery func(event RecoveryEvent)
+}
+
+// RecoveryEvent describes one attempt to bring a preempted or missing node
+// back to READY.
+type RecoveryEvent struct {
+	Instance  string
+	Index     int
+	Action    string
+	Reason    string
+	StartedAt time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// Watch polls the cluster every opts.Interval and recovers nodes that went
+// down underneath it, until ctx is cancelled. Poll errors are reported and
+// retried on the next tick.
+func (s *Service) Watch(ctx context.Context, clusterName string, opts WatchOptions) error {
+	if !validate.IsResourceName(clusterName) {
+		return fmt.Errorf("invalid cluster name: %s", clusterName)
+	}
+	if opts.Start.NumInstances <= 0 {
+		return fmt.Errorf("num-instances must be >= 1")
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	s.UI.Infof("Watching cluster %s every %s (Ctrl-C to stop)", clusterName, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RecoverOnce(ctx, clusterName, opts); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			s.UI.Warnf("Watch poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type recoveryCandidate struct {
+	node   clusterNode
+	action string
+	reason string
+}
+
+// RecoverOnce lists the cluster once and restarts TERMINATED nodes or
+// recreates missing ones under the same name, waiting for readiness again.
+// A failed recovery is reported in its event and does not stop the others.
+func (s *Service) RecoverOnce(ctx context.Context, clusterName string, opts WatchOptions) ([]RecoveryEvent, error) {
+	if opts.Start.NumInstances <= 0 {
+		return nil, fmt.Errorf("num-instances must be >= 1")
+	}
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := s.layoutNodes(clusterName, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	existing := instancesByName(instances)
+
+	candidates := []recoveryCandidate{}
+	for _, node := range layout {
+		candidate := recoveryCandidate{node: node}
+		inst := existing[node.Name]
+		switch {
+		case inst == nil:
+			candidate.action = RecoveryActionRecreate
+			candidate.reason = "MISSING"
+		case needsRecovery(inst.GetStatus()):
+			candidate.action = RecoveryActionRestart
+			candidate.reason = inst.GetStatus()
+		default:
+			continue
+		}
+		if opts.ShouldRecover != nil && !opts.ShouldRecover(node.Name) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	split := s.UI.StartLiveSplit()
+	if split != nil {
+		defer split.Stop()
+	}
+	network, err := s.resolveStartNetwork(ctx, clusterName, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]clusterNode, 0, len(candidates))
+	for _, candidate := range candidates {
+		nodes = append(nodes, candidate.node)
+	}
+	cloudInits, err := s.renderCloudInits(nodes, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	taskNames := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		taskNames = append(taskNames, fmt.Sprintf("instance %s (%s)", candidate.node.Name, candidate.reason))
+	}
+	progress := s.UI.TaskList("Recovering", taskNames)
+	readiness := s.newClusterReadiness(layout[0].Name, opts.Start, progress)
+
+	events := make([]RecoveryEvent, len(candidates))
+	var group errgroup.Group
+	for i, candidate := range candidates {
+		i, candidate := i, candidate
+		group.Go(func() error {
+			started := time.Now()
+			err := s.startNode(ctx, candidate.node, network, cloudInits[candidate.node.Pool], opts.Start, progress, i, readiness)
+			if err != nil {
+				progress.MarkWarning(i, fmt.Sprintf("Failed to recover %s: %v", candidate.node.Name, err))
+			}
+			events[i] = RecoveryEvent{
+				Instance:  candidate.node.Name,
+				Index:     candidate.node.Index,
+				Action:    candidate.action,
+				Reason:    candidate.reason,
+				StartedAt: started,
+				Duration:  time.Since(started),
+				Err:       err,
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+	progress.Stop()
+
+	if opts.OnRecovery != nil {
+		for _, event := range events {
+			opts.OnRecovery(event)
+		}
+	}
+	return events, nil
+}
+
+// needsRecovery reports whether a compute status means the node is down and
+// will not come back on its own. Spot preemption leaves nodes TERMINATED.
+func needsRecovery(status string) bool {
+	return status == "TERMINATED"
+}
@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRecoverOnceRestartsAndRecreatesNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	for _, inst := range []struct {
+		index  int
+		status string
+	}{{0, "RUNNING"}, {1, "TERMINATED"}, {3, "STOPPING"}} {
+		compute.put(zone, fakeClusterInstance(service, "train", inst.index, inst.status))
+	}
+
+	var recorded []RecoveryEvent
+	events, err := service.RecoverOnce(context.Background(), "train", WatchOptions{
+		Start:      StartOptions{NumInstances: 4},
+		OnRecovery: func(event RecoveryEvent) { recorded = append(recorded, event) },
+	})
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if len(events) != 2 || len(recorded) != 2 {
+		t.Fatalf("expected 2 recovery events, got %+v", events)
+	}
+	byName := map[string]RecoveryEvent{}
+	for _, event := range events {
+		if event.Err != nil {
+			t.Fatalf("unexpected recovery error for %s: %v", event.Instance, event.Err)
+		}
+		byName[event.Instance] = event
+	}
+	if got := byName["train-1"]; got.Action != RecoveryActionRestart || got.Reason != "TERMINATED" || got.Index != 1 {
+		t.Fatalf("unexpected event for train-1: %+v", got)
+	}
+	if got := byName["train-2"]; got.Action != RecoveryActionRecreate || got.Reason != "MISSING" || got.Index != 2 {
+		t.Fatalf("unexpected event for train-2: %+v", got)
+	}
+	if want := []string{"insert " + zone + "/train-2", "start train-1"}; !reflect.DeepEqual(compute.Calls(), want) {
+		t.Fatalf("unexpected compute calls: %v, want %v", compute.Calls(), want)
+	}
+	if compute.Status(zone, "train-2") != "RUNNING" {
+		t.Fatalf("expected train-2 to be recreated")
+	}
+	if labels := compute.get(zone, "train-2").GetLabels(); labels["cluster_index"] != "2" {
+		t.Fatalf("expected recreated node to keep index label: %v", labels)
+	}
+}
+
+func TestRecoverOnceSkipsNodesNotExpectedRunning(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	zone := service.Config.Project.Zone
+	for i := 0; i < 2; i++ {
+		compute.put(zone, fakeClusterInstance(service, "idle", i, "TERMINATED"))
+	}
+
+	events, err := service.RecoverOnce(context.Background(), "idle", WatchOptions{
+		Start:         StartOptions{NumInstances: 2},
+		ShouldRecover: func(name string) bool { return name == "idle-1" },
+	})
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if len(events) != 1 || events[0].Instance != "idle-1" {
+		t.Fatalf("expected only idle-1 to be recovered, got %+v", events)
+	}
+	if compute.Status(zone, "idle-0") != "TERMINATED" {
+		t.Fatalf("idle-0 should have been left alone")
+	}
+}
+
+func TestNeedsRecovery(t *testing.T) {
+	tests := map[string]bool{
+		"TERMINATED": true,
+		"RUNNING":    false,
+		"STOPPING":   false,
+		"SUSPENDING": false,
+		"SUSPENDED":  false,
+		"STAGING":    false,
+	}
+	for status, want := range tests {
+		if got := needsRecovery(status); got != want {
+			t.Errorf("needsRecovery(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"gpunow/internal/gcp"
+	"gpunow/internal/instance"
+)
+
+// inZone returns a copy of the service whose config and builder target zone.
+func (s *Service) inZone(zone string) *Service {
+	if zone == "" || zone == s.Config.Project.Zone {
+		return s
+	}
+	cfg := s.Config.WithZone(zone)
+	clone := *s
+	clone.Config = cfg
+	clone.Builder = instance.NewBuilder(cfg)
+	return &clone
+}
+
+// startExhausted reports whether a failed start ran out of zone capacity,
+// either for the whole attempt or for any individual node.
+func startExhausted(result *StartResult, err error) bool {
+	if gcp.IsResourceExhausted(err) {
+		return true
+	}
+	for _, node := range result.Failed() {
+		if gcp.IsResourceExhausted(node.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinClusterZone narrows zones to the one candidate zone that already holds
+// cluster nodes, so a cluster partially created by an earlier start is
+// resumed where it is instead of copied into another zone. pinned reports
+// whether nodes were found. Nodes in more than one zone are an error.
+func (s *Service) pinClusterZone(ctx context.Context, clusterName string, zones []string) ([]string, bool, error) {
+	found := []string{}
+	for _, zone := range zones {
+		instances, err := s.inZone(zone).listClusterInstances(ctx, clusterName)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(instances) > 0 {
+			found = append(found, zone)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return zones, false, nil
+	case 1:
+		return found, true, nil
+	default:
+		return nil, false, fmt.Errorf("cluster %s has instances in several zones (%s); delete the extra copy first", clusterName, strings.Join(found, ", "))
+	}
+}
+
+// abandonZone removes what a failed start left in the service's zone before
+// the cluster is retried in nextZone: every cluster instance (with its disks)
+// and, when the region changes, the regional subnet so its CIDR can be reused,
+// the NAT router and the regional placement policy.
+func (s *Service) abandonZone(ctx context.Context, clusterName, nextZone string, opts StartOptions) error {
+	instances, err := s.listClusterInstances(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(instances) > 0 {
+		progress := s.UI.TaskList("Cleaning up", instanceNames(instances))
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i, inst := range instances {
+			i, inst := i, inst
+			group.Go(func() error {
+				return s.deleteNode(groupCtx, inst, true, opts.OnStateChange, progress, i)
+			})
+		}
+		err := group.Wait()
+		progress.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	network, err := s.resolveClusterNetwork(clusterName)
+	if err != nil {
+		return err
+	}
+	nextRegion, err := gcp.RegionFromZone(nextZone)
+	if err != nil {
+		return err
+	}
+	if nextRegion == network.Region {
+		return nil
+	}
+	if network.RouterName != "" {
+		if err := s.deleteRouter(ctx, network.NetworkProject, network.Region, network.RouterName); err != nil {
+			return err
+		}
+	}
+	if !network.Existing {
+		if err := s.deleteSubnetwork(ctx, network.NetworkProject, network.Region, network.SubnetName); err != nil {
+			return err
+		}
+	}
+	if network.PlacementPolicy == "" {
+		return nil
+	}
+	return s.deletePlacementPolicy(ctx, network.Project, network.Region, network.PlacementPolicy)
+}
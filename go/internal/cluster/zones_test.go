@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/googleapi"
+
+	"gpunow/internal/gcp"
+)
+
+func TestStartFallsBackToNextZoneOnStockout(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	compute.exhausted["us-east1-d"] = true
+
+	result, err := service.Start(context.Background(), "burst", StartOptions{
+		NumInstances: 2,
+		Zones:        []string{"us-east1-d", "us-central1-a"},
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if result.Zone != "us-central1-a" || result.ReadyCount() != 2 {
+		t.Fatalf("unexpected result: zone=%s ready=%d", result.Zone, result.ReadyCount())
+	}
+	for _, name := range []string{"burst-0", "burst-1"} {
+		if compute.Status("us-central1-a", name) != "RUNNING" {
+			t.Fatalf("expected %s running in fallback zone", name)
+		}
+	}
+	network := service.clusterNetworkName("burst")
+	subnet := service.clusterSubnetName(network)
+	resources := map[string]bool{}
+	for _, key := range compute.Resources() {
+		resources[key] = true
+	}
+	if resources["subnetworks/us-east1/"+subnet] {
+		t.Fatalf("expected subnet in abandoned region to be deleted: %v", compute.Resources())
+	}
+	if !resources["subnetworks/us-central1/"+subnet] {
+		t.Fatalf("expected subnet in fallback region: %v", compute.Resources())
+	}
+}
+
+func TestStartDoesNotFallBackWithExistingNodes(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	compute.exhausted["us-east1-d"] = true
+	compute.put("us-east1-d", fakeClusterInstance(service, "pinned", 0, "RUNNING"))
+
+	result, err := service.Start(context.Background(), "pinned", StartOptions{
+		NumInstances: 2,
+		Zones:        []string{"us-east1-d", "us-central1-a"},
+	})
+	if err == nil {
+		t.Fatalf("expected stockout error")
+	}
+	if result == nil || result.Zone != "us-east1-d" {
+		t.Fatalf("expected attempt to stay in us-east1-d, got %+v", result)
+	}
+	if compute.Status("us-central1-a", "pinned-1") != "" {
+		t.Fatalf("cluster with existing nodes must not move zones")
+	}
+}
+
+// nodeStockout runs out of capacity for one instance in one zone.
+type nodeStockout struct {
+	*fakeCompute
+	zone, name string
+}
+
+func (f nodeStockout) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (gcp.Operation, error) {
+	if req.GetZone() == f.zone && req.GetInstanceResource().GetName() == f.name {
+		return nil, &googleapi.Error{Code: 503, Message: "ZONE_RESOURCE_POOL_EXHAUSTED"}
+	}
+	return f.fakeCompute.InsertInstance(ctx, req)
+}
+
+func TestStartFallbackDeletesPartialCluster(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, nodeStockout{fakeCompute: compute, zone: "us-east1-d", name: "half-1"})
+
+	result, err := service.Start(context.Background(), "half", StartOptions{
+		NumInstances: 2,
+		Zones:        []string{"us-east1-d", "us-central1-a"},
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if result.Zone != "us-central1-a" {
+		t.Fatalf("expected fallback zone, got %s", result.Zone)
+	}
+	if compute.Status("us-east1-d", "half-0") != "" {
+		t.Fatalf("expected the partial copy in us-east1-d to be deleted")
+	}
+}
+
+func TestStartResumesClusterInLaterCandidateZone(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	compute.put("us-central1-a", fakeClusterInstance(service, "moved", 0, "TERMINATED"))
+
+	result, err := service.Start(context.Background(), "moved", StartOptions{
+		NumInstances: 2,
+		Zones:        []string{"us-east1-d", "us-central1-a"},
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if result.Zone != "us-central1-a" {
+		t.Fatalf("expected the cluster to stay in us-central1-a, got %s", result.Zone)
+	}
+	for _, name := range []string{"moved-0", "moved-1"} {
+		if compute.Status("us-east1-d", name) != "" {
+			t.Fatalf("expected no copy of %s in us-east1-d", name)
+		}
+		if compute.Status("us-central1-a", name) != "RUNNING" {
+			t.Fatalf("expected %s running in us-central1-a", name)
+		}
+	}
+}
+
+func TestStartRefusesClusterInSeveralZones(t *testing.T) {
+	compute := newFakeCompute()
+	service := newTestService(t, compute)
+	compute.put("us-east1-d", fakeClusterInstance(service, "split", 0, "TERMINATED"))
+	compute.put("us-central1-a", fakeClusterInstance(service, "split", 1, "TERMINATED"))
+
+	if _, err := service.Start(context.Background(), "split", StartOptions{
+		NumInstances: 2,
+		Zones:        []string{"us-east1-d", "us-central1-a"},
+	}); err == nil {
+		t.Fatalf("expected error for a cluster in several zones")
+	}
+	if compute.Status("us-east1-d", "split-1") != "" || compute.Status("us-central1-a", "split-0") != "" {
+		t.Fatalf("no instances should be created")
+	}
+}
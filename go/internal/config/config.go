@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/go-playground/validator/v10"
 
+	"gpunow/internal/gcp"
+	"gpunow/internal/parse"
 	"gpunow/internal/validate"
 )
 
@@ -25,6 +28,7 @@ type Config struct {
 	Shielded       ShieldedConfig       `toml:"shielded"`
 	Reservation    ReservationConfig    `toml:"reservation"`
 	SSH            SSHConfig            `toml:"ssh"`
+	Hooks          HooksConfig          `toml:"hooks"`
 	Files          FilesConfig          `toml:"files" validate:"required"`
 	Metadata       map[string]string    `toml:"metadata"`
 	Paths          Paths                `toml:"-"`
@@ -38,17 +42,49 @@ type Paths struct {
 	SetupScript      string
 	ZshrcFile        string
 	ProfilesBasePath string
+	// PoolSetupScripts maps pool names to their resolved setup_script, for
+	// pools that override files.setup_script.
+	PoolSetupScripts map[string]string
 }
 
 type ProjectConfig struct {
-	ID   string `toml:"id" validate:"required"`
-	Zone string `toml:"zone" validate:"required"`
+	ID            string   `toml:"id" validate:"required"`
+	Zone          string   `toml:"zone" validate:"required"`
+	FallbackZones []string `toml:"fallback_zones" validate:"dive,required"`
 }
 
 type ClusterConfig struct {
 	NetworkNamePrefix string `toml:"network_name_prefix" validate:"required"`
 	SubnetCIDRBase    string `toml:"subnet_cidr_base" validate:"required,cidr"`
 	SubnetPrefix      int    `toml:"subnet_prefix" validate:"gte=8,lte=30"`
+	Placement         string `toml:"placement" validate:"omitempty,oneof=compact"`
+	// NetworkMode is "per_cluster" (a VPC and subnet per cluster) or
+	// "existing" (nodes join Network/Subnetwork, which may be a Shared VPC
+	// subnet of NetworkProject).
+	NetworkMode    string `toml:"network_mode" validate:"oneof=per_cluster existing"`
+	Network        string `toml:"network"`
+	Subnetwork     string `toml:"subnetwork"`
+	NetworkProject string `toml:"network_project"`
+	// PrivateWorkers gives only the master a public IP; workers egress
+	// through a per-cluster Cloud NAT and are reached through the master.
+	PrivateWorkers bool `toml:"private_workers"`
+	// Pools splits cluster nodes into named groups. The first pool holds the
+	// master node. Empty when every node shares the instance settings.
+	Pools []PoolConfig `toml:"pools" validate:"dive"`
+	// SharedDatasetDisk is an existing disk in the cluster zone, or a
+	// snapshot ("snapshots/<name>" or a full snapshot path) to create one
+	// from, attached read-only to every node.
+	SharedDatasetDisk string `toml:"shared_dataset_disk"`
+}
+
+// PoolConfig declares a named group of cluster nodes. Unset fields fall back
+// to instance.machine_type, disk.size_gb and files.setup_script.
+type PoolConfig struct {
+	Name        string `toml:"name" validate:"required"`
+	Count       int    `toml:"count" validate:"gte=1"`
+	MachineType string `toml:"machine_type"`
+	DiskSizeGB  int    `toml:"disk_size_gb" validate:"gte=0"`
+	SetupScript string `toml:"setup_script"`
 }
 
 type InstanceConfig struct {
@@ -64,10 +100,20 @@ type InstanceConfig struct {
 
 type NetworkConfig struct {
 	DefaultNetwork string   `toml:"default_network" validate:"required"`
-	StackType      string   `toml:"stack_type" validate:"required"`
+	StackType      string   `toml:"stack_type" validate:"required,oneof=IPV4_ONLY IPV4_IPV6"`
 	NetworkTier    string   `toml:"network_tier" validate:"required"`
-	Ports          []int    `toml:"ports" validate:"min=1,dive,gt=0,lte=65535"`
 	TagsBase       []string `toml:"tags_base" validate:"min=1,dive,required"`
+	// Ports entries are port numbers, spec strings such as
+	// "60000-61000/udp", or tables with per-entry source_ranges; see
+	// parse.PortSpec.
+	Ports []parse.PortSpec `toml:"ports" validate:"min=1"`
+	// SSHSourceRanges and PortSourceRanges are the CIDRs allowed to reach
+	// SSH and the exposed ports. Both default to 0.0.0.0/0.
+	SSHSourceRanges  []string `toml:"ssh_source_ranges" validate:"dive,cidr"`
+	PortSourceRanges []string `toml:"port_source_ranges" validate:"dive,cidr"`
+	// MyIPURL returns the caller's public address as plain text; used by
+	// --my-ip.
+	MyIPURL string `toml:"my_ip_url" validate:"required,url"`
 }
 
 type DiskConfig struct {
@@ -77,8 +123,45 @@ type DiskConfig struct {
 	Type       string `toml:"type" validate:"required"`
 	Mode       string `toml:"mode" validate:"required"`
 	Image      string `toml:"image" validate:"required"`
+	// Extra disks are attached to every node next to the boot disk.
+	Extra []ExtraDiskConfig `toml:"extra" validate:"dive"`
+}
+
+// ExtraDiskConfig declares a data disk attached to every node: a persistent
+// disk named <instance>-<name>, or Count local SSDs (375 GB each) when Type
+// is "local-ssd". Disks with a MountPath are formatted and mounted by
+// cloud-init; several local SSDs in one entry are striped into one array.
+type ExtraDiskConfig struct {
+	Name   string `toml:"name" validate:"required"`
+	Type   string `toml:"type" validate:"required"`
+	SizeGB int    `toml:"size_gb" validate:"gte=0"`
+	// KeepOnDelete keeps a persistent disk when its node is deleted, so a
+	// recreated node (reimage, watch) gets the same data back.
+	KeepOnDelete bool   `toml:"keep_on_delete"`
+	Count        int    `toml:"count" validate:"gte=0"`
+	Interface    string `toml:"interface" validate:"omitempty,oneof=NVME SCSI"`
+	MountPath    string `toml:"mount_path"`
+}
+
+// LocalSSD reports whether the entry declares local SSDs rather than a
+// persistent disk.
+func (d ExtraDiskConfig) LocalSSD() bool {
+	return d.Type == LocalSSDType
 }
 
+// DatasetDeviceName and DatasetMountPath are the device name and mount
+// point of cluster.shared_dataset_disk on every node.
+const (
+	DatasetDeviceName = "dataset"
+	DatasetMountPath  = "/mnt/dataset"
+)
+
+// LocalSSDType is the disk type of local NVMe/SCSI scratch SSDs.
+const LocalSSDType = "local-ssd"
+
+// LocalSSDSizeGB is the fixed size of one local SSD.
+const LocalSSDSizeGB = 375
+
 type ServiceAccountConfig struct {
 	Email  string   `toml:"email"`
 	Scopes []string `toml:"scopes"`
@@ -99,6 +182,15 @@ type SSHConfig struct {
 	IdentityFile string `toml:"identity_file"`
 }
 
+type HooksConfig struct {
+	// PreStop commands run over SSH on every running node before a cluster
+	// is stopped or deleted, e.g. to sync checkpoints to GCS.
+	PreStop []string `toml:"pre_stop" validate:"dive,required"`
+	// PreStopTimeoutSeconds bounds each node's hooks; 0 (or unset) means
+	// the default of 600.
+	PreStopTimeoutSeconds int `toml:"pre_stop_timeout_seconds" validate:"gte=0"`
+}
+
 type FilesConfig struct {
 	CloudInit   string `toml:"cloud_init" validate:"required"`
 	SetupScript string `toml:"setup_script" validate:"required"`
@@ -139,6 +231,12 @@ func Load(profile string, baseDir string) (*Config, error) {
 		SetupScript:      filepath.Join(cfgDir, cfg.Files.SetupScript),
 		ZshrcFile:        filepath.Join(cfgDir, cfg.Files.Zshrc),
 		ProfilesBasePath: baseDir,
+		PoolSetupScripts: map[string]string{},
+	}
+	for _, pool := range cfg.Cluster.Pools {
+		if pool.SetupScript != "" {
+			cfg.Paths.PoolSetupScripts[pool.Name] = filepath.Join(cfgDir, pool.SetupScript)
+		}
 	}
 
 	if err := validateConfig(&cfg); err != nil {
@@ -148,6 +246,49 @@ func Load(profile string, baseDir string) (*Config, error) {
 	return &cfg, nil
 }
 
+// Zones returns the primary zone followed by the fallback zones, without
+// duplicates.
+func (c *Config) Zones() []string {
+	zones := []string{}
+	seen := map[string]bool{}
+	for _, zone := range append([]string{c.Project.Zone}, c.Project.FallbackZones...) {
+		zone = strings.TrimSpace(zone)
+		if zone == "" || seen[zone] {
+			continue
+		}
+		seen[zone] = true
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+// Pool returns the pool named name from cluster.pools.
+func (c *Config) Pool(name string) (PoolConfig, bool) {
+	for _, pool := range c.Cluster.Pools {
+		if pool.Name == name {
+			return pool, true
+		}
+	}
+	return PoolConfig{}, false
+}
+
+// PoolSetupScript returns the setup script used by nodes in pool, falling
+// back to files.setup_script.
+func (c *Config) PoolSetupScript(pool string) string {
+	if path, ok := c.Paths.PoolSetupScripts[pool]; ok {
+		return path
+	}
+	return c.Paths.SetupScript
+}
+
+// WithZone returns a shallow copy of the config targeting zone instead of
+// project.zone.
+func (c *Config) WithZone(zone string) *Config {
+	clone := *c
+	clone.Project.Zone = zone
+	return &clone
+}
+
 func applyDefaults(cfg *Config) {
 	if cfg.Version == 0 {
 		cfg.Version = configVersion
@@ -164,6 +305,26 @@ func applyDefaults(cfg *Config) {
 	if cfg.Files.Zshrc == "" {
 		cfg.Files.Zshrc = "zshrc"
 	}
+	if cfg.Cluster.NetworkMode == "" {
+		cfg.Cluster.NetworkMode = "per_cluster"
+	}
+	if len(cfg.Network.SSHSourceRanges) == 0 {
+		cfg.Network.SSHSourceRanges = []string{"0.0.0.0/0"}
+	}
+	if len(cfg.Network.PortSourceRanges) == 0 {
+		cfg.Network.PortSourceRanges = []string{"0.0.0.0/0"}
+	}
+	if cfg.Network.MyIPURL == "" {
+		cfg.Network.MyIPURL = "https://api.ipify.org"
+	}
+	if cfg.Hooks.PreStopTimeoutSeconds == 0 {
+		cfg.Hooks.PreStopTimeoutSeconds = 600
+	}
+	for i := range cfg.Disk.Extra {
+		if cfg.Disk.Extra[i].LocalSSD() && cfg.Disk.Extra[i].Interface == "" {
+			cfg.Disk.Extra[i].Interface = "NVME"
+		}
+	}
 }
 
 func validateConfig(cfg *Config) error {
@@ -178,12 +339,46 @@ func validateConfig(cfg *Config) error {
 	if !validate.IsResourceName(cfg.Cluster.NetworkNamePrefix) {
 		return fmt.Errorf("cluster.network_name_prefix must be a valid resource name")
 	}
+	if cfg.Cluster.NetworkMode == "existing" {
+		if err := validateExistingNetwork(cfg); err != nil {
+			return err
+		}
+	}
 	if !validate.IsResourceName(cfg.Network.DefaultNetwork) {
 		return fmt.Errorf("network.default_network must be a valid resource name")
 	}
 	if cfg.Instance.HostnameDomain != "" && !validate.IsHostnameDomain(cfg.Instance.HostnameDomain) {
 		return fmt.Errorf("instance.hostname_domain must be a valid DNS domain like example.com")
 	}
+	if _, err := gcp.RegionFromZone(cfg.Project.Zone); err != nil {
+		return fmt.Errorf("project.zone: %w", err)
+	}
+	for _, zone := range cfg.Project.FallbackZones {
+		if _, err := gcp.RegionFromZone(zone); err != nil {
+			return fmt.Errorf("project.fallback_zones: %w", err)
+		}
+	}
+	seenPools := map[string]bool{}
+	for _, pool := range cfg.Cluster.Pools {
+		if !validate.IsResourceName(pool.Name) {
+			return fmt.Errorf("cluster.pools: %q must be a valid resource name", pool.Name)
+		}
+		if seenPools[pool.Name] {
+			return fmt.Errorf("cluster.pools: duplicate pool %q", pool.Name)
+		}
+		seenPools[pool.Name] = true
+	}
+	if dataset := cfg.Cluster.SharedDatasetDisk; dataset != "" && !validate.IsResourceName(path.Base(dataset)) {
+		return fmt.Errorf("cluster.shared_dataset_disk must name a disk or a snapshot")
+	}
+	for _, extra := range cfg.Disk.Extra {
+		if cfg.Cluster.SharedDatasetDisk != "" && extra.Name == DatasetDeviceName {
+			return fmt.Errorf("disk.extra: %q is the device name of cluster.shared_dataset_disk", extra.Name)
+		}
+	}
+	if err := validateExtraDisks(cfg.Disk.Extra); err != nil {
+		return err
+	}
 	if cfg.ServiceAccount.Email == "" && len(cfg.ServiceAccount.Scopes) > 0 {
 		return fmt.Errorf("service_account.email is required when service_account.scopes are set")
 	}
@@ -200,10 +395,109 @@ func validateConfig(cfg *Config) error {
 	if err := ensureFile(cfg.Paths.ZshrcFile); err != nil {
 		return fmt.Errorf("zshrc file: %w", err)
 	}
+	for _, pool := range cfg.Cluster.Pools {
+		if path, ok := cfg.Paths.PoolSetupScripts[pool.Name]; ok {
+			if err := ensureFile(path); err != nil {
+				return fmt.Errorf("pool %s setup script: %w", pool.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateExistingNetwork checks the cluster.network_mode = "existing"
+// settings. The subnet is regional, so fallback zones must stay in the region
+// of project.zone.
+func validateExistingNetwork(cfg *Config) error {
+	if !validate.IsResourceName(cfg.Cluster.Network) {
+		return fmt.Errorf("cluster.network must name an existing network when cluster.network_mode = \"existing\"")
+	}
+	if !validate.IsResourceName(cfg.Cluster.Subnetwork) {
+		return fmt.Errorf("cluster.subnetwork must name an existing subnetwork when cluster.network_mode = \"existing\"")
+	}
+	region, err := gcp.RegionFromZone(cfg.Project.Zone)
+	if err != nil {
+		return fmt.Errorf("project.zone: %w", err)
+	}
+	for _, zone := range cfg.Project.FallbackZones {
+		if zoneRegion, err := gcp.RegionFromZone(zone); err == nil && zoneRegion != region {
+			return fmt.Errorf("project.fallback_zones: %s is outside %s, the region of cluster.subnetwork", zone, region)
+		}
+	}
+	return nil
+}
 
+// validateExtraDisks checks disk.extra entries. Names become device names
+// and disk name suffixes, so they must be unique resource names.
+func validateExtraDisks(disks []ExtraDiskConfig) error {
+	seenNames := map[string]bool{}
+	seenPaths := map[string]bool{}
+	for _, disk := range disks {
+		if !validate.IsResourceName(disk.Name) {
+			return fmt.Errorf("disk.extra: %q must be a valid resource name", disk.Name)
+		}
+		if seenNames[disk.Name] {
+			return fmt.Errorf("disk.extra: duplicate disk %q", disk.Name)
+		}
+		seenNames[disk.Name] = true
+		if disk.LocalSSD() {
+			if disk.Count < 1 {
+				return fmt.Errorf("disk.extra %s: count must be >= 1 for local-ssd", disk.Name)
+			}
+			if disk.SizeGB != 0 || disk.KeepOnDelete {
+				return fmt.Errorf("disk.extra %s: local-ssd disks have a fixed size and are never kept", disk.Name)
+			}
+		} else {
+			if disk.SizeGB <= 0 {
+				return fmt.Errorf("disk.extra %s: size_gb must be > 0", disk.Name)
+			}
+			if disk.Count != 0 || disk.Interface != "" {
+				return fmt.Errorf("disk.extra %s: count and interface only apply to local-ssd", disk.Name)
+			}
+		}
+		if disk.MountPath == "" {
+			continue
+		}
+		if !filepath.IsAbs(disk.MountPath) || filepath.Clean(disk.MountPath) == "/" || strings.ContainsFunc(disk.MountPath, unsafePathRune) {
+			return fmt.Errorf("disk.extra %s: mount_path must be an absolute path other than / made of letters, digits, '.', '_', '-' and '/'", disk.Name)
+		}
+		if seenPaths[filepath.Clean(disk.MountPath)] {
+			return fmt.Errorf("disk.extra %s: mount_path %s is used twice", disk.Name, disk.MountPath)
+		}
+		seenPaths[filepath.Clean(disk.MountPath)] = true
+	}
 	return nil
 }
 
+// unsafePathRune reports runes kept out of mount paths, which end up
+// unquoted in the cloud-init mount script.
+func unsafePathRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case r == '.', r == '_', r == '-', r == '/':
+		return false
+	}
+	return true
+}
+
+// ClusterNetworkProject returns the project hosting cluster networks:
+// cluster.network_project for an existing (Shared VPC) network when set,
+// project.id otherwise.
+func (c *Config) ClusterNetworkProject() string {
+	if c.Cluster.NetworkMode == "existing" && c.Cluster.NetworkProject != "" {
+		return c.Cluster.NetworkProject
+	}
+	return c.Project.ID
+}
+
+// DualStack reports whether nodes get IPv6 next to IPv4
+// (network.stack_type = "IPV4_IPV6").
+func (n NetworkConfig) DualStack() bool {
+	return n.StackType == "IPV4_IPV6"
+}
+
 func validateCIDR(fl validator.FieldLevel) bool {
 	value := fl.Field().String()
 	_, _, err := net.ParseCIDR(value)
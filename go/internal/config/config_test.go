@@ -103,6 +103,62 @@ func TestLoadConfigDefaultsHostnameAndOptionalServiceAccount(t *testing.T) {
 	}
 }
 
+func TestLoadConfigPools(t *testing.T) {
+	baseConfigPath := filepath.Join("..", "..", "..", "profiles", "default", "config.toml")
+	data, err := os.ReadFile(baseConfigPath)
+	if err != nil {
+		t.Fatalf("read default config: %v", err)
+	}
+	pools := "[[cluster.pools]]\nname = \"head\"\ncount = 1\nmachine_type = \"n2-standard-8\"\n\n" +
+		"[[cluster.pools]]\nname = \"gpu\"\ncount = 4\nmachine_type = \"g2-standard-48\"\nsetup_script = \"gpu-setup.sh\"\n\n[instance]\n"
+	updated := strings.Replace(string(data), "[instance]\n", pools, 1)
+
+	tmp := t.TempDir()
+	configDir := filepath.Join(tmp, "pools")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"config.toml":     updated,
+		"cloud-init.yaml": "#cloud-config\n",
+		"setup.sh":        "#!/bin/bash\n",
+		"zshrc":           "export TEST=1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if _, err := Load("pools", tmp); err == nil || !strings.Contains(err.Error(), "pool gpu setup script") {
+		t.Fatalf("expected missing pool setup script error, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "gpu-setup.sh"), []byte("#!/bin/bash\n"), 0o644); err != nil {
+		t.Fatalf("write gpu setup: %v", err)
+	}
+	cfg, err := Load("pools", tmp)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if len(cfg.Cluster.Pools) != 2 || cfg.Cluster.Pools[0].Name != "head" || cfg.Cluster.Pools[1].Count != 4 {
+		t.Fatalf("unexpected pools: %+v", cfg.Cluster.Pools)
+	}
+	if got := cfg.PoolSetupScript("gpu"); got != filepath.Join(configDir, "gpu-setup.sh") {
+		t.Fatalf("gpu setup script mismatch: %s", got)
+	}
+	if got := cfg.PoolSetupScript("head"); got != cfg.Paths.SetupScript {
+		t.Fatalf("head setup script should fall back: %s", got)
+	}
+
+	duplicate := strings.Replace(updated, "\nname = \"gpu\"", "\nname = \"head\"", 1)
+	if err := os.WriteFile(filepath.Join(configDir, "config.toml"), []byte(duplicate), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := Load("pools", tmp); err == nil || !strings.Contains(err.Error(), "duplicate pool") {
+		t.Fatalf("expected duplicate pool error, got %v", err)
+	}
+}
+
 func removeTOMLSection(content, section string) string {
 	lines := strings.Split(content, "\n")
 	header := "[" + section + "]"
@@ -130,3 +186,100 @@ func removeTOMLSection(content, section string) string {
 	}
 	return strings.Join(append(lines[:start], lines[end:]...), "\n")
 }
+
+func TestExistingNetworkModeValidation(t *testing.T) {
+	baseConfigPath := filepath.Join("..", "..", "..", "profiles", "default", "config.toml")
+	data, err := os.ReadFile(baseConfigPath)
+	if err != nil {
+		t.Fatalf("read default config: %v", err)
+	}
+	tmp := t.TempDir()
+	writeProfile := func(name, cluster string) {
+		t.Helper()
+		configDir := filepath.Join(tmp, name)
+		if err := os.MkdirAll(configDir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		files := map[string]string{
+			"config.toml":     strings.Replace(string(data), "[cluster]\n", "[cluster]\n"+cluster, 1),
+			"cloud-init.yaml": "#cloud-config\n",
+			"setup.sh":        "#!/bin/bash\n",
+			"zshrc":           "export TEST=1\n",
+		}
+		for file, content := range files {
+			if err := os.WriteFile(filepath.Join(configDir, file), []byte(content), 0o644); err != nil {
+				t.Fatalf("write %s: %v", file, err)
+			}
+		}
+	}
+
+	writeProfile("existing", "network_mode = \"existing\"\nnetwork = \"shared\"\nsubnetwork = \"gpu\"\nnetwork_project = \"host\"\n")
+	cfg, err := Load("existing", tmp)
+	if err != nil {
+		t.Fatalf("load existing network config: %v", err)
+	}
+	if got := cfg.ClusterNetworkProject(); got != "host" {
+		t.Fatalf("ClusterNetworkProject() = %q, want host", got)
+	}
+
+	writeProfile("no-subnet", "network_mode = \"existing\"\nnetwork = \"shared\"\n")
+	if _, err := Load("no-subnet", tmp); err == nil {
+		t.Fatalf("expected error for existing network mode without a subnetwork")
+	}
+}
+
+func TestLoadConfigExtraDisks(t *testing.T) {
+	baseConfigPath := filepath.Join("..", "..", "..", "profiles", "default", "config.toml")
+	data, err := os.ReadFile(baseConfigPath)
+	if err != nil {
+		t.Fatalf("read default config: %v", err)
+	}
+	tmp := t.TempDir()
+	writeProfile := func(name, cluster, extra string) {
+		t.Helper()
+		configDir := filepath.Join(tmp, name)
+		if err := os.MkdirAll(configDir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		files := map[string]string{
+			"config.toml":     strings.Replace(strings.Replace(string(data), "[service_account]\n", extra+"\n[service_account]\n", 1), "[cluster]\n", "[cluster]\n"+cluster, 1),
+			"cloud-init.yaml": "#cloud-config\n",
+			"setup.sh":        "#!/bin/bash\n",
+			"zshrc":           "export TEST=1\n",
+		}
+		for file, content := range files {
+			if err := os.WriteFile(filepath.Join(configDir, file), []byte(content), 0o644); err != nil {
+				t.Fatalf("write %s: %v", file, err)
+			}
+		}
+	}
+
+	writeProfile("extra", "", "[[disk.extra]]\nname = \"data\"\ntype = \"pd-ssd\"\nsize_gb = 500\nkeep_on_delete = true\nmount_path = \"/data\"\n\n"+
+		"[[disk.extra]]\nname = \"scratch\"\ntype = \"local-ssd\"\ncount = 4\nmount_path = \"/scratch\"\n")
+	cfg, err := Load("extra", tmp)
+	if err != nil {
+		t.Fatalf("load extra disks config: %v", err)
+	}
+	if len(cfg.Disk.Extra) != 2 || !cfg.Disk.Extra[0].KeepOnDelete || cfg.Disk.Extra[1].Interface != "NVME" || !cfg.Disk.Extra[1].LocalSSD() {
+		t.Fatalf("unexpected extra disks: %+v", cfg.Disk.Extra)
+	}
+
+	invalid := map[string]string{
+		"ssd-size":      "[[disk.extra]]\nname = \"scratch\"\ntype = \"local-ssd\"\ncount = 1\nsize_gb = 375\n",
+		"ssd-count":     "[[disk.extra]]\nname = \"scratch\"\ntype = \"local-ssd\"\n",
+		"pd-size":       "[[disk.extra]]\nname = \"data\"\ntype = \"pd-ssd\"\n",
+		"relative-path": "[[disk.extra]]\nname = \"data\"\ntype = \"pd-ssd\"\nsize_gb = 10\nmount_path = \"data\"\n",
+		"dataset-name":  "[[disk.extra]]\nname = \"dataset\"\ntype = \"pd-ssd\"\nsize_gb = 10\n",
+		"duplicate":     "[[disk.extra]]\nname = \"data\"\ntype = \"pd-ssd\"\nsize_gb = 10\n\n[[disk.extra]]\nname = \"data\"\ntype = \"pd-ssd\"\nsize_gb = 10\n",
+	}
+	for name, extra := range invalid {
+		cluster := ""
+		if name == "dataset-name" {
+			cluster = "shared_dataset_disk = \"imagenet\"\n"
+		}
+		writeProfile(name, cluster, extra)
+		if _, err := Load(name, tmp); err == nil || !strings.Contains(err.Error(), "disk.extra") {
+			t.Fatalf("%s: expected disk.extra error, got %v", name, err)
+		}
+	}
+}
@@ -14,6 +14,8 @@ type Client struct {
 	Networks     *compute.NetworksClient
 	Subnetworks  *compute.SubnetworksClient
 	MachineTypes *compute.MachineTypesClient
+	Policies     *compute.ResourcePoliciesClient
+	Routers      *compute.RoutersClient
 }
 
 func New(ctx context.Context) (*Client, error) {
@@ -56,6 +58,27 @@ func New(ctx context.Context) (*Client, error) {
 		_ = subnetworks.Close()
 		return nil, fmt.Errorf("machine types client: %w", err)
 	}
+	policies, err := compute.NewResourcePoliciesRESTClient(ctx)
+	if err != nil {
+		_ = instances.Close()
+		_ = firewalls.Close()
+		_ = disks.Close()
+		_ = networks.Close()
+		_ = subnetworks.Close()
+		_ = machineTypes.Close()
+		return nil, fmt.Errorf("resource policies client: %w", err)
+	}
+	routers, err := compute.NewRoutersRESTClient(ctx)
+	if err != nil {
+		_ = instances.Close()
+		_ = firewalls.Close()
+		_ = disks.Close()
+		_ = networks.Close()
+		_ = subnetworks.Close()
+		_ = machineTypes.Close()
+		_ = policies.Close()
+		return nil, fmt.Errorf("routers client: %w", err)
+	}
 
 	return &Client{
 		Instances:    instances,
@@ -64,6 +87,8 @@ func New(ctx context.Context) (*Client, error) {
 		Networks:     networks,
 		Subnetworks:  subnetworks,
 		MachineTypes: machineTypes,
+		Policies:     policies,
+		Routers:      routers,
 	}, nil
 }
 
@@ -87,5 +112,11 @@ func (c *Client) Close() error {
 	if c.MachineTypes != nil {
 		_ = c.MachineTypes.Close()
 	}
+	if c.Policies != nil {
+		_ = c.Policies.Close()
+	}
+	if c.Routers != nil {
+		_ = c.Routers.Close()
+	}
 	return err
 }
@@ -3,77 +3,154 @@ package gcp
 import (
 	"context"
 
-	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/iterator"
 )
 
+// Operation is a long-running compute operation. *compute.Operation
+// satisfies it; the interface lets tests supply fakes.
+type Operation interface {
+	Poll(ctx context.Context, opts ...gax.CallOption) error
+	Wait(ctx context.Context, opts ...gax.CallOption) error
+	Done() bool
+	Proto() *computepb.Operation
+}
+
+// Iterator iterates over listed resources. The compute client iterators
+// (*compute.InstanceIterator, *compute.NetworkIterator, ...) satisfy it; Next
+// returns iterator.Done when exhausted.
+type Iterator[T any] interface {
+	Next() (T, error)
+}
+
+type InstanceIterator = Iterator[*computepb.Instance]
+
+// Collect drains it into a slice.
+func Collect[T any](it Iterator[T]) ([]T, error) {
+	var items []T
+	for {
+		item, err := it.Next()
+		if err == iterator.Done {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
 type Compute interface {
 	GetInstance(ctx context.Context, req *computepb.GetInstanceRequest) (*computepb.Instance, error)
-	InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (*compute.Operation, error)
-	StartInstance(ctx context.Context, req *computepb.StartInstanceRequest) (*compute.Operation, error)
-	StopInstance(ctx context.Context, req *computepb.StopInstanceRequest) (*compute.Operation, error)
-	DeleteInstance(ctx context.Context, req *computepb.DeleteInstanceRequest) (*compute.Operation, error)
-	SetInstanceScheduling(ctx context.Context, req *computepb.SetSchedulingInstanceRequest) (*compute.Operation, error)
-	SetInstanceTags(ctx context.Context, req *computepb.SetTagsInstanceRequest) (*compute.Operation, error)
-	SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (*compute.Operation, error)
-	SetDiskAutoDelete(ctx context.Context, req *computepb.SetDiskAutoDeleteInstanceRequest) (*compute.Operation, error)
-	ListInstances(ctx context.Context, req *computepb.ListInstancesRequest) *compute.InstanceIterator
+	InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (Operation, error)
+	StartInstance(ctx context.Context, req *computepb.StartInstanceRequest) (Operation, error)
+	StopInstance(ctx context.Context, req *computepb.StopInstanceRequest) (Operation, error)
+	ResetInstance(ctx context.Context, req *computepb.ResetInstanceRequest) (Operation, error)
+	SuspendInstance(ctx context.Context, req *computepb.SuspendInstanceRequest) (Operation, error)
+	ResumeInstance(ctx context.Context, req *computepb.ResumeInstanceRequest) (Operation, error)
+	DeleteInstance(ctx context.Context, req *computepb.DeleteInstanceRequest) (Operation, error)
+	SetInstanceScheduling(ctx context.Context, req *computepb.SetSchedulingInstanceRequest) (Operation, error)
+	SetInstanceMachineType(ctx context.Context, req *computepb.SetMachineTypeInstanceRequest) (Operation, error)
+	SetInstanceTags(ctx context.Context, req *computepb.SetTagsInstanceRequest) (Operation, error)
+	SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (Operation, error)
+	SetInstanceLabels(ctx context.Context, req *computepb.SetLabelsInstanceRequest) (Operation, error)
+	SetDiskAutoDelete(ctx context.Context, req *computepb.SetDiskAutoDeleteInstanceRequest) (Operation, error)
+	ListInstances(ctx context.Context, req *computepb.ListInstancesRequest) InstanceIterator
 
 	GetMachineType(ctx context.Context, req *computepb.GetMachineTypeRequest) (*computepb.MachineType, error)
 
 	GetDisk(ctx context.Context, req *computepb.GetDiskRequest) (*computepb.Disk, error)
+	ListDisks(ctx context.Context, req *computepb.ListDisksRequest) Iterator[*computepb.Disk]
+	InsertDisk(ctx context.Context, req *computepb.InsertDiskRequest) (Operation, error)
+	ResizeDisk(ctx context.Context, req *computepb.ResizeDiskRequest) (Operation, error)
+	DeleteDisk(ctx context.Context, req *computepb.DeleteDiskRequest) (Operation, error)
 
 	GetFirewall(ctx context.Context, req *computepb.GetFirewallRequest) (*computepb.Firewall, error)
-	InsertFirewall(ctx context.Context, req *computepb.InsertFirewallRequest) (*compute.Operation, error)
-	PatchFirewall(ctx context.Context, req *computepb.PatchFirewallRequest) (*compute.Operation, error)
-	DeleteFirewall(ctx context.Context, req *computepb.DeleteFirewallRequest) (*compute.Operation, error)
+	InsertFirewall(ctx context.Context, req *computepb.InsertFirewallRequest) (Operation, error)
+	PatchFirewall(ctx context.Context, req *computepb.PatchFirewallRequest) (Operation, error)
+	DeleteFirewall(ctx context.Context, req *computepb.DeleteFirewallRequest) (Operation, error)
+	ListFirewalls(ctx context.Context, req *computepb.ListFirewallsRequest) Iterator[*computepb.Firewall]
 
 	GetNetwork(ctx context.Context, req *computepb.GetNetworkRequest) (*computepb.Network, error)
-	InsertNetwork(ctx context.Context, req *computepb.InsertNetworkRequest) (*compute.Operation, error)
-	DeleteNetwork(ctx context.Context, req *computepb.DeleteNetworkRequest) (*compute.Operation, error)
+	InsertNetwork(ctx context.Context, req *computepb.InsertNetworkRequest) (Operation, error)
+	DeleteNetwork(ctx context.Context, req *computepb.DeleteNetworkRequest) (Operation, error)
+	ListNetworks(ctx context.Context, req *computepb.ListNetworksRequest) Iterator[*computepb.Network]
 
 	GetSubnetwork(ctx context.Context, req *computepb.GetSubnetworkRequest) (*computepb.Subnetwork, error)
-	InsertSubnetwork(ctx context.Context, req *computepb.InsertSubnetworkRequest) (*compute.Operation, error)
-	DeleteSubnetwork(ctx context.Context, req *computepb.DeleteSubnetworkRequest) (*compute.Operation, error)
+	InsertSubnetwork(ctx context.Context, req *computepb.InsertSubnetworkRequest) (Operation, error)
+	PatchSubnetwork(ctx context.Context, req *computepb.PatchSubnetworkRequest) (Operation, error)
+	DeleteSubnetwork(ctx context.Context, req *computepb.DeleteSubnetworkRequest) (Operation, error)
+	ListSubnetworks(ctx context.Context, req *computepb.ListSubnetworksRequest) Iterator[*computepb.Subnetwork]
+
+	GetResourcePolicy(ctx context.Context, req *computepb.GetResourcePolicyRequest) (*computepb.ResourcePolicy, error)
+	InsertResourcePolicy(ctx context.Context, req *computepb.InsertResourcePolicyRequest) (Operation, error)
+	DeleteResourcePolicy(ctx context.Context, req *computepb.DeleteResourcePolicyRequest) (Operation, error)
+	ListResourcePolicies(ctx context.Context, req *computepb.ListResourcePoliciesRequest) Iterator[*computepb.ResourcePolicy]
+
+	GetRouter(ctx context.Context, req *computepb.GetRouterRequest) (*computepb.Router, error)
+	InsertRouter(ctx context.Context, req *computepb.InsertRouterRequest) (Operation, error)
+	DeleteRouter(ctx context.Context, req *computepb.DeleteRouterRequest) (Operation, error)
+	ListRouters(ctx context.Context, req *computepb.ListRoutersRequest) Iterator[*computepb.Router]
 }
 
 func (c *Client) GetInstance(ctx context.Context, req *computepb.GetInstanceRequest) (*computepb.Instance, error) {
 	return c.Instances.Get(ctx, req)
 }
 
-func (c *Client) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (*compute.Operation, error) {
+func (c *Client) InsertInstance(ctx context.Context, req *computepb.InsertInstanceRequest) (Operation, error) {
 	return c.Instances.Insert(ctx, req)
 }
 
-func (c *Client) StartInstance(ctx context.Context, req *computepb.StartInstanceRequest) (*compute.Operation, error) {
+func (c *Client) StartInstance(ctx context.Context, req *computepb.StartInstanceRequest) (Operation, error) {
 	return c.Instances.Start(ctx, req)
 }
 
-func (c *Client) StopInstance(ctx context.Context, req *computepb.StopInstanceRequest) (*compute.Operation, error) {
+func (c *Client) StopInstance(ctx context.Context, req *computepb.StopInstanceRequest) (Operation, error) {
 	return c.Instances.Stop(ctx, req)
 }
 
-func (c *Client) DeleteInstance(ctx context.Context, req *computepb.DeleteInstanceRequest) (*compute.Operation, error) {
+func (c *Client) ResetInstance(ctx context.Context, req *computepb.ResetInstanceRequest) (Operation, error) {
+	return c.Instances.Reset(ctx, req)
+}
+
+func (c *Client) SuspendInstance(ctx context.Context, req *computepb.SuspendInstanceRequest) (Operation, error) {
+	return c.Instances.Suspend(ctx, req)
+}
+
+func (c *Client) ResumeInstance(ctx context.Context, req *computepb.ResumeInstanceRequest) (Operation, error) {
+	return c.Instances.Resume(ctx, req)
+}
+
+func (c *Client) DeleteInstance(ctx context.Context, req *computepb.DeleteInstanceRequest) (Operation, error) {
 	return c.Instances.Delete(ctx, req)
 }
 
-func (c *Client) SetInstanceScheduling(ctx context.Context, req *computepb.SetSchedulingInstanceRequest) (*compute.Operation, error) {
+func (c *Client) SetInstanceScheduling(ctx context.Context, req *computepb.SetSchedulingInstanceRequest) (Operation, error) {
 	return c.Instances.SetScheduling(ctx, req)
 }
 
-func (c *Client) SetInstanceTags(ctx context.Context, req *computepb.SetTagsInstanceRequest) (*compute.Operation, error) {
+func (c *Client) SetInstanceMachineType(ctx context.Context, req *computepb.SetMachineTypeInstanceRequest) (Operation, error) {
+	return c.Instances.SetMachineType(ctx, req)
+}
+
+func (c *Client) SetInstanceTags(ctx context.Context, req *computepb.SetTagsInstanceRequest) (Operation, error) {
 	return c.Instances.SetTags(ctx, req)
 }
 
-func (c *Client) SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (*compute.Operation, error) {
+func (c *Client) SetInstanceMetadata(ctx context.Context, req *computepb.SetMetadataInstanceRequest) (Operation, error) {
 	return c.Instances.SetMetadata(ctx, req)
 }
 
-func (c *Client) SetDiskAutoDelete(ctx context.Context, req *computepb.SetDiskAutoDeleteInstanceRequest) (*compute.Operation, error) {
+func (c *Client) SetInstanceLabels(ctx context.Context, req *computepb.SetLabelsInstanceRequest) (Operation, error) {
+	return c.Instances.SetLabels(ctx, req)
+}
+
+func (c *Client) SetDiskAutoDelete(ctx context.Context, req *computepb.SetDiskAutoDeleteInstanceRequest) (Operation, error) {
 	return c.Instances.SetDiskAutoDelete(ctx, req)
 }
 
-func (c *Client) ListInstances(ctx context.Context, req *computepb.ListInstancesRequest) *compute.InstanceIterator {
+func (c *Client) ListInstances(ctx context.Context, req *computepb.ListInstancesRequest) InstanceIterator {
 	return c.Instances.List(ctx, req)
 }
 
@@ -85,42 +162,106 @@ func (c *Client) GetDisk(ctx context.Context, req *computepb.GetDiskRequest) (*c
 	return c.Disks.Get(ctx, req)
 }
 
+func (c *Client) ListDisks(ctx context.Context, req *computepb.ListDisksRequest) Iterator[*computepb.Disk] {
+	return c.Disks.List(ctx, req)
+}
+
+func (c *Client) InsertDisk(ctx context.Context, req *computepb.InsertDiskRequest) (Operation, error) {
+	return c.Disks.Insert(ctx, req)
+}
+
+func (c *Client) ResizeDisk(ctx context.Context, req *computepb.ResizeDiskRequest) (Operation, error) {
+	return c.Disks.Resize(ctx, req)
+}
+
+func (c *Client) DeleteDisk(ctx context.Context, req *computepb.DeleteDiskRequest) (Operation, error) {
+	return c.Disks.Delete(ctx, req)
+}
+
 func (c *Client) GetFirewall(ctx context.Context, req *computepb.GetFirewallRequest) (*computepb.Firewall, error) {
 	return c.Firewalls.Get(ctx, req)
 }
 
-func (c *Client) InsertFirewall(ctx context.Context, req *computepb.InsertFirewallRequest) (*compute.Operation, error) {
+func (c *Client) InsertFirewall(ctx context.Context, req *computepb.InsertFirewallRequest) (Operation, error) {
 	return c.Firewalls.Insert(ctx, req)
 }
 
-func (c *Client) PatchFirewall(ctx context.Context, req *computepb.PatchFirewallRequest) (*compute.Operation, error) {
+func (c *Client) PatchFirewall(ctx context.Context, req *computepb.PatchFirewallRequest) (Operation, error) {
 	return c.Firewalls.Patch(ctx, req)
 }
 
-func (c *Client) DeleteFirewall(ctx context.Context, req *computepb.DeleteFirewallRequest) (*compute.Operation, error) {
+func (c *Client) DeleteFirewall(ctx context.Context, req *computepb.DeleteFirewallRequest) (Operation, error) {
 	return c.Firewalls.Delete(ctx, req)
 }
 
+func (c *Client) ListFirewalls(ctx context.Context, req *computepb.ListFirewallsRequest) Iterator[*computepb.Firewall] {
+	return c.Firewalls.List(ctx, req)
+}
+
 func (c *Client) GetNetwork(ctx context.Context, req *computepb.GetNetworkRequest) (*computepb.Network, error) {
 	return c.Networks.Get(ctx, req)
 }
 
-func (c *Client) InsertNetwork(ctx context.Context, req *computepb.InsertNetworkRequest) (*compute.Operation, error) {
+func (c *Client) InsertNetwork(ctx context.Context, req *computepb.InsertNetworkRequest) (Operation, error) {
 	return c.Networks.Insert(ctx, req)
 }
 
-func (c *Client) DeleteNetwork(ctx context.Context, req *computepb.DeleteNetworkRequest) (*compute.Operation, error) {
+func (c *Client) DeleteNetwork(ctx context.Context, req *computepb.DeleteNetworkRequest) (Operation, error) {
 	return c.Networks.Delete(ctx, req)
 }
 
+func (c *Client) ListNetworks(ctx context.Context, req *computepb.ListNetworksRequest) Iterator[*computepb.Network] {
+	return c.Networks.List(ctx, req)
+}
+
 func (c *Client) GetSubnetwork(ctx context.Context, req *computepb.GetSubnetworkRequest) (*computepb.Subnetwork, error) {
 	return c.Subnetworks.Get(ctx, req)
 }
 
-func (c *Client) InsertSubnetwork(ctx context.Context, req *computepb.InsertSubnetworkRequest) (*compute.Operation, error) {
+func (c *Client) InsertSubnetwork(ctx context.Context, req *computepb.InsertSubnetworkRequest) (Operation, error) {
 	return c.Subnetworks.Insert(ctx, req)
 }
 
-func (c *Client) DeleteSubnetwork(ctx context.Context, req *computepb.DeleteSubnetworkRequest) (*compute.Operation, error) {
+func (c *Client) PatchSubnetwork(ctx context.Context, req *computepb.PatchSubnetworkRequest) (Operation, error) {
+	return c.Subnetworks.Patch(ctx, req)
+}
+
+func (c *Client) DeleteSubnetwork(ctx context.Context, req *computepb.DeleteSubnetworkRequest) (Operation, error) {
 	return c.Subnetworks.Delete(ctx, req)
 }
+
+func (c *Client) ListSubnetworks(ctx context.Context, req *computepb.ListSubnetworksRequest) Iterator[*computepb.Subnetwork] {
+	return c.Subnetworks.List(ctx, req)
+}
+
+func (c *Client) GetResourcePolicy(ctx context.Context, req *computepb.GetResourcePolicyRequest) (*computepb.ResourcePolicy, error) {
+	return c.Policies.Get(ctx, req)
+}
+
+func (c *Client) InsertResourcePolicy(ctx context.Context, req *computepb.InsertResourcePolicyRequest) (Operation, error) {
+	return c.Policies.Insert(ctx, req)
+}
+
+func (c *Client) DeleteResourcePolicy(ctx context.Context, req *computepb.DeleteResourcePolicyRequest) (Operation, error) {
+	return c.Policies.Delete(ctx, req)
+}
+
+func (c *Client) ListResourcePolicies(ctx context.Context, req *computepb.ListResourcePoliciesRequest) Iterator[*computepb.ResourcePolicy] {
+	return c.Policies.List(ctx, req)
+}
+
+func (c *Client) GetRouter(ctx context.Context, req *computepb.GetRouterRequest) (*computepb.Router, error) {
+	return c.Routers.Get(ctx, req)
+}
+
+func (c *Client) InsertRouter(ctx context.Context, req *computepb.InsertRouterRequest) (Operation, error) {
+	return c.Routers.Insert(ctx, req)
+}
+
+func (c *Client) DeleteRouter(ctx context.Context, req *computepb.DeleteRouterRequest) (Operation, error) {
+	return c.Routers.Delete(ctx, req)
+}
+
+func (c *Client) ListRouters(ctx context.Context, req *computepb.ListRoutersRequest) Iterator[*computepb.Router] {
+	return c.Routers.List(ctx, req)
+}
@@ -2,6 +2,7 @@ package gcp
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/googleapis/gax-go/v2/apierror"
 	"google.golang.org/api/googleapi"
@@ -48,3 +49,51 @@ func IsAlreadyExists(err error) bool {
 	}
 	return false
 }
+
+// IsPreconditionFailed reports whether err is a 412, which GCE returns when
+// a label, metadata or tag fingerprint is stale.
+func IsPreconditionFailed(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == 412
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.HTTPCode() == 412 {
+			return true
+		}
+		if st := apiErr.GRPCStatus(); st != nil && st.Code() == codes.FailedPrecondition {
+			return true
+		}
+	}
+	if status.Code(err) == codes.FailedPrecondition {
+		return true
+	}
+	return false
+}
+
+// resourceExhaustedMarkers are substrings GCE uses when a zone cannot supply
+// the requested capacity. Operation errors are only surfaced as text, so the
+// classification matches these. The generic RESOURCE_EXHAUSTED status is not
+// among them: it also covers rate limits and quota errors, which another zone
+// does not fix.
+var resourceExhaustedMarkers = []string{
+	"ZONE_RESOURCE_POOL_EXHAUSTED",
+	"STOCKOUT",
+	"does not have enough resources available",
+}
+
+// IsResourceExhausted reports whether err means the zone is out of capacity
+// for the request (GPU stockouts and similar), as opposed to a request error.
+func IsResourceExhausted(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, marker := range resourceExhaustedMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsResourceExhausted(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"zone pool", &googleapi.Error{Code: 503, Message: "errors:{code:\"ZONE_RESOURCE_POOL_EXHAUSTED_WITH_DETAILS\"}"}, true},
+		{"wrapped stockout", fmt.Errorf("create node: %w", errors.New("STOCKOUT in us-east1-d")), true},
+		{"grpc stockout", status.Error(codes.ResourceExhausted, "The zone 'us-east1-d' does not have enough resources available to fulfill the request."), true},
+		{"rate limit", &googleapi.Error{Code: 429, Message: "RESOURCE_EXHAUSTED: Quota exceeded for quota metric 'Queries' and limit 'Queries per minute'"}, false},
+		{"grpc quota", status.Error(codes.ResourceExhausted, "Quota 'NVIDIA_L4_GPUS' exceeded. Limit: 1.0 in region us-east1."), false},
+		{"not found", &googleapi.Error{Code: 404, Message: "not found"}, false},
+		{"plain", errors.New("invalid machine type"), false},
+	}
+	for _, tc := range cases {
+		if got := IsResourceExhausted(tc.err); got != tc.want {
+			t.Fatalf("%s: IsResourceExhausted = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsPreconditionFailed(t *testing.T) {
+	if !IsPreconditionFailed(fmt.Errorf("set labels: %w", &googleapi.Error{Code: 412, Message: "Labels fingerprint either invalid or resource labels have changed"})) {
+		t.Fatalf("expected a wrapped 412 to be a precondition failure")
+	}
+	if IsPreconditionFailed(&googleapi.Error{Code: 409}) || IsPreconditionFailed(errors.New("invalid")) {
+		t.Fatalf("expected other errors not to be precondition failures")
+	}
+}
@@ -3,11 +3,13 @@ package instance
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strings"
 
 	"cloud.google.com/go/compute/apiv1/computepb"
 	"google.golang.org/protobuf/proto"
 
+	"gpunow/internal/cloudinit"
 	"gpunow/internal/config"
 	"gpunow/internal/gcp"
 	"gpunow/internal/labels"
@@ -31,6 +33,10 @@ type Options struct {
 	DiskAutoDelete    *bool
 	Labels            map[string]string
 	Metadata          map[string]string
+	ResourcePolicies  []string
+	// DatasetDisk is the URL of a disk attached read-only as device
+	// config.DatasetDeviceName.
+	DatasetDisk string
 }
 
 func NewBuilder(cfg *config.Config) *Builder {
@@ -50,18 +56,10 @@ func (b *Builder) Build(ctx context.Context, compute gcp.Compute, opts Options)
 	project := b.Config.Project.ID
 	zone := b.Config.Project.Zone
 
-	maxHours := opts.MaxRunHours
-	if maxHours <= 0 {
-		maxHours = b.Config.Instance.MaxRunHours
-	}
 	machineTypeName := strings.TrimSpace(opts.MachineType)
 	if machineTypeName == "" {
 		machineTypeName = b.Config.Instance.MachineType
 	}
-	terminationAction := strings.TrimSpace(opts.TerminationAction)
-	if terminationAction == "" {
-		terminationAction = b.Config.Instance.TerminationAction
-	}
 	diskSizeGB := opts.DiskSizeGB
 	if diskSizeGB <= 0 {
 		diskSizeGB = b.Config.Disk.SizeGB
@@ -77,18 +75,30 @@ func (b *Builder) Build(ctx context.Context, compute gcp.Compute, opts Options)
 	if err != nil {
 		return nil, err
 	}
+	extraDisks, err := b.buildExtraDisks(ctx, compute, opts.Name, mergedLabels)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DatasetDisk != "" {
+		extraDisks = append(extraDisks, &computepb.AttachedDisk{
+			AutoDelete: proto.Bool(false),
+			DeviceName: proto.String(config.DatasetDeviceName),
+			Mode:       proto.String("READ_ONLY"),
+			Source:     proto.String(opts.DatasetDisk),
+			Type:       proto.String("PERSISTENT"),
+		})
+	}
 
 	machineType := gcp.ZoneResource(project, zone, "machineTypes", machineTypeName)
 
-	scheduling := b.buildScheduling(maxHours, terminationAction)
+	scheduling := b.SchedulingFor(opts)
 
 	tags := opts.Tags
 	if len(tags) == 0 {
 		tags = append([]string{}, b.Config.Network.TagsBase...)
 	}
 
-	metadata := mergeMetadata(b.Config.Metadata, opts.Metadata)
-	metadataItems := buildMetadataItems(metadata, opts.CloudInit)
+	metadataItems := buildMetadataItems(b.MetadataFor(opts), opts.CloudInit)
 
 	iface := &computepb.NetworkInterface{
 		Network:   proto.String(opts.Network),
@@ -105,12 +115,22 @@ func (b *Builder) Build(ctx context.Context, compute gcp.Compute, opts Options)
 				NetworkTier: proto.String(b.Config.Network.NetworkTier),
 			},
 		}
+		if b.Config.Network.DualStack() {
+			// External IPv6 is only offered on the premium tier.
+			iface.Ipv6AccessConfigs = []*computepb.AccessConfig{
+				{
+					Name:        proto.String("External IPv6"),
+					Type:        proto.String("DIRECT_IPV6"),
+					NetworkTier: proto.String("PREMIUM"),
+				},
+			}
+		}
 	}
 
 	instance := &computepb.Instance{
 		Name:              proto.String(opts.Name),
 		MachineType:       proto.String(machineType),
-		Disks:             []*computepb.AttachedDisk{disk},
+		Disks:             append([]*computepb.AttachedDisk{disk}, extraDisks...),
 		NetworkInterfaces: []*computepb.NetworkInterface{iface},
 		Scheduling:        scheduling,
 		Tags: &computepb.Tags{
@@ -127,6 +147,7 @@ func (b *Builder) Build(ctx context.Context, compute gcp.Compute, opts Options)
 			ConsumeReservationType: proto.String(reservationAffinityType(b.Config.Reservation.Affinity)),
 		},
 		KeyRevocationActionType: proto.String(keyRevocationActionType(b.Config.Instance.KeyRevocationAction)),
+		ResourcePolicies:        opts.ResourcePolicies,
 	}
 	if email := strings.TrimSpace(b.Config.ServiceAccount.Email); email != "" && len(b.Config.ServiceAccount.Scopes) > 0 {
 		instance.ServiceAccounts = []*computepb.ServiceAccount{{
@@ -153,6 +174,26 @@ func (b *Builder) hostname(name string) string {
 	return fmt.Sprintf("%s.%s", name, strings.TrimPrefix(domain, "."))
 }
 
+// SchedulingFor returns the scheduling Build would set for opts, applying
+// the same config defaults for max run hours and termination action.
+func (b *Builder) SchedulingFor(opts Options) *computepb.Scheduling {
+	maxHours := opts.MaxRunHours
+	if maxHours <= 0 {
+		maxHours = b.Config.Instance.MaxRunHours
+	}
+	terminationAction := strings.TrimSpace(opts.TerminationAction)
+	if terminationAction == "" {
+		terminationAction = b.Config.Instance.TerminationAction
+	}
+	return b.buildScheduling(maxHours, terminationAction)
+}
+
+// MetadataFor returns the metadata items Build would set for opts, other
+// than user-data.
+func (b *Builder) MetadataFor(opts Options) map[string]string {
+	return mergeMetadata(b.Config.Metadata, opts.Metadata)
+}
+
 func (b *Builder) Scheduling(maxHours int) *computepb.Scheduling {
 	return b.buildScheduling(maxHours, b.Config.Instance.TerminationAction)
 }
@@ -212,6 +253,118 @@ func (b *Builder) buildBootDisk(ctx context.Context, compute gcp.Compute, name s
 	}, nil
 }
 
+// buildExtraDisks returns the disk.extra disks of instance name. Persistent
+// disks are named <name>-<disk> and reattached when they already exist.
+func (b *Builder) buildExtraDisks(ctx context.Context, compute gcp.Compute, name string, diskLabels map[string]string) ([]*computepb.AttachedDisk, error) {
+	project := b.Config.Project.ID
+	zone := b.Config.Project.Zone
+
+	disks := []*computepb.AttachedDisk{}
+	for _, extra := range b.Config.Disk.Extra {
+		diskType := gcp.ZoneResource(project, zone, "diskTypes", extra.Type)
+		if extra.LocalSSD() {
+			for i := 0; i < extra.Count; i++ {
+				disks = append(disks, &computepb.AttachedDisk{
+					AutoDelete:       proto.Bool(true),
+					Type:             proto.String("SCRATCH"),
+					Interface:        proto.String(extra.Interface),
+					InitializeParams: &computepb.AttachedDiskInitializeParams{DiskType: proto.String(diskType)},
+				})
+			}
+			continue
+		}
+		diskName := ExtraDiskName(name, extra)
+		attached := &computepb.AttachedDisk{
+			AutoDelete: proto.Bool(!extra.KeepOnDelete),
+			DeviceName: proto.String(extra.Name),
+			Mode:       proto.String("READ_WRITE"),
+			Type:       proto.String("PERSISTENT"),
+		}
+		existing, err := compute.GetDisk(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: diskName})
+		switch {
+		case err == nil && existing != nil:
+			attached.Source = proto.String(gcp.ZoneResource(project, zone, "disks", diskName))
+		case err != nil && !gcp.IsNotFound(err):
+			return nil, err
+		default:
+			attached.InitializeParams = &computepb.AttachedDiskInitializeParams{
+				DiskName:   proto.String(diskName),
+				DiskSizeGb: proto.Int64(int64(extra.SizeGB)),
+				DiskType:   proto.String(diskType),
+				Labels:     diskLabels,
+			}
+			if extra.KeepOnDelete {
+				kept := map[string]string{labels.KeepKey: labels.KeepValue}
+				maps.Copy(kept, diskLabels)
+				attached.InitializeParams.Labels = kept
+			}
+		}
+		disks = append(disks, attached)
+	}
+	return disks, nil
+}
+
+// ExtraDiskName returns the name of the persistent disk.extra disk of
+// instance name.
+func ExtraDiskName(name string, extra config.ExtraDiskConfig) string {
+	return fmt.Sprintf("%s-%s", name, extra.Name)
+}
+
+// KeepsAutoDelete reports whether the auto-delete flag of an attached disk
+// is fixed by the config and must not follow --keep-disks/--delete-disks:
+// local SSDs always go with the instance, and keep_on_delete and read-only
+// (shared) disks always stay.
+func (b *Builder) KeepsAutoDelete(disk *computepb.AttachedDisk) bool {
+	if disk.GetType() == "SCRATCH" || disk.GetMode() == "READ_ONLY" {
+		return true
+	}
+	for _, extra := range b.Config.Disk.Extra {
+		if extra.KeepOnDelete && disk.GetDeviceName() == extra.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscardLocalSSD returns the discard_local_ssd flag to stop or suspend
+// inst with: GCE refuses both for an instance with local SSDs unless their
+// data is discarded. It is nil for instances without local SSDs.
+func DiscardLocalSSD(inst *computepb.Instance) *bool {
+	for _, disk := range inst.GetDisks() {
+		if disk.GetType() == "SCRATCH" {
+			return proto.Bool(true)
+		}
+	}
+	return nil
+}
+
+// Mounts returns the disk.extra filesystems cloud-init mounts, with the
+// device paths the guest sees. Local SSDs are numbered per interface across
+// entries, in attach order.
+func (b *Builder) Mounts() []cloudinit.Mount {
+	mounts := []cloudinit.Mount{}
+	localSSDs := map[string]int{}
+	for _, extra := range b.Config.Disk.Extra {
+		devices := []string{}
+		if extra.LocalSSD() {
+			prefix := "google-local-nvme-ssd-"
+			if extra.Interface == "SCSI" {
+				prefix = "google-local-ssd-"
+			}
+			for i := 0; i < extra.Count; i++ {
+				devices = append(devices, fmt.Sprintf("/dev/disk/by-id/%s%d", prefix, localSSDs[extra.Interface]))
+				localSSDs[extra.Interface]++
+			}
+		} else {
+			devices = append(devices, "/dev/disk/by-id/google-"+extra.Name)
+		}
+		if extra.MountPath != "" {
+			mounts = append(mounts, cloudinit.Mount{Name: extra.Name, Path: extra.MountPath, Devices: devices})
+		}
+	}
+	return mounts
+}
+
 func diskMode(value string) string {
 	switch strings.ToLower(value) {
 	case "rw", "read_write", "read-write", "readwrite":
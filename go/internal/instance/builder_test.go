@@ -1,6 +1,15 @@
 package instance
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+
+	"gpunow/internal/cloudinit"
+	"gpunow/internal/config"
+)
 
 func TestDiskMode(t *testing.T) {
 	cases := map[string]string{
@@ -41,3 +50,33 @@ func TestKeyRevocationActionType(t *testing.T) {
 		}
 	}
 }
+
+func TestExtraDiskMountsAndAutoDelete(t *testing.T) {
+	builder := NewBuilder(&config.Config{Disk: config.DiskConfig{Extra: []config.ExtraDiskConfig{
+		{Name: "data", Type: "pd-ssd", SizeGB: 100, KeepOnDelete: true, MountPath: "/data"},
+		{Name: "cache", Type: "pd-balanced", SizeGB: 50},
+		{Name: "scratch", Type: config.LocalSSDType, Count: 2, Interface: "NVME", MountPath: "/scratch"},
+		{Name: "spill", Type: config.LocalSSDType, Count: 1, Interface: "NVME", MountPath: "/spill"},
+	}}})
+
+	want := []cloudinit.Mount{
+		{Name: "data", Path: "/data", Devices: []string{"/dev/disk/by-id/google-data"}},
+		{Name: "scratch", Path: "/scratch", Devices: []string{"/dev/disk/by-id/google-local-nvme-ssd-0", "/dev/disk/by-id/google-local-nvme-ssd-1"}},
+		{Name: "spill", Path: "/spill", Devices: []string{"/dev/disk/by-id/google-local-nvme-ssd-2"}},
+	}
+	if got := builder.Mounts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Mounts() = %+v, want %+v", got, want)
+	}
+
+	cases := map[*computepb.AttachedDisk]bool{
+		{DeviceName: proto.String("node-0")}:                                     false,
+		{DeviceName: proto.String("data"), Type: proto.String("PERSISTENT")}:     true,
+		{DeviceName: proto.String("cache"), Type: proto.String("PERSISTENT")}:    false,
+		{DeviceName: proto.String("local-ssd-0"), Type: proto.String("SCRATCH")}: true,
+	}
+	for disk, keeps := range cases {
+		if got := builder.KeepsAutoDelete(disk); got != keeps {
+			t.Fatalf("KeepsAutoDelete(%s) = %v, want %v", disk.GetDeviceName(), got, keeps)
+		}
+	}
+}
@@ -5,6 +5,10 @@ import "fmt"
 const (
 	ManagedKey   = "gpunow"
 	ManagedValue = "0xfe"
+	// KeepKey marks disks meant to outlive their node (disk.extra
+	// keep_on_delete); gc never collects them.
+	KeepKey   = "gpunow_keep"
+	KeepValue = "true"
 )
 
 func EnsureManaged(in map[string]string) map[string]string {
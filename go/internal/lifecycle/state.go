@@ -8,6 +8,11 @@ const (
 	InstanceStateProvisioning = "PROVISIONING"
 	InstanceStateReady        = "READY"
 	InstanceStateTerminating  = "TERMINATING"
+	InstanceStateFailed       = "FAILED"
+	// Suspended nodes keep their memory and processes; resuming them skips
+	// first-boot provisioning.
+	InstanceStateSuspending = "SUSPENDING"
+	InstanceStateSuspended  = "SUSPENDED"
 )
 
 func NormalizeInstanceState(value string) string {
@@ -22,6 +27,12 @@ func NormalizeInstanceState(value string) string {
 		return InstanceStateReady
 	case InstanceStateTerminating:
 		return InstanceStateTerminating
+	case InstanceStateFailed:
+		return InstanceStateFailed
+	case InstanceStateSuspending:
+		return InstanceStateSuspending
+	case InstanceStateSuspended:
+		return InstanceStateSuspended
 	default:
 		return strings.ToUpper(strings.TrimSpace(value))
 	}
@@ -31,8 +42,12 @@ func FromComputeStatus(value string) string {
 	switch strings.ToUpper(strings.TrimSpace(value)) {
 	case "TERMINATED":
 		return InstanceStateTerminated
-	case "STOPPING", "SUSPENDING":
+	case "STOPPING":
 		return InstanceStateTerminating
+	case "SUSPENDING":
+		return InstanceStateSuspending
+	case "SUSPENDED":
+		return InstanceStateSuspended
 	case "PROVISIONING", "STAGING":
 		return InstanceStateStarting
 	case "RUNNING":
@@ -1,31 +1,252 @@
 package parse
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-func PortsCSV(value string) ([]int, error) {
+// PortSpec is one exposed port or port range with its protocol. The string
+// form is `<port>[-<port>][/tcp|/udp][@<cidr>[+<cidr>...]]`, e.g. "8888",
+// "8000-8100/tcp" or "60000-61000/udp@203.0.113.0/24". SourceRanges, when
+// set, replace the network-wide source ranges for this entry.
+type PortSpec struct {
+	From         int
+	To           int
+	Protocol     string
+	SourceRanges []string
+}
+
+// Range renders the port or port range as GCP firewall rules expect it.
+func (p PortSpec) Range() string {
+	if p.To == p.From {
+		return strconv.Itoa(p.From)
+	}
+	return fmt.Sprintf("%d-%d", p.From, p.To)
+}
+
+func (p PortSpec) String() string {
+	value := p.Range() + "/" + p.Protocol
+	if len(p.SourceRanges) > 0 {
+		value += "@" + strings.Join(p.SourceRanges, "+")
+	}
+	return value
+}
+
+// Contains reports whether the spec covers port over protocol.
+func (p PortSpec) Contains(port int, protocol string) bool {
+	return p.Protocol == protocol && port >= p.From && port <= p.To
+}
+
+// MarshalJSON stores the spec in its string form, as in state.json.
+func (p PortSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *PortSpec) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	spec, err := PortSpecString(raw)
+	if err != nil {
+		return err
+	}
+	*p = spec
+	return nil
+}
+
+// UnmarshalTOML accepts a port number, a spec string, or an inline table
+// `{ port = "8000-8100", protocol = "udp", source_ranges = ["10.0.0.0/8"] }`.
+func (p *PortSpec) UnmarshalTOML(data any) error {
+	switch value := data.(type) {
+	case int64:
+		spec, err := PortSpecString(strconv.FormatInt(value, 10))
+		if err != nil {
+			return err
+		}
+		*p = spec
+		return nil
+	case string:
+		spec, err := PortSpecString(value)
+		if err != nil {
+			return err
+		}
+		*p = spec
+		return nil
+	case map[string]any:
+		return p.unmarshalTable(value)
+	default:
+		return fmt.Errorf("invalid port %v: expected a number, string or table", data)
+	}
+}
+
+func (p *PortSpec) unmarshalTable(table map[string]any) error {
+	var raw string
+	switch port := table["port"].(type) {
+	case int64:
+		raw = strconv.FormatInt(port, 10)
+	case string:
+		raw = port
+	default:
+		return fmt.Errorf("port table requires port as a number or string")
+	}
+	if protocol, ok := table["protocol"]; ok {
+		value, ok := protocol.(string)
+		if !ok {
+			return fmt.Errorf("port %s: protocol must be a string", raw)
+		}
+		if strings.Contains(raw, "/") {
+			return fmt.Errorf("port %s: protocol is set twice", raw)
+		}
+		raw += "/" + value
+	}
+	spec, err := PortSpecString(raw)
+	if err != nil {
+		return err
+	}
+	if ranges, ok := table["source_ranges"]; ok {
+		items, ok := ranges.([]any)
+		if !ok {
+			return fmt.Errorf("port %s: source_ranges must be a list", raw)
+		}
+		for _, item := range items {
+			cidr, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("port %s: source_ranges must be strings", raw)
+			}
+			if err := spec.addSourceRange(cidr); err != nil {
+				return err
+			}
+		}
+	}
+	*p = spec
+	return nil
+}
+
+// PortSpecString parses one port spec in its string form.
+func PortSpecString(value string) (PortSpec, error) {
+	value = strings.TrimSpace(value)
+	raw := value
+	spec := PortSpec{Protocol: "tcp"}
+
+	if at := strings.Index(value, "@"); at >= 0 {
+		for _, cidr := range strings.Split(value[at+1:], "+") {
+			if err := spec.addSourceRange(cidr); err != nil {
+				return PortSpec{}, err
+			}
+		}
+		value = value[:at]
+	}
+	if slash := strings.Index(value, "/"); slash >= 0 {
+		spec.Protocol = strings.ToLower(strings.TrimSpace(value[slash+1:]))
+		value = value[:slash]
+		if spec.Protocol != "tcp" && spec.Protocol != "udp" {
+			return PortSpec{}, fmt.Errorf("invalid port %s: protocol must be tcp or udp", raw)
+		}
+	}
+
+	from, to, isRange := strings.Cut(value, "-")
+	var err error
+	if spec.From, err = parsePortNumber(from); err != nil {
+		return PortSpec{}, fmt.Errorf("invalid port: %s", raw)
+	}
+	spec.To = spec.From
+	if isRange {
+		if spec.To, err = parsePortNumber(to); err != nil || spec.To < spec.From {
+			return PortSpec{}, fmt.Errorf("invalid port range: %s", raw)
+		}
+	}
+	return spec, nil
+}
+
+func (p *PortSpec) addSourceRange(cidr string) error {
+	cidr = strings.TrimSpace(cidr)
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return fmt.Errorf("invalid source range %q for port %s", cidr, p.Range())
+	}
+	p.SourceRanges = append(p.SourceRanges, cidr)
+	return nil
+}
+
+func parsePortNumber(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port: %s", value)
+	}
+	return port, nil
+}
+
+func PortsCSV(value string) ([]PortSpec, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
 		return nil, fmt.Errorf("ports value is empty")
 	}
 	parts := strings.Split(value, ",")
-	ports := make([]int, 0, len(parts))
+	ports := make([]PortSpec, 0, len(parts))
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
-		p, err := strconv.Atoi(part)
-		if err != nil || p <= 0 || p > 65535 {
-			return nil, fmt.Errorf("invalid port: %s", part)
+		spec, err := PortSpecString(part)
+		if err != nil {
+			return nil, err
 		}
-		ports = append(ports, p)
+		ports = append(ports, spec)
 	}
 	if len(ports) == 0 {
 		return nil, fmt.Errorf("no valid ports found")
 	}
 	return ports, nil
 }
+
+// PortGroup is a set of port specs that share source ranges and therefore
+// fit in one firewall rule.
+type PortGroup struct {
+	// SourceRanges is nil for the group that uses the network-wide ranges.
+	SourceRanges []string
+	Specs        []PortSpec
+}
+
+// Allowed returns the group's port ranges per protocol, protocols in sorted
+// order.
+func (g PortGroup) Allowed() (protocols []string, ranges map[string][]string) {
+	ranges = map[string][]string{}
+	for _, spec := range g.Specs {
+		if _, ok := ranges[spec.Protocol]; !ok {
+			protocols = append(protocols, spec.Protocol)
+		}
+		ranges[spec.Protocol] = append(ranges[spec.Protocol], spec.Range())
+	}
+	sort.Strings(protocols)
+	return protocols, ranges
+}
+
+// GroupPortSpecs splits specs by source ranges. The first group is always the
+// one without its own source ranges (possibly empty); the others follow in
+// order of first appearance.
+func GroupPortSpecs(specs []PortSpec) []PortGroup {
+	groups := []PortGroup{{}}
+	index := map[string]int{}
+	for _, spec := range specs {
+		if len(spec.SourceRanges) == 0 {
+			groups[0].Specs = append(groups[0].Specs, spec)
+			continue
+		}
+		ranges := append([]string{}, spec.SourceRanges...)
+		sort.Strings(ranges)
+		key := strings.Join(ranges, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, PortGroup{SourceRanges: ranges})
+		}
+		groups[i].Specs = append(groups[i].Specs, spec)
+	}
+	return groups
+}
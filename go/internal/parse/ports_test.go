@@ -1,22 +1,98 @@
 package parse
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
 
 func TestPortsCSV(t *testing.T) {
 	ports, err := PortsCSV("22, 80,443")
 	if err != nil {
 		t.Fatalf("parse ports: %v", err)
 	}
-	if len(ports) != 3 || ports[0] != 22 || ports[1] != 80 || ports[2] != 443 {
+	if len(ports) != 3 || ports[0].From != 22 || ports[1].From != 80 || ports[2].From != 443 {
 		t.Fatalf("unexpected ports: %v", ports)
 	}
+	for _, port := range ports {
+		if port.Protocol != "tcp" || port.To != port.From {
+			t.Fatalf("expected single tcp ports, got %v", port)
+		}
+	}
 }
 
 func TestPortsCSVInvalid(t *testing.T) {
-	cases := []string{"", "0", "70000", "abc"}
+	cases := []string{"", "0", "70000", "abc", "8100-8000", "80/icmp", "80-", "80@10.0.0.0/33"}
 	for _, c := range cases {
 		if _, err := PortsCSV(c); err == nil {
 			t.Fatalf("expected error for %q", c)
 		}
 	}
 }
+
+func TestPortSpecString(t *testing.T) {
+	cases := map[string]PortSpec{
+		"8000-8100/tcp":   {From: 8000, To: 8100, Protocol: "tcp"},
+		"60000-61000/UDP": {From: 60000, To: 61000, Protocol: "udp"},
+		"6006@10.8.0.0/16+192.168.1.7/32": {
+			From: 6006, To: 6006, Protocol: "tcp",
+			SourceRanges: []string{"10.8.0.0/16", "192.168.1.7/32"},
+		},
+	}
+	for raw, want := range cases {
+		got, err := PortSpecString(raw)
+		if err != nil {
+			t.Fatalf("parse %q: %v", raw, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("parse %q = %+v, want %+v", raw, got, want)
+		}
+	}
+	if got := (PortSpec{From: 60000, To: 61000, Protocol: "udp"}).String(); got != "60000-61000/udp" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestPortSpecUnmarshalTOML(t *testing.T) {
+	var doc struct {
+		Ports []PortSpec `toml:"ports"`
+	}
+	raw := `ports = [22, "60000-61000/udp", { port = "8000-8100", protocol = "tcp", source_ranges = ["10.0.0.0/8"] }]`
+	if _, err := toml.Decode(raw, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []PortSpec{
+		{From: 22, To: 22, Protocol: "tcp"},
+		{From: 60000, To: 61000, Protocol: "udp"},
+		{From: 8000, To: 8100, Protocol: "tcp", SourceRanges: []string{"10.0.0.0/8"}},
+	}
+	if !reflect.DeepEqual(doc.Ports, want) {
+		t.Fatalf("ports = %+v, want %+v", doc.Ports, want)
+	}
+
+	for _, bad := range []string{`ports = [0]`, `ports = [{ protocol = "udp" }]`, `ports = [{ port = 80, source_ranges = ["nope"] }]`} {
+		if _, err := toml.Decode(bad, &doc); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestGroupPortSpecs(t *testing.T) {
+	ports, err := PortsCSV("22,6006@10.0.0.0/8,60000-61000/udp,8888@10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	groups := GroupPortSpecs(ports)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+	protocols, ranges := groups[0].Allowed()
+	if !reflect.DeepEqual(protocols, []string{"tcp", "udp"}) || !reflect.DeepEqual(ranges["udp"], []string{"60000-61000"}) {
+		t.Fatalf("default group allowed = %v %v", protocols, ranges)
+	}
+	_, ranges = groups[1].Allowed()
+	if !reflect.DeepEqual(groups[1].SourceRanges, []string{"10.0.0.0/8"}) || !reflect.DeepEqual(ranges["tcp"], []string{"6006", "8888"}) {
+		t.Fatalf("source group = %+v", groups[1])
+	}
+}
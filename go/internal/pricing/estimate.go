@@ -21,11 +21,19 @@ type Request struct {
 	GPUCount          int
 	DiskType          string
 	DiskSizeGB        int
+	ExtraDisks        []Disk
 	NumInstances      int
 	MaxRunHours       int
 	Refresh           bool
 }
 
+// Disk is a disk attached to every instance next to the boot disk. Local
+// SSDs use Type "local-ssd" and their total size.
+type Disk struct {
+	Type   string
+	SizeGB int
+}
+
 type Result struct {
 	Currency       string
 	CachePath      string
@@ -208,6 +216,14 @@ func validateRequest(req Request) error {
 	if req.DiskSizeGB <= 0 {
 		return fmt.Errorf("disk size must be positive")
 	}
+	for _, disk := range req.ExtraDisks {
+		if strings.TrimSpace(disk.Type) == "" {
+			return fmt.Errorf("extra disk type is required")
+		}
+		if disk.SizeGB <= 0 {
+			return fmt.Errorf("extra disk size must be positive")
+		}
+	}
 	if req.NumInstances <= 0 {
 		return fmt.Errorf("num instances must be positive")
 	}
@@ -336,6 +352,9 @@ func buildSelectors(req Request) ([]skuSelector, error) {
 			QuantityUnit:        "GiB",
 		},
 	}
+	for _, disk := range req.ExtraDisks {
+		selectors = append(selectors, extraDiskSelector(disk, usage, region))
+	}
 	if gpuType := strings.TrimSpace(req.GPUType); gpuType != "" && req.GPUCount > 0 {
 		gpuTokens := gpuDescriptionTokens(gpuType)
 		gpuTokens = append(gpuTokens, "gpu")
@@ -356,6 +375,34 @@ func buildSelectors(req Request) ([]skuSelector, error) {
 	return selectors, nil
 }
 
+// extraDiskSelector prices a disk.extra disk. Local SSDs are billed at the
+// spot rate on spot instances; persistent disks are not.
+func extraDiskSelector(disk Disk, usage usageExpectation, region string) skuSelector {
+	group, tokens, forbidden := diskSelectorCriteria(disk.Type)
+	if len(forbidden) == 0 {
+		forbidden = []string{"snapshot", "egress", "operation"}
+	}
+	diskKey := normalizeKeyPart(disk.Type)
+	key := fmt.Sprintf("compute.disk.%s.%s", diskKey, region)
+	diskUsage := usageAny
+	if strings.EqualFold(strings.TrimSpace(disk.Type), "local-ssd") {
+		diskUsage = usage
+		key = fmt.Sprintf("compute.disk.%s.%s.%s", diskKey, usageKey(usage), region)
+	}
+	return skuSelector{
+		Key:                 key,
+		Name:                fmt.Sprintf("Extra disk (%s)", disk.Type),
+		Region:              region,
+		ResourceFamily:      "Storage",
+		ResourceGroup:       group,
+		Usage:               diskUsage,
+		RequiredTokens:      tokens,
+		ForbiddenTokens:     forbidden,
+		QuantityPerInstance: float64(disk.SizeGB),
+		QuantityUnit:        "GiB",
+	}
+}
+
 func usageKey(value usageExpectation) string {
 	switch value {
 	case usageSpot:
@@ -699,6 +746,10 @@ func diskSelectorCriteria(diskType string) (resourceGroup string, required []str
 		return "SSD",
 			[]string{"hyperdisk", "extreme", "capacity"},
 			[]string{"snapshot", "instant snapshot", "egress", "operation", "asynchronous replication", "storage pools", "confidential mode"}
+	case "local-ssd":
+		return "LocalSSD",
+			[]string{"ssd backed local storage"},
+			[]string{"commitment", "committed use", "reservation", "sole tenancy"}
 	default:
 		return "", diskDescriptionTokens(diskType), []string{"snapshot", "egress", "operation"}
 	}
@@ -352,6 +352,50 @@ func TestEstimatorUsesFirstPositiveTierPrice(t *testing.T) {
 	}
 }
 
+func TestEstimatorPricesExtraDisks(t *testing.T) {
+	tmp := t.TempDir()
+	cache := NewCacheStore(filepath.Join(tmp, "pricing-cache.json"))
+	catalog := &fakeCatalog{
+		skus: []*cloudbilling.Sku{
+			testSKU("core", "G2 Instance Core running in us-east1", "Compute", "CPU", "Spot", "h", 0.05, []string{"us-east1"}),
+			testSKU("ram", "G2 Instance Ram running in us-east1", "Compute", "RAM", "Spot", "GiBy.h", 0.01, []string{"us-east1"}),
+			testSKU("disk", "Storage PD Capacity in us-east1", "Storage", "PDStandard", "OnDemand", "GiBy.mo", 0.04, []string{"us-east1"}),
+			testSKU("ssd-pd", "SSD backed PD Capacity in us-east1", "Storage", "SSD", "OnDemand", "GiBy.mo", 0.17, []string{"us-east1"}),
+			testSKU("local-ssd", "SSD backed Local Storage in us-east1", "Storage", "LocalSSD", "OnDemand", "GiBy.mo", 0.08, []string{"us-east1"}),
+			testSKU("local-ssd-spot", "SSD backed Local Storage attached to Spot Preemptible VMs in us-east1", "Storage", "LocalSSD", "Preemptible", "GiBy.mo", 0.048, []string{"us-east1"}),
+		},
+	}
+	estimator := NewEstimator(cache, catalog)
+
+	result, err := estimator.Estimate(context.Background(), Request{
+		Currency:          "USD",
+		Zone:              "us-east1-d",
+		MachineType:       "g2-standard-16",
+		VCPU:              16,
+		MemoryMB:          65536,
+		ProvisioningModel: "SPOT",
+		DiskType:          "pd-standard",
+		DiskSizeGB:        200,
+		ExtraDisks:        []Disk{{Type: "pd-ssd", SizeGB: 1000}, {Type: "local-ssd", SizeGB: 750}},
+		NumInstances:      2,
+		MaxRunHours:       12,
+	})
+	if err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	skus := map[string]string{}
+	for _, component := range result.Components {
+		skus[component.Name] = component.SKUID
+	}
+	if skus["Extra disk (pd-ssd)"] != "ssd-pd" || skus["Extra disk (local-ssd)"] != "local-ssd-spot" {
+		t.Fatalf("unexpected extra disk SKUs: %v", skus)
+	}
+	wantPerHour := (16*0.05 + 64*0.01 + (200*0.04+1000*0.17+750*0.048)/730.0) * 2
+	if !closeEnough(result.TotalPerHour, wantPerHour) {
+		t.Fatalf("total per hour mismatch: got=%.8f want=%.8f", result.TotalPerHour, wantPerHour)
+	}
+}
+
 func testSKU(id, desc, family, group, usage, unit string, price float64, regions []string) *cloudbilling.Sku {
 	return &cloudbilling.Sku{
 		Name:        "services/6F81-5844-456A/skus/" + id,
@@ -8,7 +8,9 @@ type SSHOptions struct {
 	ProxyJump    string
 	ForwardAgent bool
 	IdentityFile string
-	Command      []string
+	// Options are passed as -o flags, e.g. "BatchMode=yes".
+	Options []string
+	Command []string
 }
 
 type SCPOptions struct {
@@ -23,6 +25,9 @@ func BuildSSHArgs(opts SSHOptions) []string {
 	if opts.IdentityFile != "" {
 		args = append(args, "-i", opts.IdentityFile)
 	}
+	for _, option := range opts.Options {
+		args = append(args, "-o", option)
+	}
 	if opts.ForwardAgent {
 		args = append(args, "-A")
 	}
@@ -57,6 +62,15 @@ func FormatUserHost(user, host string) string {
 	return user + "@" + host
 }
 
+// FormatRemoteHost formats user@host for ProxyJump and scp paths, where an
+// IPv6 address must be bracketed.
+func FormatRemoteHost(user, host string) string {
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return FormatUserHost(user, host)
+}
+
 func NormalizePath(path string) string {
 	return strings.TrimSpace(path)
 }
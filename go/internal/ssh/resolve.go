@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
 
 	"gpunow/internal/config"
 	"gpunow/internal/gcp"
@@ -16,6 +17,19 @@ type ResolvedTarget struct {
 	Index          int
 	Host           string
 	MasterPublicIP string
+	// Private is set for workers without an external IP: Host is then the
+	// internal IP, reached through the master at MasterPublicIP.
+	Private    bool
+	MasterName string
+}
+
+// ProxyJump returns the -J value for reaching a private target through the
+// master, or "" when the target is reachable directly.
+func (r *ResolvedTarget) ProxyJump(user string) string {
+	if r == nil || !r.Private {
+		return ""
+	}
+	return FormatRemoteHost(user, r.MasterPublicIP)
 }
 
 func ResolveTarget(ctx context.Context, compute gcp.Compute, cfg *config.Config, raw string) (*ResolvedTarget, error) {
@@ -43,10 +57,45 @@ func ResolveClusterTarget(ctx context.Context, compute gcp.Compute, cfg *config.
 		return nil, err
 	}
 	publicIP := externalIP(inst)
-	if publicIP == "" {
-		return nil, fmt.Errorf("instance %s has no external IP", parsed.Name)
+	if publicIP != "" {
+		return &ResolvedTarget{Cluster: cluster, Index: parsed.Index, Host: publicIP, MasterPublicIP: publicIP}, nil
+	}
+	internal := internalIP(inst)
+	if internal == "" {
+		return nil, fmt.Errorf("instance %s has no IP address", parsed.Name)
+	}
+	master, err := findMaster(ctx, compute, cfg, cluster)
+	if err != nil {
+		return nil, err
+	}
+	masterIP := externalIP(master)
+	if masterIP == "" {
+		return nil, fmt.Errorf("instance %s has no external IP and master %s has none to jump through", parsed.Name, master.GetName())
+	}
+	return &ResolvedTarget{
+		Cluster:        cluster,
+		Index:          parsed.Index,
+		Host:           internal,
+		MasterPublicIP: masterIP,
+		Private:        true,
+		MasterName:     master.GetName(),
+	}, nil
+}
+
+// findMaster returns the instance labeled as the master of cluster.
+func findMaster(ctx context.Context, compute gcp.Compute, cfg *config.Config, cluster string) (*computepb.Instance, error) {
+	instances, err := gcp.Collect(compute.ListInstances(ctx, &computepb.ListInstancesRequest{
+		Project: cfg.Project.ID,
+		Zone:    cfg.Project.Zone,
+		Filter:  proto.String(fmt.Sprintf("labels.cluster = %q AND labels.cluster_role = \"master\"", cluster)),
+	}))
+	if err != nil {
+		return nil, err
 	}
-	return &ResolvedTarget{Cluster: cluster, Index: parsed.Index, Host: publicIP, MasterPublicIP: publicIP}, nil
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("master of cluster %s not found", cluster)
+	}
+	return instances[0], nil
 }
 
 func resolveVMTarget(ctx context.Context, compute gcp.Compute, cfg *config.Config, name string) (*ResolvedTarget, error) {
@@ -76,13 +125,24 @@ func getInstance(ctx context.Context, compute gcp.Compute, cfg *config.Config, n
 	return inst, nil
 }
 
+func internalIP(inst *computepb.Instance) string {
+	if inst == nil || len(inst.GetNetworkInterfaces()) == 0 {
+		return ""
+	}
+	return inst.GetNetworkInterfaces()[0].GetNetworkIP()
+}
+
 func externalIP(inst *computepb.Instance) string {
 	if inst == nil || len(inst.GetNetworkInterfaces()) == 0 {
 		return ""
 	}
 	iface := inst.GetNetworkInterfaces()[0]
-	if len(iface.GetAccessConfigs()) == 0 {
-		return ""
+	if len(iface.GetAccessConfigs()) > 0 && iface.GetAccessConfigs()[0].GetNatIP() != "" {
+		return iface.GetAccessConfigs()[0].GetNatIP()
+	}
+	// IPv6-only nodes are reached on their external IPv6 address.
+	if len(iface.GetIpv6AccessConfigs()) > 0 {
+		return iface.GetIpv6AccessConfigs()[0].GetExternalIpv6()
 	}
-	return iface.GetAccessConfigs()[0].GetNatIP()
+	return ""
 }
@@ -17,6 +17,33 @@ func TestBuildSSHArgs(t *testing.T) {
 	}
 }
 
+func TestBuildSSHArgsOptions(t *testing.T) {
+	args := BuildSSHArgs(SSHOptions{
+		User:    "mo",
+		Host:    "1.2.3.4",
+		Options: []string{"BatchMode=yes", "ConnectTimeout=5"},
+	})
+	joined := join(args)
+	if joined != "-o BatchMode=yes -o ConnectTimeout=5 mo@1.2.3.4" {
+		t.Fatalf("unexpected args: %s", joined)
+	}
+}
+
+func TestResolvedTargetProxyJump(t *testing.T) {
+	direct := &ResolvedTarget{Host: "1.2.3.4", MasterPublicIP: "1.2.3.4"}
+	if jump := direct.ProxyJump("mo"); jump != "" {
+		t.Fatalf("expected no jump for public target, got %q", jump)
+	}
+	private := &ResolvedTarget{Host: "10.0.0.3", MasterPublicIP: "5.6.7.8", Private: true}
+	if jump := private.ProxyJump("mo"); jump != "mo@5.6.7.8" {
+		t.Fatalf("unexpected jump %q", jump)
+	}
+	ipv6 := &ResolvedTarget{Host: "10.0.0.3", MasterPublicIP: "2600:1900::1", Private: true}
+	if jump := ipv6.ProxyJump("mo"); jump != "mo@[2600:1900::1]" {
+		t.Fatalf("unexpected IPv6 jump %q", jump)
+	}
+}
+
 func TestBuildSCPArgs(t *testing.T) {
 	args := BuildSCPArgs(SCPOptions{
 		ProxyJump:    "mo@5.6.7.8",
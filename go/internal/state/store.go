@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"gpunow/internal/lifecycle"
+	"gpunow/internal/parse"
 )
 
 const stateVersion = 3
@@ -30,8 +31,10 @@ type Cluster struct {
 	Name         string                      `json:"name"`
 	Profile      string                      `json:"profile"`
 	NumInstances int                         `json:"num_instances"`
+	Zone         string                      `json:"zone,omitempty"`
 	Config       ClusterConfig               `json:"config,omitempty"`
 	Instances    map[string]*ClusterInstance `json:"instances,omitempty"`
+	Recoveries   []ClusterRecovery           `json:"recoveries,omitempty"`
 	Status       string                      `json:"status"`
 	CreatedAt    string                      `json:"created_at,omitempty"`
 	UpdatedAt    string                      `json:"updated_at,omitempty"`
@@ -43,19 +46,76 @@ type Cluster struct {
 type ClusterInstance struct {
 	Name       string `json:"name"`
 	Index      int    `json:"index"`
+	Pool       string `json:"pool,omitempty"`
 	State      string `json:"state"`
 	ExternalIP string `json:"external_ip,omitempty"`
 	InternalIP string `json:"internal_ip,omitempty"`
-	CreatedAt  string `json:"created_at,omitempty"`
-	UpdatedAt  string `json:"updated_at,omitempty"`
+	// FailureReason records why the last start attempt for this node failed.
+	FailureReason string `json:"failure_reason,omitempty"`
+	// Image is the source image the node's boot disk was created from.
+	Image     string `json:"image,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
+// ClusterRecovery records one attempt by `gpunow watch` to bring a
+// preempted or missing node back.
+type ClusterRecovery struct {
+	Instance    string `json:"instance"`
+	Index       int    `json:"index"`
+	Action      string `json:"action"`
+	Reason      string `json:"reason,omitempty"`
+	Error       string `json:"error,omitempty"`
+	StartedAt   string `json:"started_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// maxClusterRecoveries bounds the recovery history kept per cluster.
+const maxClusterRecoveries = 50
+
 type ClusterConfig struct {
 	GCPMachineType       string `json:"gcp_machine_type,omitempty"`
 	GCPMaxRunHours       int    `json:"gcp_max_run_hours,omitempty"`
 	GCPTerminationAction string `json:"gcp_termination_action,omitempty"`
 	GCPDiskSizeGB        int    `json:"gcp_disk_size_gb,omitempty"`
 	KeepDisks            bool   `json:"keep_disks,omitempty"`
+	// Pools records the node count of each pool, in profile order, for
+	// clusters laid out with cluster.pools.
+	Pools []ClusterPool `json:"pools,omitempty"`
+	// SSHSourceRanges and PortSourceRanges override the profile firewall
+	// allowlists for this cluster.
+	SSHSourceRanges  []string `json:"ssh_source_ranges,omitempty"`
+	PortSourceRanges []string `json:"port_source_ranges,omitempty"`
+	// Ports replaces network.ports for this cluster once changed with
+	// `gpunow ports`.
+	Ports []parse.PortSpec `json:"ports,omitempty"`
+	// SubnetCIDR is the cluster subnet range, pinned with --subnet-cidr or
+	// recorded after the first start so it stays stable across restarts.
+	SubnetCIDR string `json:"subnet_cidr,omitempty"`
+	// Labels and Metadata are added to every node, set with `gpunow update`.
+	Labels   map[string]string `json:"labels,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Network holds the profile settings copied at create that shape
+	// resources the cluster already owns, so later profile edits do not
+	// change them. nil (clusters created before it was recorded) uses the
+	// live profile.
+	Network *ClusterNetwork `json:"network,omitempty"`
+}
+
+// ClusterNetwork records the cluster.* profile settings a cluster was
+// created with.
+type ClusterNetwork struct {
+	Placement      string `json:"placement,omitempty"`
+	PrivateWorkers bool   `json:"private_workers,omitempty"`
+	NetworkMode    string `json:"network_mode,omitempty"`
+	Network        string `json:"network,omitempty"`
+	Subnetwork     string `json:"subnetwork,omitempty"`
+	NetworkProject string `json:"network_project,omitempty"`
+}
+
+type ClusterPool struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
 }
 
 type VM struct {
@@ -97,7 +157,7 @@ func (s *Store) RecordClusterCreate(name, profile string, numInstances int, clus
 	entry.Profile = profile
 	entry.NumInstances = numInstances
 	entry.Config = clusterConfig
-	entry.Instances = ensureClusterInstances(name, entry.Instances, numInstances, ts)
+	entry.Instances = ensureClusterInstances(name, entry.Instances, numInstances, clusterConfig.Pools, ts)
 	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
 	entry.LastAction = "create"
 	entry.LastActionAt = ts
@@ -127,7 +187,7 @@ func (s *Store) RecordClusterStart(name, profile string, numInstances int, clust
 	entry.Profile = profile
 	entry.NumInstances = numInstances
 	entry.Config = clusterConfig
-	entry.Instances = ensureClusterInstances(name, entry.Instances, numInstances, ts)
+	entry.Instances = ensureClusterInstances(name, entry.Instances, numInstances, clusterConfig.Pools, ts)
 	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
 	entry.LastAction = "start"
 	entry.LastActionAt = ts
@@ -165,6 +225,7 @@ func (s *Store) RecordClusterStop(name string, deleted bool, when time.Time) err
 			instance.State = lifecycle.InstanceStateTerminated
 			instance.ExternalIP = ""
 			instance.InternalIP = ""
+			instance.FailureReason = ""
 			instance.UpdatedAt = ts
 		}
 		entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
@@ -175,7 +236,85 @@ func (s *Store) RecordClusterStop(name string, deleted bool, when time.Time) err
 	return s.save(data)
 }
 
-func (s *Store) RecordClusterUpdate(name string, when time.Time) error {
+// RecordClusterScale records a new node count; pools, when non-nil, replace
+// the recorded pool counts.
+func (s *Store) RecordClusterScale(name string, numInstances int, pools []ClusterPool, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if data.Clusters == nil {
+		data.Clusters = map[string]*Cluster{}
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		entry = &Cluster{Name: name}
+		data.Clusters[name] = entry
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	if entry.CreatedAt == "" {
+		entry.CreatedAt = ts
+	}
+	entry.UpdatedAt = ts
+	entry.NumInstances = numInstances
+	if pools != nil {
+		entry.Config.Pools = pools
+	}
+	layout := map[string]bool{}
+	for _, instance := range clusterLayout(name, numInstances, entry.Config.Pools) {
+		layout[instance.Name] = true
+	}
+	for instanceName, instance := range entry.Instances {
+		if instance == nil || !layout[instanceName] {
+			delete(entry.Instances, instanceName)
+		}
+	}
+	entry.Instances = ensureClusterInstances(name, entry.Instances, numInstances, entry.Config.Pools, ts)
+	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
+	entry.LastAction = "scale"
+	entry.LastActionAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterPorts stores the cluster's port set after `gpunow ports`.
+func (s *Store) RecordClusterPorts(name string, ports []parse.PortSpec, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		return fmt.Errorf("cluster %s not found in state", name)
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.Config.Ports = ports
+	entry.UpdatedAt = ts
+	entry.LastAction = "ports"
+	entry.LastActionAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+func (s *Store) RecordClusterDiskSize(name string, sizeGB int, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		return fmt.Errorf("cluster %s not found in state", name)
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.Config.GCPDiskSizeGB = sizeGB
+	entry.UpdatedAt = ts
+	entry.LastAction = "disk resize"
+	entry.LastActionAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+func (s *Store) RecordClusterRecovery(name string, recovery ClusterRecovery, when time.Time) error {
 	data, err := s.load()
 	if err != nil {
 		return err
@@ -189,6 +328,98 @@ func (s *Store) RecordClusterUpdate(name string, when time.Time) error {
 		data.Clusters[name] = entry
 	}
 	ts := when.UTC().Format(time.RFC3339)
+	if recovery.CompletedAt == "" {
+		recovery.CompletedAt = ts
+	}
+	entry.UpdatedAt = ts
+	entry.Recoveries = append(entry.Recoveries, recovery)
+	if len(entry.Recoveries) > maxClusterRecoveries {
+		entry.Recoveries = entry.Recoveries[len(entry.Recoveries)-maxClusterRecoveries:]
+	}
+	entry.LastAction = "recover"
+	entry.LastActionAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterZone records the zone a cluster's nodes actually run in, which
+// may differ from project.zone after a stockout fallback.
+func (s *Store) RecordClusterZone(name, zone string, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if data.Clusters == nil {
+		data.Clusters = map[string]*Cluster{}
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		entry = &Cluster{Name: name}
+		data.Clusters[name] = entry
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.UpdatedAt = ts
+	entry.Zone = strings.TrimSpace(zone)
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterSubnetCIDR stores the subnet range a start used for the
+// cluster.
+func (s *Store) RecordClusterSubnetCIDR(name, cidr string, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		return fmt.Errorf("cluster %s not found in state", name)
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.UpdatedAt = ts
+	entry.Config.SubnetCIDR = strings.TrimSpace(cidr)
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterSuspend records a suspend, or a resume when suspended is
+// false. Instance states are recorded as each node changes.
+func (s *Store) RecordClusterSuspend(name string, suspended bool, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		return fmt.Errorf("cluster %s not found in state", name)
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.UpdatedAt = ts
+	entry.LastAction = "resume"
+	if suspended {
+		entry.LastAction = "suspend"
+	}
+	entry.LastActionAt = ts
+	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+func (s *Store) RecordClusterUpdate(name string, clusterConfig ClusterConfig, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if data.Clusters == nil {
+		data.Clusters = map[string]*Cluster{}
+	}
+	entry := data.Clusters[name]
+	if entry == nil {
+		entry = &Cluster{Name: name}
+		data.Clusters[name] = entry
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.Config = clusterConfig
 	entry.UpdatedAt = ts
 	entry.LastAction = "update"
 	entry.LastActionAt = ts
@@ -227,19 +458,22 @@ func (s *Store) RecordClusterInstanceState(clusterName, instanceName, instanceSt
 	}
 	entry.UpdatedAt = ts
 	entry.DeletedAt = ""
-	entry.Instances = ensureClusterInstances(clusterName, entry.Instances, entry.NumInstances, ts)
+	entry.Instances = ensureClusterInstances(clusterName, entry.Instances, entry.NumInstances, entry.Config.Pools, ts)
 
 	instanceEntry := entry.Instances[instanceName]
 	if instanceEntry == nil {
 		instanceEntry = &ClusterInstance{
 			Name:      instanceName,
-			Index:     parseInstanceIndex(clusterName, instanceName),
+			Index:     parseInstanceIndex(clusterName, "", instanceName),
 			CreatedAt: ts,
 		}
 		entry.Instances[instanceName] = instanceEntry
 	}
 	instanceEntry.State = lifecycle.NormalizeInstanceState(instanceState)
 	instanceEntry.UpdatedAt = ts
+	if instanceEntry.State != lifecycle.InstanceStateFailed {
+		instanceEntry.FailureReason = ""
+	}
 	if externalIP != "" || instanceEntry.State == lifecycle.InstanceStateTerminated {
 		instanceEntry.ExternalIP = externalIP
 	}
@@ -258,6 +492,95 @@ func (s *Store) RecordClusterInstanceState(clusterName, instanceName, instanceSt
 	return s.save(data)
 }
 
+func (s *Store) RecordClusterInstanceFailure(clusterName, instanceName, reason string, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if data.Clusters == nil {
+		data.Clusters = map[string]*Cluster{}
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil {
+		entry = &Cluster{Name: clusterName}
+		data.Clusters[clusterName] = entry
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	if entry.CreatedAt == "" {
+		entry.CreatedAt = ts
+	}
+	entry.UpdatedAt = ts
+	entry.Instances = ensureClusterInstances(clusterName, entry.Instances, entry.NumInstances, entry.Config.Pools, ts)
+
+	instanceEntry := entry.Instances[instanceName]
+	if instanceEntry == nil {
+		instanceEntry = &ClusterInstance{
+			Name:      instanceName,
+			Index:     parseInstanceIndex(clusterName, "", instanceName),
+			CreatedAt: ts,
+		}
+		entry.Instances[instanceName] = instanceEntry
+	}
+	instanceEntry.State = lifecycle.InstanceStateFailed
+	instanceEntry.FailureReason = strings.TrimSpace(reason)
+	instanceEntry.UpdatedAt = ts
+
+	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterInstanceDeleted removes a node that was deleted outside a
+// scale or stop, such as a node dropped by --delete-failed. A node inside
+// the recorded layout reads as TERMINATED until it is created again.
+func (s *Store) RecordClusterInstanceDeleted(clusterName, instanceName string, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil || entry.Instances[instanceName] == nil {
+		return nil
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	delete(entry.Instances, instanceName)
+	entry.Instances = ensureClusterInstances(clusterName, entry.Instances, entry.NumInstances, entry.Config.Pools, ts)
+	entry.Status = deriveClusterState(entry.Instances, entry.NumInstances)
+	entry.UpdatedAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
+// RecordClusterInstanceImage records the image a node's boot disk was
+// created from.
+func (s *Store) RecordClusterInstanceImage(clusterName, instanceName, image string, when time.Time) error {
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := data.Clusters[clusterName]
+	if entry == nil {
+		return fmt.Errorf("cluster %s not found in state", clusterName)
+	}
+	ts := when.UTC().Format(time.RFC3339)
+	entry.UpdatedAt = ts
+	entry.Instances = ensureClusterInstances(clusterName, entry.Instances, entry.NumInstances, entry.Config.Pools, ts)
+
+	instanceEntry := entry.Instances[instanceName]
+	if instanceEntry == nil {
+		instanceEntry = &ClusterInstance{
+			Name:      instanceName,
+			Index:     parseInstanceIndex(clusterName, "", instanceName),
+			CreatedAt: ts,
+		}
+		entry.Instances[instanceName] = instanceEntry
+	}
+	instanceEntry.Image = image
+	instanceEntry.UpdatedAt = ts
+	data.UpdatedAt = ts
+	return s.save(data)
+}
+
 func (s *Store) RecordVMStart(name, profile string, when time.Time) error {
 	data, err := s.load()
 	if err != nil {
@@ -377,7 +700,7 @@ func (s *Store) load() (*Data, error) {
 		if cluster.Name == "" {
 			cluster.Name = name
 		}
-		cluster.Instances = ensureClusterInstances(cluster.Name, cluster.Instances, cluster.NumInstances, cluster.CreatedAt)
+		cluster.Instances = ensureClusterInstances(cluster.Name, cluster.Instances, cluster.NumInstances, cluster.Config.Pools, cluster.CreatedAt)
 		for instanceName, instance := range cluster.Instances {
 			if instance == nil {
 				instance = &ClusterInstance{Name: instanceName}
@@ -386,7 +709,7 @@ func (s *Store) load() (*Data, error) {
 			if instance.Name == "" {
 				instance.Name = instanceName
 			}
-			instance.Index = parseInstanceIndex(cluster.Name, instance.Name)
+			instance.Index = parseInstanceIndex(cluster.Name, instance.Pool, instance.Name)
 			if instance.State == "" {
 				instance.State = lifecycle.InstanceStateTerminated
 			} else {
@@ -427,20 +750,39 @@ func (s *Store) save(data *Data) error {
 	return nil
 }
 
-func ensureClusterInstances(clusterName string, existing map[string]*ClusterInstance, numInstances int, ts string) map[string]*ClusterInstance {
+// clusterLayout returns the expected instances of a cluster: one per pool
+// index when pools are recorded, otherwise <cluster>-0 to <cluster>-(n-1).
+func clusterLayout(clusterName string, numInstances int, pools []ClusterPool) []ClusterInstance {
+	layout := []ClusterInstance{}
+	if len(pools) == 0 {
+		for i := 0; i < numInstances; i++ {
+			layout = append(layout, ClusterInstance{Name: fmt.Sprintf("%s-%d", clusterName, i), Index: i})
+		}
+		return layout
+	}
+	for _, pool := range pools {
+		for i := 0; i < pool.Count; i++ {
+			layout = append(layout, ClusterInstance{Name: fmt.Sprintf("%s-%s-%d", clusterName, pool.Name, i), Index: i, Pool: pool.Name})
+		}
+	}
+	return layout
+}
+
+func ensureClusterInstances(clusterName string, existing map[string]*ClusterInstance, numInstances int, pools []ClusterPool, ts string) map[string]*ClusterInstance {
 	if existing == nil {
 		existing = map[string]*ClusterInstance{}
 	}
 	if numInstances <= 0 {
 		return existing
 	}
-	for i := 0; i < numInstances; i++ {
-		name := fmt.Sprintf("%s-%d", clusterName, i)
+	for _, expected := range clusterLayout(clusterName, numInstances, pools) {
+		name := expected.Name
 		entry := existing[name]
 		if entry == nil {
 			entry = &ClusterInstance{
 				Name:      name,
-				Index:     i,
+				Index:     expected.Index,
+				Pool:      expected.Pool,
 				State:     lifecycle.InstanceStateTerminated,
 				CreatedAt: ts,
 				UpdatedAt: ts,
@@ -451,7 +793,8 @@ func ensureClusterInstances(clusterName string, existing map[string]*ClusterInst
 		if entry.Name == "" {
 			entry.Name = name
 		}
-		entry.Index = i
+		entry.Index = expected.Index
+		entry.Pool = expected.Pool
 		if entry.State == "" {
 			entry.State = lifecycle.InstanceStateTerminated
 		}
@@ -474,6 +817,8 @@ func deriveClusterState(instances map[string]*ClusterInstance, configuredCount i
 	hasStarting := false
 	hasProvisioning := false
 	hasTerminating := false
+	hasSuspending := false
+	suspendedCount := 0
 	for _, instance := range instances {
 		if instance == nil {
 			continue
@@ -489,28 +834,43 @@ func deriveClusterState(instances map[string]*ClusterInstance, configuredCount i
 			readyCount++
 		case lifecycle.InstanceStateTerminated:
 			terminatedCount++
+		case lifecycle.InstanceStateSuspending:
+			hasSuspending = true
+		case lifecycle.InstanceStateSuspended:
+			suspendedCount++
 		}
 	}
 	if hasTerminating {
 		return lifecycle.InstanceStateTerminating
 	}
+	if hasSuspending {
+		return lifecycle.InstanceStateSuspending
+	}
 	if hasStarting {
 		return lifecycle.InstanceStateStarting
 	}
 	if hasProvisioning {
 		return lifecycle.InstanceStateProvisioning
 	}
-	if readyCount > 0 && terminatedCount == 0 {
+	if readyCount > 0 && terminatedCount == 0 && suspendedCount == 0 {
 		return lifecycle.InstanceStateReady
 	}
 	if readyCount > 0 {
 		return lifecycle.InstanceStateStarting
 	}
+	if suspendedCount > 0 {
+		return lifecycle.InstanceStateSuspended
+	}
 	return lifecycle.InstanceStateTerminated
 }
 
-func parseInstanceIndex(clusterName, instanceName string) int {
+// parseInstanceIndex returns the index from a <cluster>-<index> or
+// <cluster>-<pool>-<index> instance name, or -1.
+func parseInstanceIndex(clusterName, pool, instanceName string) int {
 	prefix := clusterName + "-"
+	if pool != "" {
+		prefix = fmt.Sprintf("%s-%s-", clusterName, pool)
+	}
 	if !strings.HasPrefix(instanceName, prefix) {
 		return -1
 	}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"gpunow/internal/lifecycle"
+	"gpunow/internal/parse"
 )
 
 func TestStoreRecordClusterLifecycle(t *testing.T) {
@@ -124,6 +127,330 @@ func TestStoreRecordClusterInstanceState(t *testing.T) {
 	}
 }
 
+func TestStoreRecordClusterSuspend(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 8, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("delta", "default", 2, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	for _, name := range []string{"delta-0", "delta-1"} {
+		if err := store.RecordClusterInstanceState("delta", name, lifecycle.InstanceStateSuspended, "", "", when.Add(time.Minute)); err != nil {
+			t.Fatalf("record state: %v", err)
+		}
+	}
+	if err := store.RecordClusterSuspend("delta", true, when.Add(2*time.Minute)); err != nil {
+		t.Fatalf("record suspend: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["delta"]
+	if entry.Status != lifecycle.InstanceStateSuspended || entry.LastAction != "suspend" {
+		t.Fatalf("unexpected suspended entry: %+v", entry)
+	}
+
+	if err := store.RecordClusterInstanceState("delta", "delta-0", lifecycle.InstanceStateReady, "34.1.2.3", "10.0.0.2", when.Add(3*time.Minute)); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	data, err = store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if got := data.Clusters["delta"].Status; got != lifecycle.InstanceStateStarting {
+		t.Fatalf("partially resumed cluster status = %s", got)
+	}
+	if err := store.RecordClusterSuspend("missing", false, when); err == nil {
+		t.Fatalf("expected error for unknown cluster")
+	}
+}
+
+func TestStoreRecordClusterInstanceImage(t *testing.T) {
+	store := New(t.TempDir())
+	when := time.Date(2026, 2, 9, 8, 0, 0, 0, time.UTC)
+	if err := store.RecordClusterCreate("echo", "default", 2, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	image := "projects/ubuntu-os-accelerator-images/global/images/family/ubuntu-accelerator-2404-amd64-with-nvidia-570"
+	if err := store.RecordClusterInstanceImage("echo", "echo-1", image, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record image: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if got := data.Clusters["echo"].Instances["echo-1"].Image; got != image {
+		t.Fatalf("image = %q", got)
+	}
+	if err := store.RecordClusterInstanceImage("missing", "missing-0", image, when); err == nil {
+		t.Fatalf("expected error for unknown cluster")
+	}
+}
+
+func TestStoreRecordClusterScale(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 9, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("delta", "default", 1, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterInstanceState("delta", "delta-0", lifecycle.InstanceStateReady, "34.1.2.3", "10.0.0.2", when); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	if err := store.RecordClusterScale("delta", 4, nil, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record scale up: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["delta"]
+	if entry == nil || entry.NumInstances != 4 || len(entry.Instances) != 4 || entry.LastAction != "scale" {
+		t.Fatalf("unexpected scaled-up entry: %+v", entry)
+	}
+	if entry.Instances["delta-0"].State != lifecycle.InstanceStateReady {
+		t.Fatalf("expected master to keep its state: %+v", entry.Instances["delta-0"])
+	}
+
+	if err := store.RecordClusterScale("delta", 2, nil, when.Add(2*time.Minute)); err != nil {
+		t.Fatalf("record scale down: %v", err)
+	}
+	data, err = store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry = data.Clusters["delta"]
+	if entry.NumInstances != 2 || len(entry.Instances) != 2 {
+		t.Fatalf("unexpected scaled-down entry: %+v", entry)
+	}
+	if entry.Instances["delta-3"] != nil {
+		t.Fatalf("expected delta-3 to be removed")
+	}
+}
+
+func TestStoreRecordClusterPools(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 9, 30, 0, 0, time.UTC)
+
+	pools := []ClusterPool{{Name: "head", Count: 1}, {Name: "gpu", Count: 2}}
+	if err := store.RecordClusterCreate("zeta", "default", 3, ClusterConfig{Pools: pools}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterInstanceState("zeta", "zeta-gpu-1", lifecycle.InstanceStateReady, "34.1.2.3", "10.0.0.3", when); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["zeta"]
+	if len(entry.Instances) != 3 {
+		t.Fatalf("unexpected instances: %+v", entry.Instances)
+	}
+	gpu := entry.Instances["zeta-gpu-1"]
+	if gpu == nil || gpu.Pool != "gpu" || gpu.Index != 1 || gpu.State != lifecycle.InstanceStateReady {
+		t.Fatalf("unexpected gpu instance: %+v", gpu)
+	}
+	if head := entry.Instances["zeta-head-0"]; head == nil || head.Pool != "head" || head.Index != 0 {
+		t.Fatalf("unexpected head instance: %+v", head)
+	}
+
+	scaled := []ClusterPool{{Name: "head", Count: 1}, {Name: "gpu", Count: 1}}
+	if err := store.RecordClusterScale("zeta", 2, scaled, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record scale: %v", err)
+	}
+	data, err = store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry = data.Clusters["zeta"]
+	if len(entry.Instances) != 2 || entry.Instances["zeta-gpu-1"] != nil {
+		t.Fatalf("expected zeta-gpu-1 to be removed: %+v", entry.Instances)
+	}
+	if len(entry.Config.Pools) != 2 || entry.Config.Pools[1].Count != 1 {
+		t.Fatalf("unexpected recorded pools: %+v", entry.Config.Pools)
+	}
+}
+
+func TestStoreRecordClusterInstanceFailure(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 10, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("eps", "default", 2, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterInstanceState("eps", "eps-0", lifecycle.InstanceStateReady, "34.1.2.3", "10.0.0.2", when); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	if err := store.RecordClusterInstanceFailure("eps", "eps-1", "ZONE_RESOURCE_POOL_EXHAUSTED", when.Add(time.Minute)); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["eps"]
+	failed := entry.Instances["eps-1"]
+	if failed.State != lifecycle.InstanceStateFailed || failed.FailureReason != "ZONE_RESOURCE_POOL_EXHAUSTED" {
+		t.Fatalf("unexpected failed instance: %+v", failed)
+	}
+	if entry.Status != lifecycle.InstanceStateReady {
+		t.Fatalf("expected cluster to stay READY with a failed node, got %s", entry.Status)
+	}
+
+	if err := store.RecordClusterInstanceState("eps", "eps-1", lifecycle.InstanceStateStarting, "", "", when.Add(2*time.Minute)); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	data, err = store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if reason := data.Clusters["eps"].Instances["eps-1"].FailureReason; reason != "" {
+		t.Fatalf("expected failure reason to be cleared, got %q", reason)
+	}
+}
+
+func TestStoreRecordClusterInstanceDeleted(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 10, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("eps", "default", 2, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterInstanceState("eps", "eps-0", lifecycle.InstanceStateReady, "34.1.2.3", "10.0.0.2", when); err != nil {
+		t.Fatalf("record state: %v", err)
+	}
+	if err := store.RecordClusterInstanceFailure("eps", "eps-1", "boot failed", when); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if err := store.RecordClusterInstanceDeleted("eps", "eps-1", when.Add(time.Minute)); err != nil {
+		t.Fatalf("record deleted: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	deleted := data.Clusters["eps"].Instances["eps-1"]
+	if deleted == nil || deleted.State != lifecycle.InstanceStateTerminated || deleted.FailureReason != "" {
+		t.Fatalf("expected eps-1 to read as a fresh TERMINATED node, got %+v", deleted)
+	}
+}
+
+func TestStoreRecordClusterRecovery(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 11, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("zeta", "default", 2, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	for i := 0; i < maxClusterRecoveries+5; i++ {
+		recovery := ClusterRecovery{Instance: "zeta-1", Index: 1, Action: "restart", Reason: "TERMINATED"}
+		if err := store.RecordClusterRecovery("zeta", recovery, when.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("record recovery: %v", err)
+		}
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["zeta"]
+	if len(entry.Recoveries) != maxClusterRecoveries {
+		t.Fatalf("expected %d recoveries, got %d", maxClusterRecoveries, len(entry.Recoveries))
+	}
+	last := entry.Recoveries[len(entry.Recoveries)-1]
+	if last.CompletedAt != "2026-02-09T11:54:00Z" || entry.LastAction != "recover" {
+		t.Fatalf("unexpected last recovery: %+v (last action %s)", last, entry.LastAction)
+	}
+}
+
+func TestStoreRecordClusterZone(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("eta", "default", 1, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterZone("eta", "us-central1-a", when); err != nil {
+		t.Fatalf("record zone: %v", err)
+	}
+	if err := store.RecordClusterStart("eta", "default", 1, ClusterConfig{}, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record start: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if zone := data.Clusters["eta"].Zone; zone != "us-central1-a" {
+		t.Fatalf("expected recorded zone to survive start, got %q", zone)
+	}
+}
+
+func TestStoreRecordClusterPorts(t *testing.T) {
+	tmp := t.TempDir()
+	store := New(tmp)
+	when := time.Date(2026, 2, 10, 9, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("theta", "default", 1, ClusterConfig{}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	ports, err := parse.PortsCSV("22,6006@10.8.0.0/16,60000-61000/udp")
+	if err != nil {
+		t.Fatalf("parse ports: %v", err)
+	}
+	if err := store.RecordClusterPorts("theta", ports, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record ports: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(tmp, "state.json"))
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	if !strings.Contains(string(raw), `"6006/tcp@10.8.0.0/16"`) || !strings.Contains(string(raw), `"60000-61000/udp"`) {
+		t.Fatalf("expected ports stored as spec strings, got %s", raw)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["theta"]
+	if !reflect.DeepEqual(entry.Config.Ports, ports) || entry.LastAction != "ports" {
+		t.Fatalf("unexpected cluster after ports: %+v", entry)
+	}
+	if err := store.RecordClusterPorts("missing", ports, when); err == nil {
+		t.Fatalf("expected error for a cluster missing from state")
+	}
+}
+
+func TestStoreRecordClusterDiskSize(t *testing.T) {
+	store := New(t.TempDir())
+	when := time.Date(2026, 2, 10, 9, 0, 0, 0, time.UTC)
+
+	if err := store.RecordClusterCreate("theta", "default", 1, ClusterConfig{GCPDiskSizeGB: 200, GCPMachineType: "g2-standard-4"}, when); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+	if err := store.RecordClusterDiskSize("theta", 500, when.Add(time.Minute)); err != nil {
+		t.Fatalf("record disk size: %v", err)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	entry := data.Clusters["theta"]
+	if entry.Config.GCPDiskSizeGB != 500 || entry.Config.GCPMachineType != "g2-standard-4" || entry.LastAction != "disk resize" {
+		t.Fatalf("unexpected cluster after disk resize: %+v", entry)
+	}
+	if err := store.RecordClusterDiskSize("missing", 500, when); err == nil {
+		t.Fatalf("expected error for a cluster missing from state")
+	}
+}
+
 func TestStoreRecordVMLifecycle(t *testing.T) {
 	tmp := t.TempDir()
 	store := New(tmp)
@@ -2,6 +2,7 @@ package target
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -9,9 +10,11 @@ import (
 )
 
 type Target struct {
-	Raw       string
-	Name      string
-	Cluster   string
+	Raw     string
+	Name    string
+	Cluster string
+	// Pool is set for cluster/pool/index targets.
+	Pool      string
 	Index     int
 	IsCluster bool
 }
@@ -33,15 +36,22 @@ func Parse(input string) (Target, error) {
 		}
 		return Target{Raw: input, Name: input}, nil
 	}
-	if len(parts) != 2 {
+	if len(parts) != 2 && len(parts) != 3 {
 		return Target{}, fmt.Errorf("invalid target format: %s", input)
 	}
 
 	cluster := parts[0]
-	indexRaw := parts[1]
+	indexRaw := parts[len(parts)-1]
 	if !validate.IsResourceName(cluster) {
 		return Target{}, fmt.Errorf("invalid cluster name: %s", cluster)
 	}
+	pool := ""
+	if len(parts) == 3 {
+		pool = parts[1]
+		if !validate.IsResourceName(pool) {
+			return Target{}, fmt.Errorf("invalid pool name: %s", pool)
+		}
+	}
 
 	index, err := strconv.Atoi(indexRaw)
 	if err != nil || index < 0 {
@@ -49,7 +59,10 @@ func Parse(input string) (Target, error) {
 	}
 
 	name := fmt.Sprintf("%s-%d", cluster, index)
-	return Target{Raw: input, Name: name, Cluster: cluster, Index: index, IsCluster: true}, nil
+	if pool != "" {
+		name = fmt.Sprintf("%s-%s-%d", cluster, pool, index)
+	}
+	return Target{Raw: input, Name: name, Cluster: cluster, Pool: pool, Index: index, IsCluster: true}, nil
 }
 
 func parseClusterAlias(input string) (string, int, bool) {
@@ -68,3 +81,97 @@ func parseClusterAlias(input string) (string, int, bool) {
 	}
 	return cluster, index, true
 }
+
+// Selection is a set of nodes of one cluster: cluster/2, cluster-2, a range
+// like cluster/1-3, a list like cluster/0,2, or any of these within a pool
+// (cluster/gpu/0-1).
+type Selection struct {
+	Raw     string
+	Cluster string
+	Pool    string
+	Indices []int
+}
+
+// Names returns the instance names of the selected nodes.
+func (s Selection) Names() []string {
+	names := make([]string, 0, len(s.Indices))
+	for _, index := range s.Indices {
+		if s.Pool != "" {
+			names = append(names, fmt.Sprintf("%s-%s-%d", s.Cluster, s.Pool, index))
+			continue
+		}
+		names = append(names, fmt.Sprintf("%s-%d", s.Cluster, index))
+	}
+	return names
+}
+
+func ParseSelection(input string) (Selection, error) {
+	input = strings.TrimSpace(input)
+	parts := strings.Split(input, "/")
+	if len(parts) == 1 {
+		parsed, err := Parse(input)
+		if err != nil {
+			return Selection{}, err
+		}
+		if !parsed.IsCluster {
+			return Selection{}, fmt.Errorf("target must be cluster/index: %s", input)
+		}
+		return Selection{Raw: input, Cluster: parsed.Cluster, Indices: []int{parsed.Index}}, nil
+	}
+	if len(parts) != 2 && len(parts) != 3 {
+		return Selection{}, fmt.Errorf("invalid target format: %s", input)
+	}
+	cluster := parts[0]
+	if !validate.IsResourceName(cluster) {
+		return Selection{}, fmt.Errorf("invalid cluster name: %s", cluster)
+	}
+	pool := ""
+	if len(parts) == 3 {
+		pool = parts[1]
+		if !validate.IsResourceName(pool) {
+			return Selection{}, fmt.Errorf("invalid pool name: %s", pool)
+		}
+	}
+	indices, err := parseIndices(parts[len(parts)-1])
+	if err != nil {
+		return Selection{}, err
+	}
+	return Selection{Raw: input, Cluster: cluster, Pool: pool, Indices: indices}, nil
+}
+
+// maxSelected bounds the indexes of one selection before the cluster layout
+// is known, so a typo like cluster/0-999999999 fails instead of allocating.
+// Callers check the indexes against the layout itself.
+const maxSelected = 4096
+
+// parseIndices parses a comma separated list of indexes and from-to ranges
+// into sorted, distinct indexes.
+func parseIndices(raw string) ([]int, error) {
+	seen := map[int]bool{}
+	indices := []int{}
+	for _, item := range strings.Split(raw, ",") {
+		fromRaw, toRaw, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(fromRaw)
+		if err != nil || from < 0 {
+			return nil, fmt.Errorf("invalid instance index: %s", item)
+		}
+		to := from
+		if isRange {
+			to, err = strconv.Atoi(toRaw)
+			if err != nil || to < from {
+				return nil, fmt.Errorf("invalid instance range: %s", item)
+			}
+		}
+		if len(indices)+to-from >= maxSelected {
+			return nil, fmt.Errorf("instance range %s selects more than %d nodes", item, maxSelected)
+		}
+		for index := from; index <= to; index++ {
+			if !seen[index] {
+				seen[index] = true
+				indices = append(indices, index)
+			}
+		}
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
@@ -1,6 +1,9 @@
 package target
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseName(t *testing.T) {
 	parsed, err := Parse("gpu0")
@@ -31,6 +34,19 @@ func TestParseClusterRef(t *testing.T) {
 	}
 }
 
+func TestParsePoolRef(t *testing.T) {
+	parsed, err := Parse("my-cluster/gpu/3")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !parsed.IsCluster || parsed.Cluster != "my-cluster" || parsed.Pool != "gpu" || parsed.Index != 3 {
+		t.Fatalf("unexpected pool parse: %+v", parsed)
+	}
+	if parsed.Name != "my-cluster-gpu-3" {
+		t.Fatalf("unexpected name: %s", parsed.Name)
+	}
+}
+
 func TestParseClusterAlias(t *testing.T) {
 	parsed, err := Parse("my-cluster-2")
 	if err != nil {
@@ -48,10 +64,33 @@ func TestParseClusterAlias(t *testing.T) {
 }
 
 func TestParseInvalid(t *testing.T) {
-	cases := []string{"bad/", "bad/abc", "Bad/0", "bad/1/2", "/0"}
+	cases := []string{"bad/", "bad/abc", "Bad/0", "bad/1/2", "/0", "bad/gpu/x", "bad/Gpu/0", "bad/gpu/0/1"}
 	for _, c := range cases {
 		if _, err := Parse(c); err == nil {
 			t.Fatalf("expected error for %s", c)
 		}
 	}
 }
+
+func TestParseSelection(t *testing.T) {
+	cases := map[string][]string{
+		"my-cluster-2":       {"my-cluster-2"},
+		"my-cluster/1-3":     {"my-cluster-1", "my-cluster-2", "my-cluster-3"},
+		"my-cluster/4,0,2-3": {"my-cluster-0", "my-cluster-2", "my-cluster-3", "my-cluster-4"},
+		"my-cluster/gpu/0-1": {"my-cluster-gpu-0", "my-cluster-gpu-1"},
+	}
+	for input, want := range cases {
+		parsed, err := ParseSelection(input)
+		if err != nil {
+			t.Fatalf("parse %s: %v", input, err)
+		}
+		if got := parsed.Names(); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("ParseSelection(%q).Names() = %v, want %v", input, got, want)
+		}
+	}
+	for _, input := range []string{"my-cluster", "my-cluster/3-1", "my-cluster/", "my-cluster/1,,2", "my-cluster/-1", "my-cluster/0-999999999"} {
+		if _, err := ParseSelection(input); err == nil {
+			t.Fatalf("expected error for %s", input)
+		}
+	}
+}
@@ -22,6 +22,7 @@ type TaskList struct {
 type taskItem struct {
 	name    string
 	percent int32
+	status  string
 	message string
 }
 
@@ -98,6 +99,17 @@ func (t *TaskList) MarkWarning(index int, message string) {
 	t.setMessage(index, line)
 }
 
+// SetStatus sets a short detail shown next to an in-progress row, such as a
+// readiness state and elapsed time. An empty status clears it.
+func (t *TaskList) SetStatus(index int, status string) {
+	if t == nil || index < 0 || index >= len(t.items) {
+		return
+	}
+	t.mu.Lock()
+	t.items[index].status = status
+	t.mu.Unlock()
+}
+
 func (t *TaskList) setMessage(index int, line string) {
 	t.mu.Lock()
 	t.items[index].message = line
@@ -179,6 +191,13 @@ func (t *TaskList) render(frame string) {
 		if icon != "" {
 			line = fmt.Sprintf("%s %s", line, icon)
 		}
+		if items[i].status != "" {
+			status := items[i].status
+			if t.ui.UseColor {
+				status = t.ui.style(t.ui.styles.Dim, status)
+			}
+			line = fmt.Sprintf("%s %s", line, status)
+		}
 		lines = append(lines, line)
 	}
 	if t.split != nil {
@@ -4,11 +4,9 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 	"time"
 
-	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
@@ -17,6 +15,7 @@ import (
 	"gpunow/internal/config"
 	"gpunow/internal/gcp"
 	"gpunow/internal/instance"
+	"gpunow/internal/parse"
 	"gpunow/internal/ui"
 )
 
@@ -29,7 +28,7 @@ type Service struct {
 }
 
 type StartOptions struct {
-	Ports         []int
+	Ports         []parse.PortSpec
 	MaxRunHours   int
 	MaxHoursSet   bool
 	AllowCreate   bool
@@ -104,7 +103,7 @@ func (s *Service) Start(ctx context.Context, name string, opts StartOptions) err
 		return fmt.Errorf("%s not found; use cluster start to create it", name)
 	}
 
-	cloudInit, err := cloudinit.Render(s.Config.Paths.CloudInitFile, s.Config.Paths.SetupScript, s.Config.Paths.ZshrcFile)
+	cloudInit, err := cloudinit.Render(s.Config.Paths.CloudInitFile, s.Config.Paths.SetupScript, s.Config.Paths.ZshrcFile, s.Builder.Mounts(), nil)
 	if err != nil {
 		return err
 	}
@@ -143,18 +142,18 @@ func (s *Service) Stop(ctx context.Context, name string, opts StopOptions) error
 	project := s.Config.Project.ID
 	zone := s.Config.Project.Zone
 
-	instance, err := s.getInstance(ctx, name)
+	inst, err := s.getInstance(ctx, name)
 	if err != nil {
 		return err
 	}
-	if instance == nil {
+	if inst == nil {
 		s.UI.Infof("%s not found", name)
 		return nil
 	}
 
-	status := instance.GetStatus()
+	status := inst.GetStatus()
 	if opts.Delete {
-		if err := s.setAutoDelete(ctx, name, instance, !opts.KeepDisks); err != nil {
+		if err := s.setAutoDelete(ctx, name, inst, !opts.KeepDisks); err != nil {
 			return err
 		}
 		call := s.api("compute.instances.delete", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Deleting %s", name))
@@ -181,9 +180,10 @@ func (s *Service) Stop(ctx context.Context, name string, opts StopOptions) error
 
 	call := s.api("compute.instances.stop", gcp.ZoneResource(project, zone, "instances", name), fmt.Sprintf("Stopping %s", name))
 	op, err := s.Compute.StopInstance(ctx, &computepb.StopInstanceRequest{
-		Project:  project,
-		Zone:     zone,
-		Instance: name,
+		Project:         project,
+		Zone:            zone,
+		Instance:        name,
+		DiscardLocalSsd: instance.DiscardLocalSSD(inst),
 	})
 	if err != nil {
 		call.Stop()
@@ -227,6 +227,9 @@ func (s *Service) Show(ctx context.Context, name string) error {
 		if len(iface.GetAccessConfigs()) > 0 {
 			externalIP = iface.GetAccessConfigs()[0].GetNatIP()
 		}
+		if externalIP == "" && len(iface.GetIpv6AccessConfigs()) > 0 {
+			externalIP = iface.GetIpv6AccessConfigs()[0].GetExternalIpv6()
+		}
 	}
 
 	nameStyled := s.UI.Bold(instance.GetName())
@@ -419,21 +422,40 @@ func (s *Service) getInstance(ctx context.Context, name string) (*computepb.Inst
 	return instance, nil
 }
 
-func (s *Service) ensureFirewall(ctx context.Context, name string, ports []int) error {
+// ensureFirewall ensures the "<name>-ports" rule and, for ports with their
+// own source ranges, one "<name>-ports-<n>" rule per distinct set of ranges.
+func (s *Service) ensureFirewall(ctx context.Context, name string, ports []parse.PortSpec) error {
 	project := s.Config.Project.ID
-	ruleName := fmt.Sprintf("%s-ports", name)
+	portTag := fmt.Sprintf("%s-ports", name)
 	network := gcp.GlobalResource(project, "networks", s.Config.Network.DefaultNetwork)
-	allowed := portsToAllowed(ports)
-	portTag := ruleName
 
-	rule := &computepb.Firewall{
-		Name:         proto.String(ruleName),
-		Network:      proto.String(network),
-		Direction:    proto.String("INGRESS"),
-		Allowed:      allowed,
-		TargetTags:   []string{portTag},
-		SourceRanges: []string{"0.0.0.0/0"},
+	for i, group := range parse.GroupPortSpecs(ports) {
+		ruleName := portTag
+		ranges := s.Config.Network.PortSourceRanges
+		if i > 0 {
+			ruleName = fmt.Sprintf("%s-%d", portTag, i)
+			ranges = group.SourceRanges
+		} else if len(group.Specs) == 0 {
+			continue
+		}
+		rule := &computepb.Firewall{
+			Name:         proto.String(ruleName),
+			Network:      proto.String(network),
+			Direction:    proto.String("INGRESS"),
+			Allowed:      portsToAllowed(group),
+			TargetTags:   []string{portTag},
+			SourceRanges: append([]string{}, ranges...),
+		}
+		if err := s.ensureFirewallRule(ctx, rule); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+func (s *Service) ensureFirewallRule(ctx context.Context, rule *computepb.Firewall) error {
+	project := s.Config.Project.ID
+	ruleName := rule.GetName()
 
 	getCall := s.api("compute.firewalls.get", gcp.GlobalResource(project, "firewalls", ruleName), "")
 	_, err := s.Compute.GetFirewall(ctx, &computepb.GetFirewallRequest{
@@ -546,6 +568,9 @@ func (s *Service) setAutoDelete(ctx context.Context, name string, instance *comp
 	zone := s.Config.Project.Zone
 
 	for _, disk := range instance.GetDisks() {
+		if s.Builder.KeepsAutoDelete(disk) {
+			continue
+		}
 		req := &computepb.SetDiskAutoDeleteInstanceRequest{
 			Project:    project,
 			Zone:       zone,
@@ -570,7 +595,7 @@ func (s *Service) api(action, resource, label string) *ui.APICall {
 	return s.UI.APICall(action, resource, label)
 }
 
-func (s *Service) wait(ctx context.Context, call *ui.APICall, op *compute.Operation) error {
+func (s *Service) wait(ctx context.Context, call *ui.APICall, op gcp.Operation) error {
 	err := op.Wait(ctx)
 	if call != nil {
 		call.Stop()
@@ -578,15 +603,16 @@ func (s *Service) wait(ctx context.Context, call *ui.APICall, op *compute.Operat
 	return err
 }
 
-func portsToAllowed(ports []int) []*computepb.Allowed {
-	var portStrings []string
-	for _, port := range ports {
-		portStrings = append(portStrings, strconv.Itoa(port))
+func portsToAllowed(group parse.PortGroup) []*computepb.Allowed {
+	protocols, ranges := group.Allowed()
+	allowed := make([]*computepb.Allowed, 0, len(protocols))
+	for _, protocol := range protocols {
+		allowed = append(allowed, &computepb.Allowed{
+			IPProtocol: proto.String(protocol),
+			Ports:      ranges[protocol],
+		})
 	}
-	return []*computepb.Allowed{{
-		IPProtocol: proto.String("tcp"),
-		Ports:      portStrings,
-	}}
+	return allowed
 }
 
 func containsAll(existing []string, required []string) bool {